@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Abilities are stored as a JSON array of scopes. Existing tokens keep full
+-- access so that upgrading does not lock anyone out.
+UPDATE personal_access_tokens
+SET abilities = '["*"]'
+WHERE abilities IS NULL;
+ALTER TABLE personal_access_tokens
+    ALTER COLUMN abilities SET DEFAULT '["*"]';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE personal_access_tokens
+    ALTER COLUMN abilities DROP DEFAULT;
+-- +goose StatementEnd
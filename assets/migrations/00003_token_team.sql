@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Optionally bind a key to a single team. Bound keys always act within that
+-- team, unbound keys use the X-Tawny-Team header or the user's current team.
+ALTER TABLE personal_access_tokens
+    ADD COLUMN team_id TEXT NULL REFERENCES teams (uuid) ON DELETE CASCADE;
+CREATE INDEX personal_access_tokens_team_id_index
+    ON personal_access_tokens (team_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS personal_access_tokens_team_id_index;
+ALTER TABLE personal_access_tokens
+    DROP COLUMN IF EXISTS team_id;
+-- +goose StatementEnd
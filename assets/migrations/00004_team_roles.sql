@@ -0,0 +1,19 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+-- Roles are ordered from most to least privileged. New enum values cannot be
+-- used in the transaction that creates them, hence NO TRANSACTION.
+ALTER TYPE user_role ADD VALUE IF NOT EXISTS 'owner' BEFORE 'admin';
+ALTER TYPE user_role ADD VALUE IF NOT EXISTS 'viewer' AFTER 'maintainer';
+-- Users own their personal team.
+UPDATE team_user
+SET role = 'owner'
+WHERE team_id IN (SELECT uuid FROM teams WHERE personal_team = true);
+
+-- +goose Down
+-- Postgres cannot drop enum values so fold the new roles into the old ones.
+UPDATE team_user
+SET role = 'admin'
+WHERE role = 'owner';
+UPDATE team_user
+SET role = 'maintainer'
+WHERE role = 'viewer';
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Web UI sessions. Only a SHA-256 hash of the session cookie is stored.
+CREATE TABLE sessions
+(
+    id         BIGSERIAL PRIMARY KEY,
+    token_hash VARCHAR(64)                 NOT NULL,
+    user_id    TEXT                        NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+    ip_address VARCHAR(45)                 NULL,
+    user_agent TEXT                        NULL,
+    expires_at TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT sessions_token_hash_unique UNIQUE (token_hash)
+);
+CREATE INDEX sessions_user_id_index ON sessions (user_id);
+CREATE INDEX sessions_expires_at_index ON sessions (expires_at);
+CREATE TRIGGER trigger_updated_at_sessions
+    BEFORE UPDATE
+    ON sessions
+    FOR EACH ROW
+EXECUTE FUNCTION updated_at_trigger();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS sessions;
+-- +goose StatementEnd
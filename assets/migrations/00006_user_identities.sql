@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+-- External identities (e.g. OpenID Connect subjects) linked to a user.
+CREATE TABLE user_identities
+(
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    TEXT                        NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+    provider   VARCHAR(255)                NOT NULL,
+    subject    VARCHAR(255)                NOT NULL,
+    email      VARCHAR(255)                NULL,
+    avatar_url VARCHAR(2048)               NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT user_identities_provider_subject_unique UNIQUE (provider, subject)
+);
+CREATE INDEX user_identities_user_id_index ON user_identities (user_id);
+CREATE TRIGGER trigger_updated_at_user_identities
+    BEFORE UPDATE
+    ON user_identities
+    FOR EACH ROW
+EXECUTE FUNCTION updated_at_trigger();
+
+-- The identity used to sign in, if the session was created through SSO.
+ALTER TABLE sessions
+    ADD COLUMN identity_id BIGINT NULL REFERENCES user_identities (id) ON DELETE CASCADE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS identity_id;
+DROP TABLE IF EXISTS user_identities;
+-- +goose StatementEnd
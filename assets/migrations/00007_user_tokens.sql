@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Single use tokens sent by email. Only a SHA-256 hash of the token is stored.
+CREATE TABLE user_tokens
+(
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    TEXT                        NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+    purpose    VARCHAR(32)                 NOT NULL,
+    token_hash VARCHAR(64)                 NOT NULL,
+    expires_at TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+    used_at    TIMESTAMP(0) WITH TIME ZONE NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT user_tokens_token_hash_unique UNIQUE (token_hash),
+    CONSTRAINT user_tokens_purpose_check CHECK (purpose IN ('email_verification', 'password_reset'))
+);
+CREATE INDEX user_tokens_user_id_purpose_index ON user_tokens (user_id, purpose);
+
+-- Accounts created before verification existed are treated as verified so
+-- they keep the ability to provision domains.
+UPDATE users
+SET email_verified_at = NOW()
+WHERE email_verified_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_tokens;
+-- +goose StatementEnd
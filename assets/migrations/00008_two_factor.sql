@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+-- two_factor_secret is encrypted by the application before it is stored.
+ALTER TABLE users
+    ADD COLUMN two_factor_secret       TEXT                        NULL,
+    ADD COLUMN two_factor_confirmed_at TIMESTAMP(0) WITH TIME ZONE NULL,
+    -- Last accepted TOTP time step, used to reject replayed codes
+    ADD COLUMN two_factor_last_counter BIGINT                      NULL;
+
+CREATE TABLE two_factor_recovery_codes
+(
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    TEXT                        NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+    code_hash  VARCHAR(64)                 NOT NULL,
+    used_at    TIMESTAMP(0) WITH TIME ZONE NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT two_factor_recovery_codes_unique UNIQUE (user_id, code_hash)
+);
+
+-- Members of a team with this set must enrol in two-factor authentication.
+ALTER TABLE teams
+    ADD COLUMN require_two_factor BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Pending logins waiting for a second factor reuse the single use tokens.
+ALTER TABLE user_tokens
+    DROP CONSTRAINT user_tokens_purpose_check,
+    ADD CONSTRAINT user_tokens_purpose_check
+        CHECK (purpose IN ('email_verification', 'password_reset', 'two_factor_login'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE
+FROM user_tokens
+WHERE purpose = 'two_factor_login';
+ALTER TABLE user_tokens
+    DROP CONSTRAINT user_tokens_purpose_check,
+    ADD CONSTRAINT user_tokens_purpose_check CHECK (purpose IN ('email_verification', 'password_reset'));
+ALTER TABLE teams
+    DROP COLUMN IF EXISTS require_two_factor;
+DROP TABLE IF EXISTS two_factor_recovery_codes;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS two_factor_secret,
+    DROP COLUMN IF EXISTS two_factor_confirmed_at,
+    DROP COLUMN IF EXISTS two_factor_last_counter;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Domains routed to an application. Cluster resources created for a domain
+-- are labelled with the owning team so they can be found again on deletion.
+CREATE TABLE domains
+(
+    id               BIGSERIAL PRIMARY KEY,
+    uuid             TEXT UNIQUE                 NOT NULL DEFAULT ('domain_' || generate_uid(7)),
+    team_id          TEXT                        NOT NULL REFERENCES teams (uuid) ON DELETE CASCADE,
+    app_id           TEXT                        NOT NULL,
+    name             TEXT UNIQUE                 NOT NULL,
+    certificate_type TEXT                        NOT NULL DEFAULT 'production',
+    created_at       TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+CREATE INDEX domains_team_id_idx ON domains (team_id);
+CREATE TRIGGER trigger_updated_at_domains
+    BEFORE UPDATE
+    ON domains
+    FOR EACH ROW
+EXECUTE FUNCTION updated_at_trigger();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS domains;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Who did what, to which resource and with which outcome. Actors are not
+-- foreign keys so that history outlives deleted users, tokens and teams.
+CREATE TABLE audit_events
+(
+    id             BIGSERIAL PRIMARY KEY,
+    actor_user_id  TEXT                        NULL,
+    actor_token_id BIGINT                      NULL,
+    team_id        TEXT                        NULL,
+    action         TEXT                        NOT NULL,
+    resource_type  TEXT                        NOT NULL DEFAULT '',
+    resource_id    TEXT                        NOT NULL DEFAULT '',
+    request_id     TEXT                        NOT NULL DEFAULT '',
+    ip_address     VARCHAR(45)                 NULL,
+    outcome        TEXT                        NOT NULL CHECK (outcome IN ('success', 'failure', 'denied')),
+    created_at     TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+CREATE INDEX audit_events_team_id_created_at_idx ON audit_events (team_id, created_at DESC);
+CREATE INDEX audit_events_actor_user_id_idx ON audit_events (actor_user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS audit_events;
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Token buckets shared by replicas. Rows are swept once full_at passes as a
+-- full bucket is the same as no bucket.
+CREATE UNLOGGED TABLE rate_limit_buckets
+(
+    key        TEXT PRIMARY KEY,
+    tokens     DOUBLE PRECISION            NOT NULL,
+    updated_at TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    full_at    TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+CREATE INDEX rate_limit_buckets_full_at_idx ON rate_limit_buckets (full_at);
+
+-- Failed authentications by client address or hashed credential.
+CREATE UNLOGGED TABLE auth_failures
+(
+    key          TEXT PRIMARY KEY,
+    failures     INTEGER                     NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP(6) WITH TIME ZONE NULL,
+    expires_at   TIMESTAMP(6) WITH TIME ZONE NOT NULL
+);
+CREATE INDEX auth_failures_expires_at_idx ON auth_failures (expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS auth_failures;
+DROP TABLE IF EXISTS rate_limit_buckets;
+-- +goose StatementEnd
@@ -0,0 +1,57 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Team owned machine users for automation. They have no password and cannot
+-- sign in to the web UI, only authenticate with their own API keys. Keys
+-- outlive the admin who created them.
+CREATE TABLE service_accounts
+(
+    id          BIGSERIAL PRIMARY KEY,
+    uuid        TEXT UNIQUE                 NOT NULL DEFAULT ('sa_' || generate_uid(7)),
+    team_id     TEXT                        NOT NULL REFERENCES teams (uuid) ON DELETE CASCADE,
+    name        TEXT                        NOT NULL,
+    description TEXT                        NOT NULL DEFAULT '',
+    role        user_role                   NOT NULL DEFAULT 'maintainer' CHECK (role IN ('maintainer', 'viewer')),
+    created_by  TEXT                        NULL REFERENCES users (uuid) ON DELETE SET NULL,
+    created_at  TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (team_id, name)
+);
+CREATE TRIGGER trigger_updated_at_service_accounts
+    BEFORE UPDATE
+    ON service_accounts
+    FOR EACH ROW
+EXECUTE FUNCTION updated_at_trigger();
+
+-- Keys now belong to either a user or a service account. The owner is split
+-- into generated columns so that each keeps a cascading foreign key.
+ALTER TABLE personal_access_tokens
+    DROP CONSTRAINT fk_tokenable_id,
+    ADD CONSTRAINT personal_access_tokens_tokenable_type_check
+        CHECK (tokenable_type IN ('user', 'service_account')),
+    ADD COLUMN user_id            TEXT GENERATED ALWAYS AS
+        (CASE WHEN tokenable_type = 'user' THEN tokenable_id END) STORED
+        REFERENCES users (uuid) ON DELETE CASCADE,
+    ADD COLUMN service_account_id TEXT GENERATED ALWAYS AS
+        (CASE WHEN tokenable_type = 'service_account' THEN tokenable_id END) STORED
+        REFERENCES service_accounts (uuid) ON DELETE CASCADE;
+CREATE INDEX personal_access_tokens_service_account_id_index
+    ON personal_access_tokens (service_account_id);
+
+ALTER TABLE audit_events
+    ADD COLUMN actor_service_account_id TEXT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE audit_events
+    DROP COLUMN IF EXISTS actor_service_account_id;
+DELETE
+FROM personal_access_tokens
+WHERE tokenable_type <> 'user';
+ALTER TABLE personal_access_tokens
+    DROP COLUMN IF EXISTS service_account_id,
+    DROP COLUMN IF EXISTS user_id,
+    DROP CONSTRAINT IF EXISTS personal_access_tokens_tokenable_type_check,
+    ADD CONSTRAINT fk_tokenable_id FOREIGN KEY (tokenable_id) REFERENCES users (uuid) ON DELETE CASCADE;
+DROP TABLE IF EXISTS service_accounts;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The ClusterIssuer which issues the certificate of a domain. Existing
+-- domains are pointed at the shared Let's Encrypt issuer of their type.
+ALTER TABLE domains
+    ADD COLUMN issuer TEXT NOT NULL DEFAULT '';
+UPDATE domains
+SET issuer = 'tawny-letsencrypt-' || certificate_type
+WHERE issuer = '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE domains
+    DROP COLUMN IF EXISTS issuer;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The Secret holding the certificate a domain is served with. Domains covered
+-- by a wildcard domain of the same team share the wildcard's Secret.
+ALTER TABLE domains
+    ADD COLUMN tls_secret TEXT NOT NULL DEFAULT '';
+UPDATE domains
+SET tls_secret = replace(lower(name), '.', '-') || '-cert-secret'
+WHERE tls_secret = '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE domains
+    DROP COLUMN IF EXISTS tls_secret;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Expiry of a certificate uploaded for a domain. Certificates issued by
+-- cert-manager are renewed by it and leave this unset.
+ALTER TABLE domains
+    ADD COLUMN certificate_expires_at TIMESTAMP(0) WITH TIME ZONE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE domains
+    DROP COLUMN IF EXISTS certificate_expires_at;
+-- +goose StatementEnd
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Ports of an application exposed on a traefik TCP or UDP entrypoint. TCP
+-- mappings with TLS passthrough are routed by hostname (SNI), so several may
+-- share an entrypoint; all others take the entrypoint to themselves.
+CREATE TABLE port_mappings
+(
+    id           BIGSERIAL PRIMARY KEY,
+    uuid         TEXT UNIQUE                 NOT NULL DEFAULT ('port_' || generate_uid(7)),
+    team_id      TEXT                        NOT NULL REFERENCES teams (uuid) ON DELETE CASCADE,
+    app_id       TEXT                        NOT NULL,
+    entrypoint   TEXT                        NOT NULL,
+    protocol     TEXT                        NOT NULL,
+    hostname     TEXT                        NOT NULL DEFAULT '',
+    passthrough  BOOLEAN                     NOT NULL DEFAULT FALSE,
+    service_port INTEGER                     NOT NULL,
+    created_at   TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (entrypoint, hostname)
+);
+CREATE INDEX port_mappings_team_id_idx ON port_mappings (team_id);
+CREATE TRIGGER trigger_updated_at_port_mappings
+    BEFORE UPDATE
+    ON port_mappings
+    FOR EACH ROW
+EXECUTE FUNCTION updated_at_trigger();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS port_mappings;
+-- +goose StatementEnd
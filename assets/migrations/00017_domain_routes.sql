@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Ordered routes of a domain, each matching requests by path prefix and
+-- headers and sharing them among the services of one or more apps. A domain
+-- without routes sends every request to its app.
+ALTER TABLE domains
+    ADD COLUMN routes JSONB NOT NULL DEFAULT '[]';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE domains
+    DROP COLUMN IF EXISTS routes;
+-- +goose StatementEnd
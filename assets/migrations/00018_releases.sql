@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Releases of a new version of an application alongside the running one.
+-- The domain shares its requests for the app between both versions by
+-- weight until the release is promoted or aborted. An app has at most one
+-- active release.
+CREATE TABLE releases
+(
+    id                BIGSERIAL PRIMARY KEY,
+    uuid              TEXT UNIQUE                 NOT NULL DEFAULT ('rel_' || generate_uid(7)),
+    team_id           TEXT                        NOT NULL REFERENCES teams (uuid) ON DELETE CASCADE,
+    app_id            TEXT                        NOT NULL,
+    domain            TEXT                        NOT NULL,
+    strategy          TEXT                        NOT NULL,
+    image             TEXT                        NOT NULL,
+    container         TEXT                        NOT NULL,
+    stable_deployment TEXT                        NOT NULL,
+    weight            INTEGER                     NOT NULL,
+    status            TEXT                        NOT NULL DEFAULT 'active',
+    created_at        TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at        TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX releases_active_app_idx ON releases (team_id, app_id) WHERE status = 'active';
+CREATE INDEX releases_team_id_domain_idx ON releases (team_id, domain);
+CREATE TRIGGER trigger_updated_at_releases
+    BEFORE UPDATE
+    ON releases
+    FOR EACH ROW
+EXECUTE FUNCTION updated_at_trigger();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS releases;
+-- +goose StatementEnd
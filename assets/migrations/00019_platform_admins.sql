@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Platform admins may create users and teams. Team roles never grant this, so
+-- owning a personal team does not make a user an admin of the platform.
+ALTER TABLE users
+    ADD COLUMN platform_admin BOOLEAN NOT NULL DEFAULT FALSE;
+-- The bootstrap admin was only recognisable by name. It is created on first
+-- boot, so it is the oldest user named admin.
+UPDATE users
+SET platform_admin = TRUE
+WHERE id = (SELECT u.id
+            FROM users u
+                     JOIN team_user tu ON u.uuid = tu.user_id
+            WHERE u.name = 'admin'
+              AND tu.role IN ('owner', 'admin')
+            ORDER BY u.id
+            LIMIT 1);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users
+    DROP COLUMN IF EXISTS platform_admin;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Certificate notifications already sent, shared by replicas and kept across
+-- restarts. Rows are removed once their condition clears so that it is
+-- notified again should it recur.
+CREATE TABLE certificate_notifications
+(
+    key        TEXT PRIMARY KEY,
+    created_at TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS certificate_notifications;
+-- +goose StatementEnd
@@ -0,0 +1,193 @@
+package design
+
+import (
+	. "goa.design/goa/v3/dsl"
+)
+
+var _ = Service("certificates", func() {
+	Description("Inventory of the certificates serving the caller's team's domains")
+	HTTP(func() {
+		Path("/certificates")
+	})
+	Security(APIKeyAuth)
+	commonErrors()
+	Method("listCertificates", func() {
+		Description("List the certificates of the caller's team with their expiry and renewal status")
+		requireScopes(ScopeDomainsRead)
+		Payload(func() {
+			apiKeyAuth()
+			paginationPayload()
+			Required(apiKeyName)
+		})
+		Result(CertificatesResult)
+		HTTP(func() {
+			GET("")
+			Response(StatusOK)
+			Header(apiKeyHeader)
+			paginationParams()
+			commonResponses()
+		})
+	})
+	Method("diagnoseDomain", func() {
+		Description("Walk the cert-manager objects issuing a domain's certificate, from Certificate through " +
+			"CertificateRequests and Orders to Challenges, and check the domain resolves to the cluster's ingress")
+		requireScopes(ScopeDomainsRead)
+		Payload(func() {
+			apiKeyAuth()
+			Attribute("domain", String, func() {
+				Pattern(domainRx)
+				Example("example.com")
+			})
+			Required(apiKeyName, "domain")
+		})
+		Result(DiagnosisResult)
+		HTTP(func() {
+			GET("/diagnostics/{domain}")
+			Response(StatusOK)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
+	Method("rootCA", func() {
+		Description("Download the PEM encoded root certificate of the internal CA, for clients to trust the " +
+			"certificates it issues. The root is public, so no credentials are required.")
+		NoSecurity()
+		Result(func() {
+			Attribute("length", Int64, "Length of the bundle in bytes", func() { Example(712) })
+			Attribute("encoding", String, func() { Example("application/x-pem-file") })
+			Attribute("disposition", String, func() { Example(`attachment; filename="tawny-ca.pem"`) })
+			Required("length", "encoding", "disposition")
+		})
+		HTTP(func() {
+			GET("/ca.pem")
+			SkipResponseBodyEncodeDecode()
+			Response(StatusOK, func() {
+				Header("length:Content-Length")
+				Header("encoding:Content-Type")
+				Header("disposition:Content-Disposition")
+			})
+			commonResponses()
+		})
+	})
+})
+
+var DiagnosticStep = Type("DiagnosticStep", func() {
+	Description("State of a cert-manager object")
+	Attribute("kind", String, func() { Example("Order") })
+	Attribute("name", String, func() { Example("app-example-com-1-2745594137") })
+	Attribute("state", String, func() { Example("pending") })
+	Attribute("reason", String, func() { Example("Waiting for HTTP-01 challenge propagation") })
+	Attribute("last_transition", String, "When the state last changed, when known", func() {
+		Example("2024-01-01 00:00:00 +0000 UTC")
+	})
+	Required("kind", "name")
+})
+
+var ChallengeDiagnosis = Type("ChallengeDiagnosis", func() {
+	Extend(DiagnosticStep)
+	Attribute("type", String, func() { Example("HTTP-01") })
+	Attribute("dns_name", String, func() { Example("app.example.com") })
+	Attribute("token", String, "Token presented to the ACME server", func() {
+		Example("evaGxfADs6pSRb2LAv9IZf17Dt3juxGJ-PCt92wr-oA")
+	})
+	Attribute("presented", Boolean, "Whether the token is being presented", func() { Example(true) })
+	Required("presented")
+})
+
+var OrderDiagnosis = Type("OrderDiagnosis", func() {
+	Extend(DiagnosticStep)
+	Attribute("url", String, "URL of the order at the ACME server", func() {
+		Example("https://acme-v02.api.letsencrypt.org/acme/order/1/2")
+	})
+	Attribute("challenges", ArrayOf(ChallengeDiagnosis))
+	Required("challenges")
+})
+
+var RequestDiagnosis = Type("RequestDiagnosis", func() {
+	Extend(DiagnosticStep)
+	Attribute("orders", ArrayOf(OrderDiagnosis))
+	Required("orders")
+})
+
+var DNSCheck = Type("DNSCheck", func() {
+	Description("Whether the domain resolves to the cluster's ingress")
+	Attribute("ok", Boolean, func() { Example(true) })
+	Attribute("expected", ArrayOf(String), "Addresses of the ingress", func() { Example([]string{"203.0.113.10"}) })
+	Attribute("resolved", ArrayOf(String), "Addresses the domain resolves to", func() {
+		Example([]string{"203.0.113.10"})
+	})
+	Attribute("error", String, func() { Example("app.example.com does not resolve to the ingress") })
+	Required("ok")
+})
+
+var DiagnosisResult = ResultType("application/vnd.tawny.diagnosis", func() {
+	TypeName("DiagnosisResult")
+	Description("How far issuance of a domain's certificate got")
+	Attribute("domain", String, func() { Example("app.example.com") })
+	Attribute("certificate", DiagnosticStep)
+	Attribute("requests", ArrayOf(RequestDiagnosis), "CertificateRequests of the certificate, newest first")
+	Attribute("dns", DNSCheck)
+	Required("domain", "certificate", "requests", "dns")
+
+	View(viewDefault, func() {
+		Attribute("domain")
+		Attribute("certificate")
+		Attribute("requests")
+		Attribute("dns")
+	})
+})
+
+var CertificateResult = ResultType("application/vnd.tawny.certificate", func() {
+	TypeName("CertificateResult")
+	Description("A certificate served for one or more domains")
+	Attribute("name", String, "Name of the certificate", func() { Example("app-example-com") })
+	Attribute("source", String, "Whether cert-manager issues the certificate or it was uploaded", func() {
+		Enum("cert-manager", "uploaded")
+		Example("cert-manager")
+	})
+	Attribute("domains", ArrayOf(String), "Domains the certificate covers", func() {
+		Example([]string{"app.example.com"})
+	})
+	Attribute("issuer", String, "Issuer of the certificate, absent for uploaded certificates", func() {
+		Example("tawny-letsencrypt-production")
+	})
+	Attribute("secret_name", String, "Secret holding the certificate", func() {
+		Example("app-example-com-cert-secret")
+	})
+	Attribute("ready", Boolean, "Whether the certificate is ready to be served", func() { Example(true) })
+	Attribute("not_after", String, "Expiry of the certificate", func() { Example("2025-01-01 00:00:00 +0000 UTC") })
+	Attribute("renewal_time", String, "When cert-manager next renews the certificate", func() {
+		Example("2024-12-02 00:00:00 +0000 UTC")
+	})
+	Attribute("renewal_failed", Boolean, "Whether the latest issuance failed", func() { Example(false) })
+	Attribute("failure_message", String, "Why the latest issuance failed", func() {
+		Example("Failed to wait for order resource to become ready")
+	})
+	Attribute("serial_number", String, "Serial number of the certificate held in the Secret", func() {
+		Example("330324528834419410223462478954127163")
+	})
+	Attribute("issued_by", String, "Common name of the certificate's issuer", func() { Example("R3") })
+	Required("name", "source", "ready", "renewal_failed")
+
+	View(viewDefault, func() {
+		Attribute("name")
+		Attribute("source")
+		Attribute("domains")
+		Attribute("issuer")
+		Attribute("secret_name")
+		Attribute("ready")
+		Attribute("not_after")
+		Attribute("renewal_time")
+		Attribute("renewal_failed")
+		Attribute("failure_message")
+		Attribute("serial_number")
+		Attribute("issued_by")
+	})
+})
+
+var CertificatesResult = ResultType("application/vnd.tawny.certificates", func() {
+	TypeName("CertificatesResult")
+	Attribute("certificates", CollectionOf(CertificateResult))
+	Attribute("metadata", PaginationMetadata)
+	Required("certificates", "metadata")
+})
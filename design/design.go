@@ -19,6 +19,15 @@ const (
 	userRx            = "^user_[a-zA-Z0-9]{7}$"
 	teamRx            = "^team_[a-zA-Z0-9]{7}$"
 	keyRx             = "^key_[a-zA-Z0-9]{20}$"
+	serviceAccountRx  = "^sa_[a-zA-Z0-9]{7}$"
+	actorRx           = "^(user|sa)_[a-zA-Z0-9]{7}$"
+	issuerRx          = "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$"
+	domainRx          = "^(\\*\\.)?([a-zA-Z0-9]([-a-zA-Z0-9]*[a-zA-Z0-9])?\\.)*[a-zA-Z0-9]([-a-zA-Z0-9]*[a-zA-Z0-9])?$"
+	headerNameRx      = "^[a-zA-Z0-9-]+$"
+	ruleValueRx       = "^[^`]*$"
+	pathPrefixRx      = "^/[^`]*$"
+	imageRx           = "^[a-zA-Z0-9][a-zA-Z0-9._/:@-]*$"
+	containerRx       = "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$"
 	apiKeyScheme      = "api_key"
 	apiKeyName        = "key"
 	apiKeyHeaderValue = "X-API-KEY"
@@ -26,8 +35,35 @@ const (
 	viewShort         = "short"
 )
 
+// Scopes which can be granted to an API key. A key is minted with a subset of
+// these and each method declares the scopes it requires.
+const (
+	ScopeIdentityRead  = "identity:read"
+	ScopeIdentityWrite = "identity:write"
+	ScopeIdentityAdmin = "identity:admin"
+	ScopeDomainsRead   = "domains:read"
+	ScopeDomainsWrite  = "domains:write"
+	ScopeAppsDeploy    = "apps:deploy"
+)
+
 var (
 	apiKeyHeader = fmt.Sprintf("%s:%s", apiKeyName, apiKeyHeaderValue)
+	// ServiceAccountScopes may be granted to service account keys. Service
+	// accounts automate deployments and cannot manage identities.
+	ServiceAccountScopes = []string{
+		ScopeDomainsRead,
+		ScopeDomainsWrite,
+		ScopeAppsDeploy,
+	}
+	// Scopes is the full scope vocabulary understood by the API.
+	Scopes = []string{
+		ScopeIdentityRead,
+		ScopeIdentityWrite,
+		ScopeIdentityAdmin,
+		ScopeDomainsRead,
+		ScopeDomainsWrite,
+		ScopeAppsDeploy,
+	}
 )
 
 // API describes the global properties of the API server.
@@ -45,8 +81,14 @@ var _ = API(assets.AppName, func() {
 })
 
 // APIKeyAuth defines a security scheme that uses API keys.
-var APIKeyAuth = APIKeySecurity("api_key", func() {
+var APIKeyAuth = APIKeySecurity(apiKeyScheme, func() {
 	Description("Secures endpoint by requiring an API key.")
+	Scope(ScopeIdentityRead, "Read users and teams")
+	Scope(ScopeIdentityWrite, "Manage the calling user's own account and API keys")
+	Scope(ScopeIdentityAdmin, "Manage users, teams and team membership")
+	Scope(ScopeDomainsRead, "Read domains")
+	Scope(ScopeDomainsWrite, "Create, update and delete domains")
+	Scope(ScopeAppsDeploy, "Deploy and release applications")
 })
 
 var Origins = []string{
@@ -70,12 +112,13 @@ func commonResponses() {
 	Response("not-found", StatusNotFound)
 	Response("bad-request", StatusBadRequest)
 	Response("server-error", StatusInternalServerError)
+	Response("invalid-scopes", StatusForbidden)
 }
 
 // commonOptions provides a range of dsl schema applicable to all services.
 func commonCors() {
 	corsRules := func() {
-		cors.Headers("X-API-TOKEN", "Content-Type")
+		cors.Headers("X-API-TOKEN", "X-Tawny-Team", "Content-Type")
 		cors.Expose("X-API-TOKEN", "Content-Type")
 		cors.Methods("GET", "OPTIONS", "POST", "DELETE", "PATCH", "PUT")
 		cors.Credentials()
@@ -90,25 +133,47 @@ func createdAndUpdateAtResult() {
 	Attribute("updated_at", String, "Updated at", func() { Example("2024-04-21 11:43:02 +0000") })
 }
 
-// PaginationQueryParams returns the current_page and page_size values
-func PaginationQueryParams(page, pageSize int) (int32, int32) {
-	return int32(page), int32((pageSize - 1) * page)
-}
 func paginationParams() {
 	Param("page_size", Int)
 	Param("page_number", Int)
+	Param("cursor", String)
 }
 func paginationPayload() {
 	Attribute("page_size", Int, func() {
 		Description("The maximum number of results to return.")
 		Default(20)
+		Minimum(1)
+		Maximum(100)
 		Example(20)
 	})
 	Attribute("page_number", Int, func() {
 		Description("The page number to view")
 		Default(1)
+		Minimum(1)
 		Example(1)
 	})
+	Attribute("cursor", String, func() {
+		Description("Opaque cursor from the metadata of a previous page. Takes precedence over page_size and page_number.")
+		Example("eyJuIjoyLCJzIjoyMH0")
+	})
+}
+
+// requireScopes sets the scopes an API key must hold to call a method.
+func requireScopes(scopes ...string) {
+	Security(APIKeyAuth, func() {
+		for _, s := range scopes {
+			Scope(s)
+		}
+	})
+}
+
+// scopesEnum restricts an attribute to the known scopes.
+func scopesEnum() {
+	vals := make([]any, len(Scopes))
+	for i, s := range Scopes {
+		vals[i] = s
+	}
+	Enum(vals...)
 }
 func apiKeyAuth() {
 	APIKey(
@@ -125,6 +190,14 @@ var PaginationMetadata = Type("PaginationMetadata", func() {
 	Attribute("first_page", Int32, func() { Example(1) })
 	Attribute("last_page", Int32, func() { Example(10) })
 	Attribute("page_size", Int32, func() { Example(20) })
+	Attribute("next", String, "Link to the next page, absent on the last page", func() {
+		Example("/identity/users?page_number=2&page_size=20")
+	})
+	Attribute("prev", String, "Link to the previous page, absent on the first page", func() {
+		Example("/identity/users?page_number=1&page_size=20")
+	})
+	Attribute("next_cursor", String, "Cursor of the next page", func() { Example("eyJuIjoyLCJzIjoyMH0") })
+	Attribute("prev_cursor", String, "Cursor of the previous page", func() { Example("eyJuIjoxLCJzIjoyMH0") })
 	Required("total", "page_size", "first_page", "current_page", "last_page")
 })
 
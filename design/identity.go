@@ -11,7 +11,9 @@ var _ = Service("identity", func() {
 	commonErrors()
 	// Users
 	Method("createUser", func() {
-		Description("Create a new user. This will also generate a new team for that user.")
+		Description("Create a new user. This will also generate a new team for that user. " +
+			"Requires a platform admin.")
+		requireScopes(ScopeIdentityAdmin)
 		Payload(func() {
 			Attribute("user", UserIn)
 			apiKeyAuth()
@@ -27,6 +29,7 @@ var _ = Service("identity", func() {
 	})
 	Method("retrieveUser", func() {
 		Description("Retrieve a single user. Can only retrieve users from an associated team.")
+		requireScopes(ScopeIdentityRead)
 		Payload(func() {
 			Attribute("user_id", String, "UUID of the user", func() {
 				Pattern(userRx)
@@ -50,6 +53,7 @@ var _ = Service("identity", func() {
 	})
 	Method("listUsers", func() {
 		Description("Retrieve all users that this user can see from associated teams.")
+		requireScopes(ScopeIdentityRead)
 		Payload(func() {
 			apiKeyAuth()
 			paginationPayload()
@@ -64,9 +68,83 @@ var _ = Service("identity", func() {
 			commonResponses()
 		})
 	})
+	Method("updateUser", func() {
+		Description(
+			"Update a user's name, email or password. Changing the email requires it to be verified again. " +
+				"Changing the password requires the current password and is only possible for your own account. " +
+				"Another user's email can only be changed by a platform admin. " +
+				"Updating another user requires the identity:admin scope and an admin role in the current team.",
+		)
+		requireScopes(ScopeIdentityWrite)
+		Payload(func() {
+			Attribute("user_id", String, "UUID of the user", func() {
+				Pattern(userRx)
+				Example("user_1234567")
+			})
+			Attribute("name", String, "Name of the user", func() { MinLength(1); Example("Daniel") })
+			Attribute("email", String, "Email of the user", func() { Format(FormatEmail); Example("email@example.com") })
+			Attribute("password", String, "New password", func() { MinLength(8); Example("newPassword") })
+			Attribute("current_password", String, "Current password, required to change the password", func() {
+				Example("fakePassword")
+			})
+			apiKeyAuth()
+			Required("user_id", apiKeyName)
+		})
+		Result(UserResult)
+		HTTP(func() {
+			PATCH("/users/{user_id}")
+			Response(StatusOK)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
+	Method("deleteUser", func() {
+		Description(
+			"Delete a user along with their tokens, sessions and any teams they are the only member of. " +
+				"Users who are the only owner or admin of a team with other members cannot be deleted. " +
+				"Deleting another user requires a platform admin.",
+		)
+		requireScopes(ScopeIdentityWrite)
+		Payload(func() {
+			Attribute("user_id", String, "UUID of the user", func() {
+				Pattern(userRx)
+				Example("user_1234567")
+			})
+			apiKeyAuth()
+			Required("user_id", apiKeyName)
+		})
+		Result(Empty)
+		HTTP(func() {
+			DELETE("/users/{user_id}")
+			Response(StatusNoContent)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
+	Method("uploadProfilePhoto", func() {
+		Description("Upload a profile photo. The request body is a PNG, JPEG, GIF or WebP image of at most 2MB.")
+		requireScopes(ScopeIdentityWrite)
+		Payload(func() {
+			Attribute("user_id", String, "UUID of the user", func() {
+				Pattern(userRx)
+				Example("user_1234567")
+			})
+			apiKeyAuth()
+			Required("user_id", apiKeyName)
+		})
+		Result(UserResult)
+		HTTP(func() {
+			PUT("/users/{user_id}/photo")
+			SkipRequestBodyEncodeDecode()
+			Response(StatusOK)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
 	// Teams
 	Method("createTeam", func() {
-		Description("Create a new team")
+		Description("Create a new team owned by the caller. Requires a platform admin.")
+		requireScopes(ScopeIdentityAdmin)
 		Payload(func() {
 			Attribute("team", TeamIn)
 			apiKeyAuth()
@@ -80,11 +158,128 @@ var _ = Service("identity", func() {
 			commonResponses()
 		})
 	})
+	Method("listTeams", func() {
+		Description("List the teams the calling user belongs to.")
+		requireScopes(ScopeIdentityRead)
+		Payload(func() {
+			apiKeyAuth()
+			paginationPayload()
+			Required(apiKeyName)
+		})
+		Result(TeamsResult)
+		HTTP(func() {
+			GET("/teams")
+			Response(StatusOK)
+			Header(apiKeyHeader)
+			paginationParams()
+			commonResponses()
+		})
+	})
+	Method("retrieveTeam", func() {
+		Description("Retrieve a team the calling user belongs to, including its members and their roles.")
+		requireScopes(ScopeIdentityRead)
+		Payload(func() {
+			Attribute("team_id", String, func() { Example("team_0000000"); Pattern(teamRx) })
+			apiKeyAuth()
+			Required("team_id", apiKeyName)
+		})
+		Result(TeamResult)
+		HTTP(func() {
+			GET("/teams/{team_id}")
+			Response(StatusOK)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
+	Method("updateTeam", func() {
+		Description("Rename a team")
+		requireScopes(ScopeIdentityAdmin)
+		Payload(func() {
+			Attribute("team_id", String, func() { Example("team_0000000"); Pattern(teamRx) })
+			Attribute("name", String, "Name", func() { MinLength(1); Example("Dream Team") })
+			apiKeyAuth()
+			Required("team_id", "name", apiKeyName)
+		})
+		Result(TeamResult)
+		HTTP(func() {
+			PATCH("/teams/{team_id}")
+			Response(StatusOK)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
+	Method("deleteTeam", func() {
+		Description(
+			"Delete a team. The team's domains and cluster resources are removed first. " +
+				"Personal teams cannot be deleted. Only the owner may delete a team.",
+		)
+		requireScopes(ScopeIdentityAdmin)
+		Payload(func() {
+			Attribute("team_id", String, func() { Example("team_0000000"); Pattern(teamRx) })
+			apiKeyAuth()
+			Required("team_id", apiKeyName)
+		})
+		Result(Empty)
+		HTTP(func() {
+			DELETE("/teams/{team_id}")
+			Response(StatusNoContent)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
+	Method("transferTeamOwnership", func() {
+		Description(
+			"Make another member the owner of a team. The current owner becomes an admin. " +
+				"Personal teams cannot be transferred.",
+		)
+		requireScopes(ScopeIdentityAdmin)
+		Payload(func() {
+			Attribute("team_id", String, func() { Example("team_0000000"); Pattern(teamRx) })
+			Attribute("user_id", String, "Member to become the owner", func() {
+				Example("user_0000000")
+				Pattern(userRx)
+			})
+			apiKeyAuth()
+			Required("team_id", "user_id", apiKeyName)
+		})
+		Result(TeamResult)
+		HTTP(func() {
+			POST("/teams/{team_id}/transfer")
+			Response(StatusOK)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
+	Method("switchTeam", func() {
+		Description(
+			"Select the team the calling user acts within by default. Applies to web sessions and to API keys " +
+				"which are not bound to a team and do not send the X-Tawny-Team header.",
+		)
+		requireScopes(ScopeIdentityWrite)
+		Payload(func() {
+			Attribute("team_id", String, func() { Example("team_0000000"); Pattern(teamRx) })
+			apiKeyAuth()
+			Required("team_id", apiKeyName)
+		})
+		Result(TeamResult)
+		HTTP(func() {
+			POST("/teams/{team_id}/switch")
+			Response(StatusOK)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
 	Method("addTeamMember", func() {
-		Description("Add a user to a team")
+		Description("Add a user to a team with a role below that of the caller. Only owners can add owners.")
+		requireScopes(ScopeIdentityAdmin)
 		Payload(func() {
 			Attribute("user_id", String, func() { Example("user_0000000"); Pattern(userRx) })
 			Attribute("team_id", String, func() { Example("team_0000000"); Pattern(teamRx) })
+			Attribute("role", String, "Role of the user within the team", func() {
+				Enum("admin", "maintainer", "viewer")
+				Default("maintainer")
+				Example("maintainer")
+			})
 			apiKeyAuth()
 			Required("user_id", "team_id", apiKeyName)
 		})
@@ -97,7 +292,9 @@ var _ = Service("identity", func() {
 		})
 	})
 	Method("removeTeamMember", func() {
-		Description("Remove a team member from a team")
+		Description("Remove a team member from a team. Members with a role at or above that of the caller " +
+			"cannot be removed, except owners by owners. Admins and owners may remove themselves.")
+		requireScopes(ScopeIdentityAdmin)
 		Payload(func() {
 			Attribute("user_id", String, func() { Example("user_0000000"); Pattern(userRx) })
 			Attribute("team_id", String, func() { Example("team_0000000"); Pattern(teamRx) })
@@ -112,13 +309,333 @@ var _ = Service("identity", func() {
 			commonResponses()
 		})
 	})
+	// Email verification and password reset
+	Method("verifyEmail", func() {
+		Description("Verify the email address of a user with the token they were emailed.")
+		NoSecurity()
+		Payload(func() {
+			Attribute("token", String, "Verification token", func() { MinLength(1) })
+			Required("token")
+		})
+		Result(Empty)
+		HTTP(func() {
+			POST("/verify-email")
+			Response(StatusOK)
+			commonResponses()
+		})
+	})
+	Method("resendVerification", func() {
+		Description("Email a new verification link to the calling user.")
+		requireScopes(ScopeIdentityWrite)
+		Payload(func() {
+			apiKeyAuth()
+			Required(apiKeyName)
+		})
+		Result(Empty)
+		HTTP(func() {
+			POST("/verify-email/resend")
+			Response(StatusAccepted)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
+	Method("forgotPassword", func() {
+		Description(
+			"Email a password reset link. The response is the same whether or not the email belongs to a user.",
+		)
+		NoSecurity()
+		Payload(func() {
+			Attribute("email", String, "Email of the user", func() {
+				Format(FormatEmail)
+				Example("email@example.com")
+			})
+			Required("email")
+		})
+		Result(Empty)
+		HTTP(func() {
+			POST("/password/forgot")
+			Response(StatusAccepted)
+			commonResponses()
+		})
+	})
+	Method("resetPassword", func() {
+		Description("Set a new password with the token from a password reset email.")
+		NoSecurity()
+		Payload(func() {
+			Attribute("token", String, "Password reset token", func() { MinLength(1) })
+			Attribute("password", String, "New password", func() { MinLength(8); Example("fakePassword") })
+			Required("token", "password")
+		})
+		Result(Empty)
+		HTTP(func() {
+			POST("/password/reset")
+			Response(StatusOK)
+			commonResponses()
+		})
+	})
+	// Two-factor authentication
+	Method("enableTwoFactor", func() {
+		Description(
+			"Start two-factor enrolment for the calling user. Scan the provisioning URI as a QR code " +
+				"then confirm with a code from the authenticator app.",
+		)
+		requireScopes(ScopeIdentityWrite)
+		Payload(func() {
+			apiKeyAuth()
+			Required(apiKeyName)
+		})
+		Result(TwoFactorEnrollment)
+		HTTP(func() {
+			POST("/2fa")
+			Response(StatusCreated)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
+	Method("confirmTwoFactor", func() {
+		Description("Activate two-factor authentication. Recovery codes are returned once and cannot be retrieved again.")
+		requireScopes(ScopeIdentityWrite)
+		Payload(func() {
+			twoFactorCode()
+			apiKeyAuth()
+			Required("code", apiKeyName)
+		})
+		Result(RecoveryCodes)
+		HTTP(func() {
+			POST("/2fa/confirm")
+			Response(StatusOK)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
+	Method("disableTwoFactor", func() {
+		Description("Disable two-factor authentication. Not permitted while a team requires it.")
+		requireScopes(ScopeIdentityWrite)
+		Payload(func() {
+			twoFactorCode()
+			apiKeyAuth()
+			Required("code", apiKeyName)
+		})
+		Result(Empty)
+		HTTP(func() {
+			POST("/2fa/disable")
+			Response(StatusOK)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
+	Method("regenerateRecoveryCodes", func() {
+		Description("Replace all recovery codes with a new set.")
+		requireScopes(ScopeIdentityWrite)
+		Payload(func() {
+			twoFactorCode()
+			apiKeyAuth()
+			Required("code", apiKeyName)
+		})
+		Result(RecoveryCodes)
+		HTTP(func() {
+			POST("/2fa/recovery-codes")
+			Response(StatusOK)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
+	Method("setTeamTwoFactorPolicy", func() {
+		Description("Require every member of a team to use two-factor authentication.")
+		requireScopes(ScopeIdentityAdmin)
+		Payload(func() {
+			Attribute("team_id", String, func() { Example("team_0000000"); Pattern(teamRx) })
+			Attribute("required", Boolean, "Whether members must enrol in two-factor authentication")
+			apiKeyAuth()
+			Required("team_id", "required", apiKeyName)
+		})
+		Result(TeamResult)
+		HTTP(func() {
+			PUT("/teams/{team_id}/2fa")
+			Response(StatusOK)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
+	// Audit
+	Method("listAuditEvents", func() {
+		Description("List audit events for the current team, newest first. Requires an admin role.")
+		requireScopes(ScopeIdentityRead)
+		Payload(func() {
+			apiKeyAuth()
+			paginationPayload()
+			Attribute("actor", String, "Only events performed by this user or service account", func() {
+				Example("user_0000000")
+				Pattern(actorRx)
+			})
+			Attribute("resource", String, "Only events for this resource type", func() { Example("team") })
+			Attribute("since", String, "Only events at or after this time", func() {
+				Format(FormatDateTime)
+				Example("2024-01-01T00:00:00Z")
+			})
+			Attribute("until", String, "Only events before this time", func() {
+				Format(FormatDateTime)
+				Example("2024-02-01T00:00:00Z")
+			})
+			Required(apiKeyName)
+		})
+		Result(AuditEventsResult)
+		HTTP(func() {
+			GET("/audit-events")
+			Response(StatusOK)
+			Header(apiKeyHeader)
+			paginationParams()
+			Param("actor")
+			Param("resource")
+			Param("since")
+			Param("until")
+			commonResponses()
+		})
+	})
+	// API keys
+	Method("createToken", func() {
+		Description(
+			"Mint a new API key. The requested scopes must be a subset of the scopes held by the calling key.",
+		)
+		requireScopes(ScopeIdentityWrite)
+		Payload(func() {
+			Attribute("token", TokenIn)
+			apiKeyAuth()
+			Required("token", apiKeyName)
+		})
+		Result(TokenResult)
+		HTTP(func() {
+			POST("/tokens")
+			Response(StatusCreated)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
+	// Service accounts
+	Method("listServiceAccounts", func() {
+		Description("List the service accounts of a team.")
+		requireScopes(ScopeIdentityRead)
+		Payload(func() {
+			Attribute("team_id", String, func() { Example("team_0000000"); Pattern(teamRx) })
+			apiKeyAuth()
+			paginationPayload()
+			Required("team_id", apiKeyName)
+		})
+		Result(ServiceAccountsResult)
+		HTTP(func() {
+			GET("/teams/{team_id}/service-accounts")
+			Response(StatusOK)
+			Header(apiKeyHeader)
+			paginationParams()
+			commonResponses()
+		})
+	})
+	Method("createServiceAccount", func() {
+		Description(
+			"Create a service account owned by a team. Service accounts have no password and cannot sign in " +
+				"to the web UI, they authenticate with their own API keys. Requires an admin role.",
+		)
+		requireScopes(ScopeIdentityAdmin)
+		Payload(func() {
+			Attribute("team_id", String, func() { Example("team_0000000"); Pattern(teamRx) })
+			Attribute("service_account", ServiceAccountIn)
+			apiKeyAuth()
+			Required("team_id", "service_account", apiKeyName)
+		})
+		Result(ServiceAccountResult)
+		HTTP(func() {
+			POST("/teams/{team_id}/service-accounts")
+			Response(StatusCreated)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
+	Method("deleteServiceAccount", func() {
+		Description("Delete a service account and revoke its API keys. Requires an admin role.")
+		requireScopes(ScopeIdentityAdmin)
+		Payload(func() {
+			Attribute("team_id", String, func() { Example("team_0000000"); Pattern(teamRx) })
+			Attribute("service_account_id", String, func() { Example("sa_0000000"); Pattern(serviceAccountRx) })
+			apiKeyAuth()
+			Required("team_id", "service_account_id", apiKeyName)
+		})
+		Result(Empty)
+		HTTP(func() {
+			DELETE("/teams/{team_id}/service-accounts/{service_account_id}")
+			Response(StatusNoContent)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
+	Method("listServiceAccountTokens", func() {
+		Description("List the API keys of a service account. Keys themselves are never returned again.")
+		requireScopes(ScopeIdentityRead)
+		Payload(func() {
+			Attribute("team_id", String, func() { Example("team_0000000"); Pattern(teamRx) })
+			Attribute("service_account_id", String, func() { Example("sa_0000000"); Pattern(serviceAccountRx) })
+			apiKeyAuth()
+			Required("team_id", "service_account_id", apiKeyName)
+		})
+		Result(ArrayOf(ServiceAccountToken))
+		HTTP(func() {
+			GET("/teams/{team_id}/service-accounts/{service_account_id}/tokens")
+			Response(StatusOK)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
+	Method("createServiceAccountToken", func() {
+		Description(
+			"Mint an API key for a service account. Keys are bound to the account's team. Scopes are limited " +
+				"to domains and apps and must be held by the calling key. Requires an admin role.",
+		)
+		requireScopes(ScopeIdentityAdmin)
+		Payload(func() {
+			Attribute("team_id", String, func() { Example("team_0000000"); Pattern(teamRx) })
+			Attribute("service_account_id", String, func() { Example("sa_0000000"); Pattern(serviceAccountRx) })
+			Attribute("name", String, "Name of the key", func() { MinLength(1); Example("github-actions") })
+			Attribute("scopes", ArrayOf(String, func() {
+				Enum(ScopeDomainsRead, ScopeDomainsWrite, ScopeAppsDeploy)
+			}), "Scopes granted to the key", func() {
+				MinLength(1)
+				Example([]string{ScopeDomainsRead, ScopeAppsDeploy})
+			})
+			apiKeyAuth()
+			Required("team_id", "service_account_id", "name", "scopes", apiKeyName)
+		})
+		Result(TokenResult)
+		HTTP(func() {
+			POST("/teams/{team_id}/service-accounts/{service_account_id}/tokens")
+			Response(StatusCreated)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
+	Method("revokeServiceAccountToken", func() {
+		Description("Revoke an API key of a service account. Requires an admin role.")
+		requireScopes(ScopeIdentityAdmin)
+		Payload(func() {
+			Attribute("team_id", String, func() { Example("team_0000000"); Pattern(teamRx) })
+			Attribute("service_account_id", String, func() { Example("sa_0000000"); Pattern(serviceAccountRx) })
+			Attribute("token_id", Int64, func() { Example(12) })
+			apiKeyAuth()
+			Required("team_id", "service_account_id", "token_id", apiKeyName)
+		})
+		Result(Empty)
+		HTTP(func() {
+			DELETE("/teams/{team_id}/service-accounts/{service_account_id}/tokens/{token_id}")
+			Response(StatusNoContent)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
 })
 
 var UserIn = Type("User", func() {
 	Description("User object")
 	Attribute("name", String, "Name of the user", func() { Example("Daniel") })
-	Attribute("password", String, "ID of the user", func() { Example("fakePassword") })
-	Attribute("email", String, "Email of the user", func() { Example("email@example.com") })
+	Attribute("password", String, "Password of the user", func() { MinLength(8); Example("fakePassword") })
+	Attribute("email", String, "Email of the user", func() { Format(FormatEmail); Example("email@example.com") })
 	Required("name", "password", "email")
 })
 var UserResult = ResultType("application/vnd.tawny.user", func() {
@@ -129,6 +646,9 @@ var UserResult = ResultType("application/vnd.tawny.user", func() {
 	Attribute("name", String, "name", func() { Example("Daniel") })
 	Attribute("email", String, "Email", func() { Example("me@gmail.com") })
 	Attribute("role", String, "Role", func() { Example("admin") })
+	Attribute("profile_photo_url", String, "URL of the profile photo", func() {
+		Example("/media/profile-photos/user_1234567-0123456789abcdef.png")
+	})
 	createdAndUpdateAtResult()
 	Required("name", "email", "role")
 
@@ -137,6 +657,7 @@ var UserResult = ResultType("application/vnd.tawny.user", func() {
 		Attribute("name")
 		Attribute("email")
 		Attribute("role")
+		Attribute("profile_photo_url")
 		Attribute("created_at")
 		Attribute("updated_at")
 	})
@@ -163,6 +684,11 @@ var TeamResult = ResultType("application/vnd.tawny.team", func() {
 	})
 	Attribute("name", String, "Name", func() { Example("Dream Team") })
 	Attribute("personal_team", Boolean, "personal_team", func() { Example(false) })
+	Attribute("require_two_factor", Boolean, "Members must use two-factor authentication", func() {
+		Example(false)
+	})
+	Attribute("role", String, "Role of the calling user within the team", func() { Example("owner") })
+	Attribute("members", ArrayOf(TeamMember), "Members of the team")
 	createdAndUpdateAtResult()
 	Required("uuid", "name", "personal_team")
 
@@ -170,5 +696,173 @@ var TeamResult = ResultType("application/vnd.tawny.team", func() {
 		Attribute("uuid")
 		Attribute("name")
 		Attribute("personal_team")
+		Attribute("require_two_factor")
+		Attribute("role")
+		Attribute("members")
+		Attribute("created_at")
+		Attribute("updated_at")
 	})
 })
+var TeamsResult = ResultType("application/vnd.tawny.teams", func() {
+	TypeName("Teams")
+	Attributes(func() {
+		Attribute("teams", CollectionOf(TeamResult))
+		Attribute("metadata", PaginationMetadata)
+		Required("teams", "metadata")
+	})
+})
+var TeamMember = Type("TeamMember", func() {
+	Description("A member of a team")
+	Attribute("user_uuid", String, "User ID", func() { Example("user_1234567") })
+	Attribute("name", String, "Name", func() { Example("Daniel") })
+	Attribute("email", String, "Email", func() { Example("me@gmail.com") })
+	Attribute("role", String, "Role within the team", func() { Example("maintainer") })
+	Attribute("joined_at", String, "When the user joined the team", func() {
+		Example("2024-01-01 00:00:00 +0000 UTC")
+	})
+	Required("user_uuid", "name", "email", "role")
+})
+var TokenIn = Type("Token", func() {
+	Description("API key object")
+	Attribute("name", String, "Name of the key", func() { Example("ci-pipeline") })
+	Attribute("scopes", ArrayOf(String, scopesEnum), "Scopes granted to the key", func() {
+		Example([]string{ScopeDomainsRead, ScopeDomainsWrite})
+	})
+	Attribute("team_id", String, "Bind the key to a single team the user belongs to", func() {
+		Example("team_0000000")
+		Pattern(teamRx)
+	})
+	Required("name", "scopes")
+})
+var TokenResult = ResultType("application/vnd.tawny.token", func() {
+	TypeName("TokenResult")
+	Description("A newly minted API key. The key is only returned once.")
+	Attribute("id", Int64, "Key ID, used to revoke the key", func() { Example(12) })
+	Attribute("name", String, "Name of the key", func() { Example("ci-pipeline") })
+	Attribute("key", String, "API key", func() { Example("key_00000000000000000000") })
+	Attribute("scopes", ArrayOf(String), "Scopes granted to the key", func() {
+		Example([]string{ScopeDomainsRead, ScopeDomainsWrite})
+	})
+	Attribute("team_id", String, "Team the key is bound to", func() { Example("team_0000000") })
+	createdAndUpdateAtResult()
+	Required("name", "key", "scopes")
+
+	View(viewDefault, func() {
+		Attribute("id")
+		Attribute("name")
+		Attribute("key")
+		Attribute("scopes")
+		Attribute("team_id")
+		Attribute("created_at")
+	})
+})
+var ServiceAccountIn = Type("ServiceAccount", func() {
+	Description("Service account object")
+	Attribute("name", String, "Name, unique within the team", func() { MinLength(1); Example("ci") })
+	Attribute("description", String, "What the account is used for", func() { Example("Deploys from CI") })
+	Attribute("role", String, "Role of the account within the team", func() {
+		Enum("maintainer", "viewer")
+		Default("maintainer")
+		Example("maintainer")
+	})
+	Required("name")
+})
+var ServiceAccountResult = ResultType("application/vnd.tawny.service-account", func() {
+	TypeName("ServiceAccountResult")
+	Description("A team owned service account")
+	Attribute("uuid", String, "Service account ID", func() { Example("sa_1234567") })
+	Attribute("team_id", String, "Owning team", func() { Example("team_1234567") })
+	Attribute("name", String, "Name", func() { Example("ci") })
+	Attribute("description", String, "Description", func() { Example("Deploys from CI") })
+	Attribute("role", String, "Role within the team", func() { Example("maintainer") })
+	Attribute("created_by", String, "User who created the account, absent once they are deleted", func() {
+		Example("user_1234567")
+	})
+	createdAndUpdateAtResult()
+	Required("uuid", "team_id", "name", "description", "role")
+
+	View(viewDefault, func() {
+		Attribute("uuid")
+		Attribute("team_id")
+		Attribute("name")
+		Attribute("description")
+		Attribute("role")
+		Attribute("created_by")
+		Attribute("created_at")
+		Attribute("updated_at")
+	})
+})
+var ServiceAccountsResult = ResultType("application/vnd.tawny.service-accounts", func() {
+	TypeName("ServiceAccounts")
+	Attributes(func() {
+		Attribute("service_accounts", CollectionOf(ServiceAccountResult))
+		Attribute("metadata", PaginationMetadata)
+		Required("service_accounts", "metadata")
+	})
+})
+var ServiceAccountToken = Type("ServiceAccountToken", func() {
+	Description("An API key of a service account")
+	Attribute("id", Int64, func() { Example(12) })
+	Attribute("name", String, "Name of the key", func() { Example("github-actions") })
+	Attribute("scopes", ArrayOf(String), "Scopes granted to the key", func() {
+		Example([]string{ScopeDomainsRead, ScopeAppsDeploy})
+	})
+	Attribute("last_used_at", String, func() { Example("2024-01-01 00:00:00 +0000 UTC") })
+	Attribute("created_at", String, func() { Example("2024-01-01 00:00:00 +0000 UTC") })
+	Required("id", "name", "scopes", "created_at")
+})
+var AuditEvent = Type("AuditEvent", func() {
+	Description("A recorded action")
+	Attribute("id", Int64, func() { Example(1) })
+	Attribute("actor_user_id", String, "User who performed the action", func() { Example("user_0000000") })
+	Attribute("actor_service_account_id", String, "Service account which performed the action", func() {
+		Example("sa_0000000")
+	})
+	Attribute("actor_token_id", Int64, "API key used, absent for web sessions", func() { Example(12) })
+	Attribute("team_id", String, func() { Example("team_0000000") })
+	Attribute("action", String, func() { Example("domains.createDomain") })
+	Attribute("resource_type", String, func() { Example("domain") })
+	Attribute("resource_id", String, func() { Example("example.com") })
+	Attribute("request_id", String, func() { Example("N6oC2hpT") })
+	Attribute("ip_address", String, func() { Example("203.0.113.10") })
+	Attribute("outcome", String, func() {
+		Enum("success", "failure", "denied")
+		Example("success")
+	})
+	Attribute("created_at", String, func() { Example("2024-01-01 00:00:00 +0000 UTC") })
+	Required("id", "action", "resource_type", "resource_id", "request_id", "outcome", "created_at")
+})
+var AuditEventsResult = ResultType("application/vnd.tawny.audit-events", func() {
+	TypeName("AuditEvents")
+	Attributes(func() {
+		Attribute("events", ArrayOf(AuditEvent))
+		Attribute("metadata", PaginationMetadata)
+		Required("events", "metadata")
+	})
+})
+var TwoFactorEnrollment = ResultType("application/vnd.tawny.two-factor-enrollment", func() {
+	TypeName("TwoFactorEnrollment")
+	Description("A pending two-factor enrolment")
+	Attribute("secret", String, "Base32 encoded TOTP secret for manual entry", func() {
+		Example("JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP")
+	})
+	Attribute("provisioning_uri", String, "otpauth:// URI to render as a QR code", func() {
+		Example("otpauth://totp/Tawny:me@example.com?issuer=Tawny&secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP")
+	})
+	Required("secret", "provisioning_uri")
+})
+var RecoveryCodes = ResultType("application/vnd.tawny.recovery-codes", func() {
+	TypeName("RecoveryCodes")
+	Description("Single use recovery codes. Store them somewhere safe.")
+	Attribute("recovery_codes", ArrayOf(String), func() { Example([]string{"abcde-fghij"}) })
+	Required("recovery_codes")
+})
+
+// twoFactorCode is a TOTP code or a recovery code.
+func twoFactorCode() {
+	Attribute("code", String, "Code from the authenticator app or a recovery code", func() {
+		MinLength(6)
+		MaxLength(11)
+		Example("123456")
+	})
+}
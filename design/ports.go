@@ -0,0 +1,129 @@
+package design
+
+import (
+	. "goa.design/goa/v3/dsl"
+)
+
+var _ = Service("ports", func() {
+	Description("Ports of an app exposed on the traefik TCP and UDP entrypoints configured for the cluster, " +
+		"e.g. for databases and game servers. Port mappings are governed by the domain roles and scopes.")
+	HTTP(func() {
+		Path("/ports")
+	})
+	Security(APIKeyAuth)
+	commonErrors()
+	Method("listPorts", func() {
+		Description("List the port mappings of an app")
+		requireScopes(ScopeDomainsRead)
+		Payload(func() {
+			apiKeyAuth()
+			Attribute("app_id", String, func() { Example("my-app") })
+			paginationPayload()
+			Required(apiKeyName, "app_id")
+		})
+		Result(PortsResult)
+		HTTP(func() {
+			GET("/{app_id}")
+			Response(StatusOK)
+			Header(apiKeyHeader)
+			paginationParams()
+			commonResponses()
+		})
+	})
+	Method("createPort", func() {
+		Description("Map an entrypoint port to a port of the app's service. TCP mappings with TLS passthrough " +
+			"are routed by hostname, so each hostname of an entrypoint, or the entrypoint as a whole when no " +
+			"hostname is given, can be mapped once. The app's Service must be labelled tawny.sh/team with the " +
+			"ID of the team.")
+		requireScopes(ScopeDomainsWrite)
+		Payload(func() {
+			apiKeyAuth()
+			Attribute("app_id", String, func() { Example("my-app") })
+			Attribute("port", Int32, "Port of the entrypoint clients connect to", func() {
+				Minimum(1)
+				Maximum(65535)
+				Example(5432)
+			})
+			Attribute("protocol", String, func() {
+				Enum("tcp", "udp")
+				Default("tcp")
+				Example("tcp")
+			})
+			Attribute("service_port", Int32, "Port of the app service to forward to", func() {
+				Minimum(1)
+				Maximum(65535)
+				Example(5432)
+			})
+			Attribute("passthrough", Boolean, func() {
+				Description("Forward TLS connections without terminating them, leaving the app to serve " +
+					"its own certificate. TCP only.")
+				Default(false)
+			})
+			Attribute("hostname", String, func() {
+				Description("Hostname (SNI) routed to the app when passthrough is set. Every hostname when " +
+					"empty.")
+				Pattern(domainRx)
+				Example("db.example.com")
+			})
+			Required(apiKeyName, "app_id", "port", "service_port")
+		})
+		Result(PortResult)
+		HTTP(func() {
+			POST("/{app_id}")
+			Response(StatusCreated)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
+	Method("deletePort", func() {
+		Description("Remove a port mapping of an app")
+		requireScopes(ScopeDomainsWrite)
+		Payload(func() {
+			apiKeyAuth()
+			Attribute("app_id", String, func() { Example("my-app") })
+			Attribute("port_id", String, func() { Example("port_1234567") })
+			Required(apiKeyName, "app_id", "port_id")
+		})
+		Result(Empty)
+		HTTP(func() {
+			DELETE("/{app_id}/{port_id}")
+			Response(StatusOK)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
+})
+
+var PortResult = ResultType("application/vnd.tawny.port", func() {
+	TypeName("PortResult")
+	Description("A port of an app exposed on an entrypoint")
+	Attribute("port_id", String, func() { Example("port_1234567") })
+	Attribute("app_id", String, func() { Example("my-app") })
+	Attribute("entrypoint", String, "traefik entrypoint the port is exposed on", func() { Example("postgres") })
+	Attribute("port", Int32, "Port of the entrypoint", func() { Example(5432) })
+	Attribute("protocol", String, func() { Example("tcp") })
+	Attribute("service_port", Int32, "Port of the app service", func() { Example(5432) })
+	Attribute("passthrough", Boolean, func() { Example(true) })
+	Attribute("hostname", String, func() { Example("db.example.com") })
+	Attribute("created_at", String, func() { Example("2024-01-01 00:00:00 +0000 UTC") })
+	Required("port_id", "app_id", "entrypoint", "port", "protocol", "service_port", "passthrough")
+
+	View(viewDefault, func() {
+		Attribute("port_id")
+		Attribute("app_id")
+		Attribute("entrypoint")
+		Attribute("port")
+		Attribute("protocol")
+		Attribute("service_port")
+		Attribute("passthrough")
+		Attribute("hostname")
+		Attribute("created_at")
+	})
+})
+
+var PortsResult = ResultType("application/vnd.tawny.ports", func() {
+	TypeName("PortsResult")
+	Attribute("ports", CollectionOf(PortResult))
+	Attribute("metadata", PaginationMetadata)
+	Required("ports", "metadata")
+})
@@ -0,0 +1,161 @@
+package design
+
+import (
+	. "goa.design/goa/v3/dsl"
+)
+
+var _ = Service("releases", func() {
+	Description("Releases of a new version of an app alongside the running one. The new version gets a " +
+		"Deployment and Service of its own, and the routes of a domain share the app's requests between " +
+		"both versions by weight until the release is promoted or aborted.")
+	HTTP(func() {
+		Path("/releases")
+	})
+	Security(APIKeyAuth)
+	commonErrors()
+	Method("getRelease", func() {
+		Description("Show the active release of an app")
+		requireScopes(ScopeAppsDeploy)
+		Payload(func() {
+			apiKeyAuth()
+			Attribute("app_id", String, func() { Example("my-app") })
+			Required(apiKeyName, "app_id")
+		})
+		Result(ReleaseResult)
+		HTTP(func() {
+			GET("/{app_id}")
+			Response(StatusOK)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
+	Method("startRelease", func() {
+		Description("Stand up a new version of an app next to the running one. A canary release sends the " +
+			"weight of the domain's requests for the app to the new version; a blue-green release sends it " +
+			"none until promoted. The app's Service and Deployment must be labelled tawny.sh/team with " +
+			"the ID of the team.")
+		requireScopes(ScopeAppsDeploy)
+		Payload(func() {
+			apiKeyAuth()
+			Attribute("app_id", String, func() { Example("my-app") })
+			Attribute("domain", String, "Domain whose routes to the app are shared between the versions", func() {
+				Pattern(domainRx)
+				Example("example.com")
+			})
+			Attribute("image", String, "Image of the new version", func() {
+				Pattern(imageRx)
+				MaxLength(255)
+				Example("ghcr.io/acme/my-app:v2")
+			})
+			Attribute("container", String, "Container running the image. Defaults to the first container.", func() {
+				Pattern(containerRx)
+				Example("web")
+			})
+			Attribute("strategy", String, func() {
+				Enum("canary", "blue_green")
+				Default("canary")
+				Example("canary")
+			})
+			Attribute("weight", Int32, func() {
+				Description("Percentage of requests sent to the new version. Defaults to 10 for canary " +
+					"releases and 0 for blue-green releases.")
+				Minimum(0)
+				Maximum(100)
+				Example(10)
+			})
+			Required(apiKeyName, "app_id", "domain", "image")
+		})
+		Result(ReleaseResult)
+		HTTP(func() {
+			POST("/{app_id}")
+			Response(StatusCreated)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
+	Method("shiftRelease", func() {
+		Description("Change the percentage of requests sent to the new version. Blue-green releases send " +
+			"all requests to one version, so take 0 or 100.")
+		requireScopes(ScopeAppsDeploy)
+		Payload(func() {
+			apiKeyAuth()
+			Attribute("app_id", String, func() { Example("my-app") })
+			Attribute("weight", Int32, "Percentage of requests sent to the new version", func() {
+				Minimum(0)
+				Maximum(100)
+				Example(50)
+			})
+			Required(apiKeyName, "app_id", "weight")
+		})
+		Result(ReleaseResult)
+		HTTP(func() {
+			PUT("/{app_id}/weight")
+			Response(StatusOK)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
+	Method("promoteRelease", func() {
+		Description("Make the new version the app's: the app's Service is pointed at it, every request is " +
+			"sent to it, and the Deployment of the old version is removed.")
+		requireScopes(ScopeAppsDeploy)
+		Payload(func() {
+			apiKeyAuth()
+			Attribute("app_id", String, func() { Example("my-app") })
+			Required(apiKeyName, "app_id")
+		})
+		Result(ReleaseResult)
+		HTTP(func() {
+			POST("/{app_id}/promote")
+			Response(StatusOK)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
+	Method("abortRelease", func() {
+		Description("Send every request back to the running version and remove the new version")
+		requireScopes(ScopeAppsDeploy)
+		Payload(func() {
+			apiKeyAuth()
+			Attribute("app_id", String, func() { Example("my-app") })
+			Required(apiKeyName, "app_id")
+		})
+		Result(ReleaseResult)
+		HTTP(func() {
+			POST("/{app_id}/abort")
+			Response(StatusOK)
+			Header(apiKeyHeader)
+			commonResponses()
+		})
+	})
+})
+
+var ReleaseResult = ResultType("application/vnd.tawny.release", func() {
+	TypeName("ReleaseResult")
+	Description("A release of a new version of an app")
+	Attribute("release_id", String, func() { Example("rel_1234567") })
+	Attribute("app_id", String, func() { Example("my-app") })
+	Attribute("domain", String, func() { Example("example.com") })
+	Attribute("strategy", String, func() { Example("canary") })
+	Attribute("image", String, func() { Example("ghcr.io/acme/my-app:v2") })
+	Attribute("container", String, func() { Example("web") })
+	Attribute("weight", Int32, "Percentage of requests sent to the new version", func() { Example(10) })
+	Attribute("status", String, func() {
+		Description("active while both versions run, then promoted or aborted")
+		Example("active")
+	})
+	Attribute("created_at", String, func() { Example("2024-01-01 00:00:00 +0000 UTC") })
+	Required("release_id", "app_id", "domain", "strategy", "image", "container", "weight", "status")
+
+	View(viewDefault, func() {
+		Attribute("release_id")
+		Attribute("app_id")
+		Attribute("domain")
+		Attribute("strategy")
+		Attribute("image")
+		Attribute("container")
+		Attribute("weight")
+		Attribute("status")
+		Attribute("created_at")
+	})
+})
@@ -0,0 +1,201 @@
+// Package account implements the email verification and password reset
+// flows shared by the API and the web UI.
+package account
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/danielmichaels/tawny/internal/crypt"
+	"github.com/danielmichaels/tawny/internal/logger"
+	"github.com/danielmichaels/tawny/internal/mailer"
+	"github.com/danielmichaels/tawny/internal/storage"
+	"github.com/danielmichaels/tawny/internal/store"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	PurposeEmailVerification = "email_verification"
+	PurposePasswordReset     = "password_reset"
+
+	MinPasswordLength = 8
+)
+
+var (
+	// ErrInvalidToken is returned for unknown, expired or already used tokens.
+	ErrInvalidToken    = errors.New("token is invalid or has expired")
+	ErrPasswordTooWeak = fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	ErrAlreadyVerified = errors.New("email address is already verified")
+	ErrUserNotFound    = errors.New("user not found")
+)
+
+// Service issues and redeems single use tokens delivered by email.
+type Service struct {
+	DB     *store.Queries
+	Mailer mailer.Mailer
+	Logger *logger.Logger
+	// Cipher encrypts two-factor secrets. Two-factor enrolment is unavailable
+	// when it is nil.
+	Cipher *crypt.Cipher
+	// Issuer is shown as the account name in authenticator apps.
+	Issuer string
+	// Files stores profile photos. Uploads are unavailable when it is nil.
+	Files storage.Store
+	// Teams removes the cluster resources of teams deleted along with their
+	// last member.
+	Teams TeamResources
+	// BaseURL is the public URL of the web UI which links in emails point to.
+	BaseURL              string
+	VerificationLifetime time.Duration
+	ResetLifetime        time.Duration
+}
+
+// TeamResources deletes the cluster resources belonging to a team.
+type TeamResources interface {
+	DeleteTeamResources(ctx context.Context, teamID string) error
+}
+
+// SendVerification emails userID a link to verify their email address.
+func (s *Service) SendVerification(ctx context.Context, userID string) error {
+	u, err := s.DB.GetUserVerificationStatus(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u.EmailVerifiedAt.Valid {
+		return ErrAlreadyVerified
+	}
+	token, err := s.issue(ctx, userID, PurposeEmailVerification, s.VerificationLifetime)
+	if err != nil {
+		return err
+	}
+	return s.Mailer.Send(ctx, mailer.Message{
+		To:      u.Email.String,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Confirm your email address by visiting the link below.\n\n%s\n\nThe link expires in %s.\n",
+			s.link("/verify-email", token),
+			s.VerificationLifetime,
+		),
+	})
+}
+
+// VerifyEmail redeems an email verification token.
+func (s *Service) VerifyEmail(ctx context.Context, token string) (string, error) {
+	userID, err := s.consume(ctx, token, PurposeEmailVerification)
+	if err != nil {
+		return "", err
+	}
+	if err := s.DB.MarkEmailVerified(ctx, userID); err != nil {
+		return "", err
+	}
+	return userID, nil
+}
+
+// RequestPasswordReset emails a reset link if email belongs to a user. Unknown
+// addresses are silently ignored so that callers cannot enumerate accounts.
+func (s *Service) RequestPasswordReset(ctx context.Context, email string) error {
+	u, err := s.DB.GetUserCredentialsByEmail(ctx, pgtype.Text{String: email, Valid: true})
+	if errors.Is(err, pgx.ErrNoRows) {
+		s.Logger.Info().Str("email", email).Msg("password reset requested for unknown email")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	token, err := s.issue(ctx, u.Uuid, PurposePasswordReset, s.ResetLifetime)
+	if err != nil {
+		return err
+	}
+	return s.Mailer.Send(ctx, mailer.Message{
+		To:      u.Email.String,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"A password reset was requested for your account. Choose a new password by visiting the link below.\n\n"+
+				"%s\n\nThe link expires in %s. If you did not request this you can ignore this email.\n",
+			s.link("/reset-password", token),
+			s.ResetLifetime,
+		),
+	})
+}
+
+// ResetPassword redeems a password reset token and sets a new password. Any
+// other outstanding reset tokens and all web sessions for the user are revoked.
+func (s *Service) ResetPassword(ctx context.Context, token, password string) error {
+	if len(password) < MinPasswordLength {
+		return ErrPasswordTooWeak
+	}
+	userID, err := s.consume(ctx, token, PurposePasswordReset)
+	if err != nil {
+		return err
+	}
+	hash, err := store.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	err = s.DB.UpdateUserPassword(ctx, store.UpdateUserPasswordParams{
+		Uuid:     userID,
+		Password: pgtype.Text{String: hash, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	err = s.DB.RevokeUserTokens(ctx, store.RevokeUserTokensParams{
+		UserID:  userID,
+		Purpose: PurposePasswordReset,
+	})
+	if err != nil {
+		return err
+	}
+	// Receiving the reset email proves ownership of the address.
+	if err := s.DB.MarkEmailVerified(ctx, userID); err != nil {
+		return err
+	}
+	return s.DB.DeleteUserSessions(ctx, userID)
+}
+
+func (s *Service) issue(ctx context.Context, userID, purpose string, lifetime time.Duration) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	err := s.DB.CreateUserToken(ctx, store.CreateUserTokenParams{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(lifetime), Valid: true},
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *Service) consume(ctx context.Context, token, purpose string) (string, error) {
+	userID, err := s.DB.ConsumeUserToken(ctx, store.ConsumeUserTokenParams{
+		TokenHash: hashToken(token),
+		Purpose:   purpose,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrInvalidToken
+	}
+	return userID, err
+}
+
+func (s *Service) link(path, token string) string {
+	return strings.TrimRight(s.BaseURL, "/") + path + "?" + url.Values{"token": {token}}.Encode()
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
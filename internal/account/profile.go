@@ -0,0 +1,208 @@
+package account
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/danielmichaels/tawny/internal/store"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// MaxProfilePhotoSize is the largest profile photo accepted, in bytes.
+const MaxProfilePhotoSize = 2 << 20
+
+var (
+	ErrEmailTaken        = errors.New("a user with this email already exists")
+	ErrIncorrectPassword = errors.New("current password is incorrect")
+	// ErrSoleAdmin is returned when deleting a user would leave a team with
+	// members but nobody able to administer it.
+	ErrSoleAdmin        = errors.New("user is the only owner or admin of a team with other members")
+	ErrStorageDisabled  = errors.New("file storage is not configured")
+	ErrPhotoTooLarge    = fmt.Errorf("profile photo must be smaller than %d bytes", MaxProfilePhotoSize)
+	ErrPhotoUnsupported = errors.New("profile photo must be a PNG, JPEG, GIF or WebP image")
+)
+
+var photoExtensions = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpg",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+}
+
+// UpdateProfile changes the name and/or email of userID. Nil values are left
+// unchanged. A new email address must be verified again so a verification
+// link is sent to it.
+func (s *Service) UpdateProfile(ctx context.Context, userID string, name, email *string) (store.UpdateUserRow, error) {
+	arg := store.UpdateUserParams{Uuid: userID}
+	if name != nil {
+		arg.Name = pgtype.Text{String: strings.TrimSpace(*name), Valid: true}
+	}
+	if email != nil {
+		arg.Email = pgtype.Text{String: strings.ToLower(strings.TrimSpace(*email)), Valid: true}
+	}
+	u, err := s.DB.UpdateUser(ctx, arg)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return u, ErrEmailTaken
+		}
+		return u, err
+	}
+	if email != nil && !u.EmailVerifiedAt.Valid {
+		// The change is saved, a failed mail can be retried by resending.
+		if err := s.SendVerification(ctx, userID); err != nil {
+			s.Logger.Error().Err(err).Str("user", userID).Msg("error sending verification email")
+		}
+	}
+	return u, nil
+}
+
+// ChangePassword sets a new password after checking the current one. Web
+// sessions and outstanding password reset links are revoked.
+func (s *Service) ChangePassword(ctx context.Context, userID, current, password string) error {
+	if len(password) < MinPasswordLength {
+		return ErrPasswordTooWeak
+	}
+	hash, err := s.DB.GetUserPassword(ctx, userID)
+	if err != nil {
+		return err
+	}
+	// Users provisioned through single sign-on have no password to check.
+	if !hash.Valid {
+		return ErrIncorrectPassword
+	}
+	ok, err := store.Matches(current, hash.String)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrIncorrectPassword
+	}
+	newHash, err := store.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	err = s.DB.UpdateUserPassword(ctx, store.UpdateUserPasswordParams{
+		Uuid:     userID,
+		Password: pgtype.Text{String: newHash, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	err = s.DB.RevokeUserTokens(ctx, store.RevokeUserTokensParams{
+		UserID:  userID,
+		Purpose: PurposePasswordReset,
+	})
+	if err != nil {
+		return err
+	}
+	return s.DB.DeleteUserSessions(ctx, userID)
+}
+
+// SetProfilePhoto validates and stores an uploaded image as the profile photo
+// of userID, removing the previous photo. The stored location is returned.
+func (s *Service) SetProfilePhoto(ctx context.Context, userID string, r io.Reader) (string, error) {
+	if s.Files == nil {
+		return "", ErrStorageDisabled
+	}
+	b, err := io.ReadAll(io.LimitReader(r, MaxProfilePhotoSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(b) > MaxProfilePhotoSize {
+		return "", ErrPhotoTooLarge
+	}
+	// The content is sniffed rather than trusting the client's content type.
+	contentType := http.DetectContentType(b)
+	ext, ok := photoExtensions[contentType]
+	if !ok {
+		return "", ErrPhotoUnsupported
+	}
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	key := fmt.Sprintf("profile-photos/%s-%s.%s", userID, hex.EncodeToString(suffix), ext)
+	location, err := s.Files.Put(ctx, key, bytes.NewReader(b), contentType)
+	if err != nil {
+		return "", err
+	}
+	previous, err := s.DB.UpdateProfilePhoto(ctx, store.UpdateProfilePhotoParams{
+		Uuid:             userID,
+		ProfilePhotoPath: pgtype.Text{String: location, Valid: true},
+	})
+	if err != nil {
+		_ = s.Files.Delete(ctx, location)
+		return "", err
+	}
+	s.removePhoto(ctx, previous)
+	return location, nil
+}
+
+// DeleteUser removes userID. Memberships, tokens, sessions and identities are
+// removed by the database. Teams the user was the only member of, including
+// their personal team, are deleted with them along with their cluster
+// resources. Users who are the only owner or
+// admin of a team with other members must hand over the team first.
+func (s *Service) DeleteUser(ctx context.Context, userID string) error {
+	owner := pgtype.Text{String: userID, Valid: true}
+	sole, err := s.DB.ListSoleAdminTeams(ctx, owner)
+	if err != nil {
+		return err
+	}
+	if len(sole) > 0 {
+		names := make([]string, len(sole))
+		for i, t := range sole {
+			names[i] = t.Name
+		}
+		return fmt.Errorf("%w: %s", ErrSoleAdmin, strings.Join(names, ", "))
+	}
+	teams, err := s.DB.ListSoleMemberTeams(ctx, owner)
+	if err != nil {
+		return err
+	}
+	photo, err := s.DB.GetUserProfilePhoto(ctx, userID)
+	if err != nil {
+		return err
+	}
+	// Cluster resources are removed before the rows which reference them so
+	// that a failure can be retried rather than orphaning resources.
+	for _, teamID := range teams {
+		if err := s.Teams.DeleteTeamResources(ctx, teamID); err != nil {
+			return fmt.Errorf("deleting resources of team %s: %w", teamID, err)
+		}
+	}
+	n, err := s.DB.DeleteUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrUserNotFound
+	}
+	if len(teams) > 0 {
+		if err := s.DB.DeleteTeams(ctx, teams); err != nil {
+			return err
+		}
+	}
+	s.removePhoto(ctx, photo)
+	return nil
+}
+
+// removePhoto deletes a stored photo. Failures leave an orphaned file but do
+// not fail the request.
+func (s *Service) removePhoto(ctx context.Context, location pgtype.Text) {
+	if s.Files == nil || !location.Valid || location.String == "" {
+		return
+	}
+	if err := s.Files.Delete(ctx, location.String); err != nil {
+		s.Logger.Warn().Err(err).Str("location", location.String).Msg("error removing profile photo")
+	}
+}
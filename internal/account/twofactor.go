@@ -0,0 +1,258 @@
+package account
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/danielmichaels/tawny/internal/store"
+	"github.com/danielmichaels/tawny/internal/totp"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	PurposeTwoFactorLogin = "two_factor_login"
+
+	// TwoFactorLoginLifetime bounds the time between the password and second
+	// factor steps of a login.
+	TwoFactorLoginLifetime = 5 * time.Minute
+	recoveryCodeCount      = 10
+)
+
+var (
+	ErrTwoFactorUnavailable = errors.New("two-factor authentication requires an encryption key to be configured")
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnrolled = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorRequired    = errors.New("two-factor authentication is required by one of your teams")
+	ErrInvalidCode          = errors.New("authentication code is invalid")
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactorStatus describes a user's enrolment.
+type TwoFactorStatus struct {
+	Enabled bool
+	// Required is true when a team the user belongs to enforces 2FA.
+	Required      bool
+	RecoveryCodes int64
+}
+
+// TwoFactorStatus returns the enrolment state for userID.
+func (s *Service) TwoFactorStatus(ctx context.Context, userID string) (TwoFactorStatus, error) {
+	u, err := s.DB.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return TwoFactorStatus{}, err
+	}
+	required, err := s.DB.IsTwoFactorRequired(ctx, pgtype.Text{String: userID, Valid: true})
+	if err != nil {
+		return TwoFactorStatus{}, err
+	}
+	st := TwoFactorStatus{Enabled: u.TwoFactorConfirmedAt.Valid, Required: required}
+	if st.Enabled {
+		st.RecoveryCodes, err = s.DB.CountRecoveryCodes(ctx, userID)
+	}
+	return st, err
+}
+
+// EnrollTwoFactor stores a new secret for userID. The enrolment is pending
+// until ConfirmTwoFactor proves the user's authenticator is in sync. The
+// secret and its otpauth:// provisioning URI are returned for display.
+func (s *Service) EnrollTwoFactor(ctx context.Context, userID string) (string, string, error) {
+	if s.Cipher == nil {
+		return "", "", ErrTwoFactorUnavailable
+	}
+	u, err := s.DB.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return "", "", err
+	}
+	if u.TwoFactorConfirmedAt.Valid {
+		return "", "", ErrTwoFactorEnabled
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	sealed, err := s.Cipher.Encrypt(secret)
+	if err != nil {
+		return "", "", err
+	}
+	err = s.DB.SetTwoFactorSecret(ctx, store.SetTwoFactorSecretParams{
+		Uuid:            userID,
+		TwoFactorSecret: pgtype.Text{String: sealed, Valid: true},
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return secret, totp.URI(s.Issuer, u.Email.String, secret), nil
+}
+
+// ConfirmTwoFactor activates a pending enrolment and returns a fresh set of
+// recovery codes. The codes are only ever shown once.
+func (s *Service) ConfirmTwoFactor(ctx context.Context, userID, code string) ([]string, error) {
+	u, err := s.DB.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.TwoFactorConfirmedAt.Valid {
+		return nil, ErrTwoFactorEnabled
+	}
+	if !u.TwoFactorSecret.Valid {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	secret, err := s.secret(u)
+	if err != nil {
+		return nil, err
+	}
+	counter, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+	err = s.DB.ConfirmTwoFactor(ctx, store.ConfirmTwoFactorParams{
+		Uuid:                 userID,
+		TwoFactorLastCounter: pgtype.Int8{Int64: int64(counter), Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.replaceRecoveryCodes(ctx, userID)
+}
+
+// VerifyTwoFactor checks a TOTP or recovery code for userID. Each TOTP time
+// step and each recovery code can only be used once.
+func (s *Service) VerifyTwoFactor(ctx context.Context, userID, code string) error {
+	u, err := s.DB.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !u.TwoFactorConfirmedAt.Valid {
+		return ErrTwoFactorNotEnrolled
+	}
+	code = strings.TrimSpace(code)
+	if len(code) != totp.Digits {
+		n, err := s.DB.UseRecoveryCode(ctx, store.UseRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: hashToken(normaliseRecoveryCode(code)),
+		})
+		if err != nil {
+			return err
+		}
+		if n != 1 {
+			return ErrInvalidCode
+		}
+		s.Logger.Info().Str("user", userID).Msg("recovery code used")
+		return nil
+	}
+	secret, err := s.secret(u)
+	if err != nil {
+		return err
+	}
+	counter, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return ErrInvalidCode
+	}
+	n, err := s.DB.AdvanceTwoFactorCounter(ctx, store.AdvanceTwoFactorCounterParams{
+		Uuid:                 userID,
+		TwoFactorLastCounter: pgtype.Int8{Int64: int64(counter), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if n != 1 {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+// DisableTwoFactor removes the enrolment after checking code. Users in a team
+// which requires 2FA cannot disable it.
+func (s *Service) DisableTwoFactor(ctx context.Context, userID, code string) error {
+	required, err := s.DB.IsTwoFactorRequired(ctx, pgtype.Text{String: userID, Valid: true})
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrTwoFactorRequired
+	}
+	if err := s.VerifyTwoFactor(ctx, userID, code); err != nil {
+		return err
+	}
+	if err := s.DB.DisableTwoFactor(ctx, userID); err != nil {
+		return err
+	}
+	return s.DB.DeleteRecoveryCodes(ctx, userID)
+}
+
+// RegenerateRecoveryCodes invalidates existing recovery codes and returns a
+// new set after checking code.
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	if err := s.VerifyTwoFactor(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	return s.replaceRecoveryCodes(ctx, userID)
+}
+
+// BeginTwoFactorLogin returns a token identifying a login which has passed
+// the first factor and is waiting for a code.
+func (s *Service) BeginTwoFactorLogin(ctx context.Context, userID string) (string, error) {
+	return s.issue(ctx, userID, PurposeTwoFactorLogin, TwoFactorLoginLifetime)
+}
+
+// CompleteTwoFactorLogin checks code for the pending login. The token is
+// consumed whatever the outcome so a failed code requires signing in again,
+// which bounds guessing to one attempt per password check.
+func (s *Service) CompleteTwoFactorLogin(ctx context.Context, token, code string) (string, error) {
+	userID, err := s.DB.GetUserTokenOwner(ctx, store.GetUserTokenOwnerParams{
+		TokenHash: hashToken(token),
+		Purpose:   PurposeTwoFactorLogin,
+	})
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	verifyErr := s.VerifyTwoFactor(ctx, userID, code)
+	if _, err := s.consume(ctx, token, PurposeTwoFactorLogin); err != nil {
+		return "", err
+	}
+	if verifyErr != nil {
+		return "", verifyErr
+	}
+	return userID, nil
+}
+
+func (s *Service) secret(u store.GetTwoFactorRow) (string, error) {
+	if s.Cipher == nil {
+		return "", ErrTwoFactorUnavailable
+	}
+	return s.Cipher.Decrypt(u.TwoFactorSecret.String)
+}
+
+func (s *Service) replaceRecoveryCodes(ctx context.Context, userID string) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		c := strings.ToLower(recoveryEncoding.EncodeToString(b))[:10]
+		codes[i] = c[:5] + "-" + c[5:]
+		hashes[i] = hashToken(c)
+	}
+	if err := s.DB.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return nil, err
+	}
+	err := s.DB.CreateRecoveryCodes(ctx, store.CreateRecoveryCodesParams{
+		UserID:     userID,
+		CodeHashes: hashes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func normaliseRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(code, "-", ""))
+}
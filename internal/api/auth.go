@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/danielmichaels/tawny/gen/certificates"
+	"github.com/danielmichaels/tawny/gen/domains"
+	"github.com/danielmichaels/tawny/gen/identity"
+	"github.com/danielmichaels/tawny/gen/issuers"
+	"github.com/danielmichaels/tawny/gen/ports"
+	"github.com/danielmichaels/tawny/gen/releases"
+	"github.com/danielmichaels/tawny/internal/auth"
+	"github.com/danielmichaels/tawny/internal/logger"
+	"github.com/danielmichaels/tawny/internal/store"
+	goa "goa.design/goa/v3/pkg"
+	"goa.design/goa/v3/security"
+)
+
+// invalidScopesError is the InvalidScopes error of a service. goa generates
+// one per service but they share a shape, as do Forbidden and Unauthorized.
+type invalidScopesError interface {
+	identity.InvalidScopes | domains.InvalidScopes | issuers.InvalidScopes |
+		certificates.InvalidScopes | ports.InvalidScopes | releases.InvalidScopes
+	error
+}
+
+// forbiddenError is the Forbidden error of a service.
+type forbiddenError interface {
+	identity.Forbidden | domains.Forbidden | issuers.Forbidden |
+		certificates.Forbidden | ports.Forbidden | releases.Forbidden
+}
+
+// unauthorizedError is the Unauthorized error of a service.
+type unauthorizedError interface {
+	identity.Unauthorized | domains.Unauthorized | issuers.Unauthorized |
+		certificates.Unauthorized | ports.Unauthorized | releases.Unauthorized
+}
+
+// validateAPIKey implements the "api_key" security scheme of every service,
+// reporting rejected keys as the InvalidScopes S, Forbidden F and
+// Unauthorized U of the calling service so its encoder can handle them.
+// Methods in twoFactorExempt may be called by users who have not enrolled in
+// two-factor authentication required by their team.
+func validateAPIKey[S invalidScopesError, F forbiddenError, U unauthorizedError, PF interface {
+	*F
+	error
+}, PU interface {
+	*U
+	error
+}](
+	ctx context.Context,
+	log *logger.Logger,
+	db *store.Queries,
+	key string,
+	scheme *security.APIKeyScheme,
+	twoFactorExempt map[string]bool,
+) (context.Context, error) {
+	forbidden := func(message string, err error) error {
+		f := F(struct{ Name, Message, Detail string }{
+			Name:    "forbidden",
+			Message: message,
+			Detail:  err.Error(),
+		})
+		return PF(&f)
+	}
+	ak := auth.NewApiKey()
+	ctx, err := ak.Validate(ctx, key, scheme, db)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidScopes) {
+			log.Warn().Err(err).Msg("token scopes invalid")
+			return ctx, S(err.Error())
+		}
+		if errors.Is(err, auth.ErrTwoFactorRequired) {
+			// Users must be able to enrol to satisfy the team policy.
+			if m, _ := ctx.Value(goa.MethodKey).(string); twoFactorExempt[m] {
+				return ctx, nil
+			}
+			log.Warn().Err(err).Msg("two-factor authentication required")
+			return ctx, forbidden("two-factor authentication required", err)
+		}
+		if errors.Is(err, auth.ErrTeamAccess) {
+			log.Warn().Err(err).Msg("team access denied")
+			return ctx, forbidden("team access denied", err)
+		}
+		log.Error().Err(err).Msg("token invalid")
+		u := U(struct{ Message string }{Message: "token invalid"})
+		return ctx, PU(&u)
+	}
+	return ctx, nil
+}
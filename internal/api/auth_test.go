@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/danielmichaels/tawny/design"
+	"github.com/danielmichaels/tawny/gen/certificates"
+	"github.com/danielmichaels/tawny/gen/domains"
+	"github.com/danielmichaels/tawny/gen/identity"
+	"github.com/danielmichaels/tawny/gen/issuers"
+	"github.com/danielmichaels/tawny/gen/ports"
+	"github.com/danielmichaels/tawny/gen/releases"
+	"github.com/danielmichaels/tawny/internal/logger"
+	"github.com/danielmichaels/tawny/internal/store"
+	"goa.design/goa/v3/security"
+)
+
+// TestAPIKeyAuthUnknownKey checks that every service rejects an unknown key
+// with its own Unauthorized error, which its generated encoder can handle.
+func TestAPIKeyAuthUnknownKey(t *testing.T) {
+	noKeys := fakeDB{
+		"RetrieveUserByAPIKEY":           func([]any) ([][]any, error) { return nil, nil },
+		"RetrieveServiceAccountByAPIKEY": func([]any) ([][]any, error) { return nil, nil },
+	}
+	log := logger.New("test", false, false)
+	db := store.New(noKeys)
+	type authFunc func(context.Context, string, *security.APIKeyScheme) (context.Context, error)
+	tests := []struct {
+		name string
+		auth authFunc
+		want any
+	}{
+		{"identity", (&identitysrvc{logger: log, db: db}).APIKeyAuth, new(*identity.Unauthorized)},
+		{"domains", (&domainssrvc{logger: log, db: db}).APIKeyAuth, new(*domains.Unauthorized)},
+		{"issuers", (&issuerssrvc{logger: log, db: db}).APIKeyAuth, new(*issuers.Unauthorized)},
+		{"certificates", (&certificatessrvc{logger: log, db: db}).APIKeyAuth, new(*certificates.Unauthorized)},
+		{"ports", (&portssrvc{logger: log, db: db}).APIKeyAuth, new(*ports.Unauthorized)},
+		{"releases", (&releasessrvc{logger: log, db: db}).APIKeyAuth, new(*releases.Unauthorized)},
+	}
+	scheme := &security.APIKeyScheme{Name: "api_key", Scopes: design.Scopes}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.auth(context.Background(), "key_unknown", scheme)
+			if !errors.As(err, tt.want) {
+				t.Errorf("APIKeyAuth() error = %T %v, want %T", err, err, tt.want)
+			}
+		})
+	}
+}
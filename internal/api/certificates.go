@@ -0,0 +1,270 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	assets "github.com/danielmichaels/tawny"
+	"github.com/danielmichaels/tawny/gen/certificates"
+	"github.com/danielmichaels/tawny/internal/auth"
+	"github.com/danielmichaels/tawny/internal/authz"
+	"github.com/danielmichaels/tawny/internal/certs"
+	"github.com/danielmichaels/tawny/internal/k8sclient"
+	"github.com/danielmichaels/tawny/internal/logger"
+	"github.com/danielmichaels/tawny/internal/ptr"
+	"github.com/danielmichaels/tawny/internal/store"
+	"github.com/jackc/pgx/v5"
+	"goa.design/goa/v3/security"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// certificates service implementation. The inventory is read from the
+// cluster on each request.
+type certificatessrvc struct {
+	logger  *logger.Logger
+	db      *store.Queries
+	kclient *k8sclient.K8sClient
+	// ingress is what domains are checked to resolve to.
+	ingress certs.Ingress
+	// caNamespace holds the Secret of the internal CA root.
+	caNamespace string
+}
+
+// NewCertificates returns the certificates service implementation.
+func NewCertificates(
+	logger *logger.Logger,
+	db *store.Queries,
+	kclient *k8sclient.K8sClient,
+	ingress certs.Ingress,
+	caNamespace string,
+) certificates.Service {
+	return &certificatessrvc{logger, db, kclient, ingress, caNamespace}
+}
+
+// APIKeyAuth implements the authorization logic for service "certificates"
+// for the "api_key" security scheme.
+func (s *certificatessrvc) APIKeyAuth(
+	ctx context.Context,
+	key string,
+	scheme *security.APIKeyScheme,
+) (context.Context, error) {
+	return validateAPIKey[certificates.InvalidScopes, certificates.Forbidden, certificates.Unauthorized](ctx, s.logger, s.db, key, scheme, nil)
+}
+
+// List the certificates of the caller's team.
+func (s *certificatessrvc) ListCertificates(
+	ctx context.Context,
+	p *certificates.ListCertificatesPayload,
+) (res *certificates.CertificatesResult, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	if err := authz.Authorize(authz.Role(ut.Role), authz.ResourceDomain, authz.ActionRead); err != nil {
+		return nil, &certificates.Forbidden{
+			Name:    "forbidden",
+			Message: "permission denied",
+			Detail:  err.Error(),
+		}
+	}
+	pg, err := listPage[certificates.BadRequest](p.PageSize, p.PageNumber, p.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	all, err := certs.Inventory(ctx, s.kclient, k8sclient.DefaultNamespace)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error reading certificate inventory")
+		return nil, certificatesServerError()
+	}
+	var owned []certs.Certificate
+	for _, c := range all {
+		if c.Team == ut.TeamUUID {
+			owned = append(owned, c)
+		}
+	}
+	// Certificates have no row IDs so pages are anchored to the newest
+	// creation time instead.
+	pg, page, total := pageOf(pg, owned, func(c certs.Certificate) int64 { return c.CreatedAt.Unix() })
+	res = &certificates.CertificatesResult{Certificates: certificates.CertificateResultCollection{}}
+	for _, c := range page {
+		res.Certificates = append(res.Certificates, certificateResult(c))
+	}
+	res.Metadata = listMetadata[certificates.PaginationMetadata](ctx, pg, total)
+	return res, nil
+}
+
+// Diagnose the issuance of a domain's certificate.
+func (s *certificatessrvc) DiagnoseDomain(
+	ctx context.Context,
+	p *certificates.DiagnoseDomainPayload,
+) (res *certificates.DiagnosisResult, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	if err := authz.Authorize(authz.Role(ut.Role), authz.ResourceDomain, authz.ActionRead); err != nil {
+		return nil, &certificates.Forbidden{
+			Name:    "forbidden",
+			Message: "permission denied",
+			Detail:  err.Error(),
+		}
+	}
+	domain := strings.ToLower(p.Domain)
+	d, err := s.db.GetDomain(ctx, store.GetDomainParams{TeamID: ut.TeamUUID, Name: domain})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, &certificates.NotFound{
+			Name:    "not found",
+			Message: "domain not found",
+			Detail:  fmt.Sprintf("%s is not a domain of your team", domain),
+		}
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Str("domain", domain).Msg("error retrieving domain")
+		return nil, certificatesServerError()
+	}
+	ingress, err := s.ingress.Lookup(ctx, s.kclient)
+	if err != nil {
+		s.logger.Warn().Err(err).Msg("error looking up ingress addresses")
+	}
+	certificate := ""
+	if d.Issuer != "" {
+		certificate = certs.CertificateFor(d.Name, d.TlsSecret)
+	}
+	diag, err := certs.Diagnose(ctx, s.kclient, net.DefaultResolver, k8sclient.DefaultNamespace, certificate, d.Name, ingress)
+	if apierrors.IsNotFound(err) {
+		return nil, &certificates.NotFound{
+			Name:    "not found",
+			Message: "certificate not found",
+			Detail:  fmt.Sprintf("certificate %s of %s does not exist", certificate, domain),
+		}
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Str("domain", domain).Msg("error diagnosing domain")
+		return nil, certificatesServerError()
+	}
+	return diagnosisResult(diag), nil
+}
+
+func diagnosisResult(d *certs.Diagnosis) *certificates.DiagnosisResult {
+	res := &certificates.DiagnosisResult{
+		Domain:      d.Domain,
+		Certificate: diagnosticStep(d.Certificate),
+		Requests:    []*certificates.RequestDiagnosis{},
+		DNS: &certificates.DNSCheck{
+			OK:       d.DNS.OK,
+			Expected: d.DNS.Expected,
+			Resolved: d.DNS.Resolved,
+			Error:    optional(d.DNS.Error),
+		},
+	}
+	for _, r := range d.Requests {
+		step := diagnosticStep(r.Step)
+		rd := &certificates.RequestDiagnosis{
+			Kind:           step.Kind,
+			Name:           step.Name,
+			State:          step.State,
+			Reason:         step.Reason,
+			LastTransition: step.LastTransition,
+			Orders:         []*certificates.OrderDiagnosis{},
+		}
+		for _, o := range r.Orders {
+			step := diagnosticStep(o.Step)
+			od := &certificates.OrderDiagnosis{
+				Kind:           step.Kind,
+				Name:           step.Name,
+				State:          step.State,
+				Reason:         step.Reason,
+				LastTransition: step.LastTransition,
+				URL:            optional(o.URL),
+				Challenges:     []*certificates.ChallengeDiagnosis{},
+			}
+			for _, c := range o.Challenges {
+				step := diagnosticStep(c.Step)
+				od.Challenges = append(od.Challenges, &certificates.ChallengeDiagnosis{
+					Kind:      step.Kind,
+					Name:      step.Name,
+					State:     step.State,
+					Reason:    step.Reason,
+					Type:      optional(c.Type),
+					DNSName:   optional(c.DNSName),
+					Token:     optional(c.Token),
+					Presented: c.Presented,
+				})
+			}
+			rd.Orders = append(rd.Orders, od)
+		}
+		res.Requests = append(res.Requests, rd)
+	}
+	return res
+}
+
+func diagnosticStep(s certs.Step) *certificates.DiagnosticStep {
+	res := &certificates.DiagnosticStep{
+		Kind:   s.Kind,
+		Name:   s.Name,
+		State:  optional(s.State),
+		Reason: optional(s.Reason),
+	}
+	if !s.LastTransition.IsZero() {
+		res.LastTransition = ptr.Ptr(s.LastTransition.String())
+	}
+	return res
+}
+
+func certificateResult(c certs.Certificate) *certificates.CertificateResult {
+	res := &certificates.CertificateResult{
+		Name:          c.Name,
+		Source:        c.Source,
+		Domains:       c.DNSNames,
+		Issuer:        optional(c.Issuer),
+		SecretName:    optional(c.SecretName),
+		Ready:         c.Ready,
+		RenewalFailed: c.RenewalFailed(),
+		SerialNumber:  optional(c.SerialNumber),
+		IssuedBy:      optional(c.IssuedBy),
+	}
+	if !c.NotAfter.IsZero() {
+		res.NotAfter = ptr.Ptr(c.NotAfter.String())
+	}
+	if !c.RenewalTime.IsZero() {
+		res.RenewalTime = ptr.Ptr(c.RenewalTime.String())
+	}
+	if c.RenewalFailed() {
+		res.FailureMessage = optional(c.FailureMessage)
+	}
+	return res
+}
+
+// Download the root certificate of the internal CA.
+func (s *certificatessrvc) RootCA(
+	ctx context.Context,
+) (res *certificates.RootCAResult, body io.ReadCloser, err error) {
+	secret, err := s.kclient.GetSecret(ctx, k8sclient.InternalCASecretName(), s.caNamespace)
+	if err != nil && !apierrors.IsNotFound(err) {
+		s.logger.Error().Err(err).Msg("error retrieving internal CA")
+		return nil, nil, certificatesServerError()
+	}
+	var root []byte
+	if err == nil {
+		root = secret.Data[v1.TLSCertKey]
+	}
+	if len(root) == 0 {
+		return nil, nil, &certificates.NotFound{
+			Name:    "not found",
+			Message: "resource not found",
+			Detail:  "the internal CA is not enabled or has not been issued yet",
+		}
+	}
+	return &certificates.RootCAResult{
+		Length:      int64(len(root)),
+		Encoding:    "application/x-pem-file",
+		Disposition: fmt.Sprintf("attachment; filename=%q", assets.AppName+"-ca.pem"),
+	}, io.NopCloser(bytes.NewReader(root)), nil
+}
+
+func certificatesServerError() *certificates.ServerError {
+	return &certificates.ServerError{
+		Name:    "internal server error",
+		Message: "an unknown error occurred",
+	}
+}
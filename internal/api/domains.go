@@ -2,14 +2,29 @@ package api
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/danielmichaels/tawny/gen/domains"
-	"github.com/danielmichaels/tawny/gen/identity"
 	"github.com/danielmichaels/tawny/internal/auth"
+	"github.com/danielmichaels/tawny/internal/authz"
+	"github.com/danielmichaels/tawny/internal/certs"
 	"github.com/danielmichaels/tawny/internal/k8sclient"
 	"github.com/danielmichaels/tawny/internal/logger"
+	"github.com/danielmichaels/tawny/internal/ptr"
 	"github.com/danielmichaels/tawny/internal/store"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	traefikv1alpha1 "github.com/traefik/traefik/v3/pkg/provider/kubernetes/crd/traefikio/v1alpha1"
 	"goa.design/goa/v3/security"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
 // domains service example implementation.
@@ -36,27 +51,675 @@ func (s *domainssrvc) APIKeyAuth(
 	key string,
 	scheme *security.APIKeyScheme,
 ) (context.Context, error) {
-	ak := auth.NewApiKey()
-	ctx, err := ak.Validate(ctx, key, scheme, s.db)
-	if err != nil {
-		s.logger.Error().Err(err).Msg("token invalid")
-		return ctx, &identity.Unauthorized{Message: "token invalid"}
-	}
-	return ctx, nil
+	return validateAPIKey[domains.InvalidScopes, domains.Forbidden, domains.Unauthorized](ctx, s.logger, s.db, key, scheme, nil)
 }
 
 func (s *domainssrvc) ListDomains(
 	ctx context.Context,
 	payload *domains.ListDomainsPayload,
 ) (res *domains.DomainsResult, err error) {
-	//TODO implement me
-	panic("implement me")
+	ut := auth.CtxAuthInfo(ctx)
+	if err := authz.Authorize(authz.Role(ut.Role), authz.ResourceDomain, authz.ActionRead); err != nil {
+		return nil, domainsForbidden(err)
+	}
+	pg, err := listPage[domains.BadRequest](payload.PageSize, payload.PageNumber, payload.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	count, err := s.db.CountDomains(ctx, store.CountDomainsParams{
+		TeamID: ut.TeamUUID,
+		AppID:  payload.AppID,
+		MaxID:  maxID(pg),
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error counting domains")
+		return nil, domainsServerError()
+	}
+	pg = pg.Anchored(count.MaxID)
+	rows, err := s.db.ListDomains(ctx, store.ListDomainsParams{
+		TeamID: ut.TeamUUID,
+		AppID:  payload.AppID,
+		MaxID:  maxID(pg),
+		Lim:    pg.Limit(),
+		Off:    pg.Offset(),
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error listing domains")
+		return nil, domainsServerError()
+	}
+	res = &domains.DomainsResult{Domains: domains.DomainResultCollection{}}
+	for _, d := range rows {
+		res.Domains = append(res.Domains, domainResult(d))
+	}
+	res.Metadata = listMetadata[domains.PaginationMetadata](ctx, pg, count.Count)
+	return res, nil
 }
 
+// Create a domain routed to an app. The domain is served over HTTPS with a
+// certificate from the chosen issuer, or the shared issuer of its certificate
+// type. Domains below a wildcard domain of the team share its certificate.
 func (s *domainssrvc) CreateDomain(
 	ctx context.Context,
 	payload *domains.CreateDomainPayload,
 ) (res *domains.DomainResult, err error) {
-	//TODO implement me
-	panic("implement me")
+	ut := auth.CtxAuthInfo(ctx)
+	// Service accounts have no email address to verify; the team member who
+	// created them was verified instead.
+	if ut.ServiceAccountUUID == "" && !ut.Verified {
+		return nil, &domains.Forbidden{
+			Name:    "forbidden",
+			Message: "email address not verified",
+			Detail:  "verify your email address before provisioning domains",
+		}
+	}
+	if err := authz.Authorize(authz.Role(ut.Role), authz.ResourceDomain, authz.ActionCreate); err != nil {
+		return nil, domainsForbidden(err)
+	}
+	domain := strings.ToLower(payload.Domain)
+	tls, err := s.domainTLS(ctx, ut.TeamUUID, domain, payload)
+	if err != nil {
+		return nil, err
+	}
+	namespace := k8sclient.DefaultNamespace
+	service := k8sclient.ServiceName(payload.AppID)
+	port, err := s.appPort(ctx, ut.TeamUUID, payload.AppID, payload.Port)
+	if err != nil {
+		return nil, err
+	}
+
+	routes, err := json.Marshal([]domainRoute{{
+		Backends: []routeBackend{{AppID: payload.AppID, Port: port, Weight: 1}},
+	}})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error encoding domain routes")
+		return nil, domainsServerError()
+	}
+	d, err := s.db.CreateDomain(ctx, store.CreateDomainParams{
+		TeamID:          ut.TeamUUID,
+		AppID:           payload.AppID,
+		Name:            domain,
+		CertificateType: tls.certificateType,
+		Issuer:          tls.issuer,
+		TlsSecret:       tls.secret,
+		Routes:          routes,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, &domains.BadRequest{
+				Name:    "bad request",
+				Message: "domain already exists",
+				Detail:  "the domain is already routed to an app",
+			}
+		}
+		s.logger.Error().Err(err).Msg("error creating domain")
+		return nil, domainsServerError()
+	}
+
+	name := k8sclient.DomainResourceName(domain)
+	if err := s.createDomainResources(ctx, ut.TeamUUID, name, namespace, domain, service, port, tls); err != nil {
+		s.logger.Error().Err(err).Str("domain", domain).Msg("error creating domain resources")
+		if err := s.db.DeleteDomain(ctx, store.DeleteDomainParams{TeamID: ut.TeamUUID, Name: domain}); err != nil {
+			s.logger.Error().Err(err).Str("domain", domain).Msg("error removing domain")
+		}
+		return nil, domainsServerError()
+	}
+	res = domainResult(d)
+	res.Port = ptr.Ptr(strconv.Itoa(int(port)))
+	return res, nil
+}
+
+// Upload a certificate chain and key for a domain. The pair is validated and
+// stored as a TLS Secret which the domain's IngressRoute is switched to; the
+// cert-manager Certificate of the domain, if any, is removed.
+func (s *domainssrvc) UploadCertificate(
+	ctx context.Context,
+	payload *domains.UploadCertificatePayload,
+) (res *domains.DomainResult, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	if err := authz.Authorize(authz.Role(ut.Role), authz.ResourceDomain, authz.ActionUpdate); err != nil {
+		return nil, domainsForbidden(err)
+	}
+	domain := strings.ToLower(payload.Domain)
+	d, err := s.db.GetDomain(ctx, store.GetDomainParams{TeamID: ut.TeamUUID, Name: domain})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		s.logger.Error().Err(err).Str("domain", domain).Msg("error retrieving domain")
+		return nil, domainsServerError()
+	}
+	if err != nil || d.AppID != payload.AppID {
+		return nil, &domains.NotFound{
+			Name:    "not found",
+			Message: "domain not found",
+			Detail:  fmt.Sprintf("%s is not routed to %s", domain, payload.AppID),
+		}
+	}
+	leaf, err := certs.ValidatePair([]byte(payload.Certificate), []byte(payload.PrivateKey), domain, time.Now())
+	if err != nil {
+		return nil, &domains.BadRequest{
+			Name:    "bad request",
+			Message: "invalid certificate",
+			Detail:  err.Error(),
+		}
+	}
+
+	namespace := k8sclient.DefaultNamespace
+	name := k8sclient.DomainResourceName(domain)
+	secret := k8sclient.CreateCertSecretName(name)
+	// cert-manager would otherwise overwrite the uploaded certificate.
+	if err := s.kclient.DeleteCertificate(ctx, name, namespace); err != nil && !apierrors.IsNotFound(err) {
+		s.logger.Error().Err(err).Str("certificate", name).Msg("error removing certificate")
+		return nil, domainsServerError()
+	}
+	_, err = s.kclient.ApplySecret(ctx, secret, namespace,
+		k8sclient.WithSecretTLS([]byte(payload.Certificate), []byte(payload.PrivateKey)),
+		k8sclient.WithSecretTeam(ut.TeamUUID),
+	)
+	if err != nil {
+		s.logger.Error().Err(err).Str("secret", secret).Msg("error storing certificate")
+		return nil, domainsServerError()
+	}
+	if _, err := s.kclient.PatchIngress(ctx, name, namespace, k8sclient.WithIngressRouteTLS(secret)); err != nil {
+		s.logger.Error().Err(err).Str("domain", domain).Msg("error updating ingress route")
+		return nil, domainsServerError()
+	}
+	d, err = s.db.SetDomainCertificate(ctx, store.SetDomainCertificateParams{
+		TeamID:               ut.TeamUUID,
+		Name:                 domain,
+		TlsSecret:            secret,
+		CertificateExpiresAt: pgtype.Timestamptz{Time: leaf.NotAfter, Valid: true},
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Str("domain", domain).Msg("error updating domain")
+		return nil, domainsServerError()
+	}
+	return domainResult(d), nil
+}
+
+// Replace the routes of a domain. The routes are stored before the
+// IngressRoute is updated, and restored should that fail.
+func (s *domainssrvc) SetRoutes(
+	ctx context.Context,
+	payload *domains.SetRoutesPayload,
+) (res *domains.DomainResult, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	if err := authz.Authorize(authz.Role(ut.Role), authz.ResourceDomain, authz.ActionUpdate); err != nil {
+		return nil, domainsForbidden(err)
+	}
+	domain := strings.ToLower(payload.Domain)
+	d, err := s.db.GetDomain(ctx, store.GetDomainParams{TeamID: ut.TeamUUID, Name: domain})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		s.logger.Error().Err(err).Str("domain", domain).Msg("error retrieving domain")
+		return nil, domainsServerError()
+	}
+	if err != nil || d.AppID != payload.AppID {
+		return nil, &domains.NotFound{
+			Name:    "not found",
+			Message: "domain not found",
+			Detail:  fmt.Sprintf("%s is not routed to %s", domain, payload.AppID),
+		}
+	}
+	routes, err := s.domainRoutes(ctx, ut.TeamUUID, payload.Routes)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(routes)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error encoding domain routes")
+		return nil, domainsServerError()
+	}
+
+	releases, err := s.db.ListActiveDomainReleases(ctx, store.ListActiveDomainReleasesParams{
+		TeamID: ut.TeamUUID,
+		Domain: domain,
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Str("domain", domain).Msg("error listing domain releases")
+		return nil, domainsServerError()
+	}
+
+	previous := d.Routes
+	d, err = s.db.SetDomainRoutes(ctx, store.SetDomainRoutesParams{TeamID: ut.TeamUUID, Name: domain, Routes: encoded})
+	if err != nil {
+		s.logger.Error().Err(err).Str("domain", domain).Msg("error updating domain routes")
+		return nil, domainsServerError()
+	}
+	if err := applyDomainRoutes(ctx, s.kclient, d, routes, releases); err != nil {
+		s.logger.Error().Err(err).Str("domain", domain).Msg("error applying domain routes")
+		_, err := s.db.SetDomainRoutes(ctx, store.SetDomainRoutesParams{
+			TeamID: ut.TeamUUID,
+			Name:   domain,
+			Routes: previous,
+		})
+		if err != nil {
+			s.logger.Error().Err(err).Str("domain", domain).Msg("error restoring domain routes")
+		}
+		return nil, domainsServerError()
+	}
+	return domainResult(d), nil
+}
+
+// domainRoute is a route of a domain as stored in its routes column.
+type domainRoute struct {
+	PathPrefix string            `json:"path_prefix,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Priority   int               `json:"priority,omitempty"`
+	Backends   []routeBackend    `json:"backends"`
+}
+
+// storedRoutes returns the routes of d. Domains created before routes were
+// stored have none.
+func storedRoutes(d store.Domains) ([]domainRoute, error) {
+	if len(d.Routes) == 0 {
+		return nil, nil
+	}
+	var routes []domainRoute
+	if err := json.Unmarshal(d.Routes, &routes); err != nil {
+		return nil, err
+	}
+	return routes, nil
+}
+
+// routeBackend is an app requests of a route are sent to. The port is
+// resolved when the route is set.
+type routeBackend struct {
+	AppID  string `json:"app_id"`
+	Port   int32  `json:"port"`
+	Weight int    `json:"weight"`
+}
+
+// domainRoutes validates routes and resolves the ports of their backends, all
+// of which must be apps of teamID.
+func (s *domainssrvc) domainRoutes(ctx context.Context, teamID string, routes []*domains.DomainRoute) ([]domainRoute, error) {
+	res := make([]domainRoute, 0, len(routes))
+	for i, r := range routes {
+		route := domainRoute{Headers: r.Headers}
+		if r.PathPrefix != nil {
+			route.PathPrefix = *r.PathPrefix
+		}
+		if r.Priority != nil {
+			route.Priority = *r.Priority
+		}
+		var total int
+		for _, b := range r.Backends {
+			port, err := s.appPort(ctx, teamID, b.AppID, b.Port)
+			if err != nil {
+				return nil, err
+			}
+			route.Backends = append(route.Backends, routeBackend{AppID: b.AppID, Port: port, Weight: b.Weight})
+			total += b.Weight
+		}
+		if total == 0 {
+			return nil, &domains.BadRequest{
+				Name:    "bad request",
+				Message: "invalid route",
+				Detail:  fmt.Sprintf("route %d has no backend with a weight above zero", i),
+			}
+		}
+		res = append(res, route)
+	}
+	return res, nil
+}
+
+// applyDomainRoutes replaces the routes of the IngressRoute of d. Requests for
+// an app with an active release are shared between its Service and that of
+// the release by the release weight. Routes left with several backends are
+// served through a weighted TraefikService of their own; those left over from
+// earlier routes are removed.
+func applyDomainRoutes(
+	ctx context.Context,
+	kclient *k8sclient.K8sClient,
+	d store.Domains,
+	routes []domainRoute,
+	releases []store.Releases,
+) error {
+	name := k8sclient.DomainResourceName(d.Name)
+	namespace := k8sclient.DefaultNamespace
+	opts := []k8sclient.IngressRouteOption{
+		k8sclient.WithIngressRouteEntryPoint(k8sclient.EntryPointWebSecure),
+		k8sclient.WithIngressRouteTLS(d.TlsSecret),
+		k8sclient.WithIngressRouteTeam(d.TeamID),
+	}
+	weighted := make(map[string]bool)
+	for i, r := range routes {
+		backends := routeServices(r.Backends, releases)
+		var backend traefikv1alpha1.LoadBalancerSpec
+		if len(backends) == 1 {
+			backend = k8sclient.ServiceBackend(backends[0].service, namespace, backends[0].port)
+		} else {
+			svc := fmt.Sprintf("%s-route-%d", name, i)
+			svcOpts := []k8sclient.TraefikServiceOption{
+				k8sclient.WithTraefikServiceTeam(d.TeamID),
+				k8sclient.WithTraefikServiceDomain(name),
+			}
+			for _, b := range backends {
+				svcOpts = append(svcOpts,
+					k8sclient.WithTraefikServiceWeighted(b.service, namespace, b.port, b.weight))
+			}
+			if _, err := kclient.ApplyTraefikService(ctx, svc, namespace, svcOpts...); err != nil {
+				return fmt.Errorf("failed to apply traefik service %q: %w", svc, err)
+			}
+			weighted[svc] = true
+			backend = k8sclient.TraefikServiceBackend(svc, namespace)
+		}
+		match := k8sclient.RouteMatch(d.Name, r.PathPrefix, r.Headers)
+		priority := routePriority(i, len(routes), r.Priority, k8sclient.IsWildcardDomain(d.Name))
+		opts = append(opts, k8sclient.WithIngressRouteMatch(match, priority, backend))
+	}
+	if _, err := kclient.UpdateIngress(ctx, name, namespace, opts...); err != nil {
+		return fmt.Errorf("failed to update ingress route: %w", err)
+	}
+
+	existing, err := kclient.ListTraefikServices(ctx, namespace, map[string]string{k8sclient.LabelDomain: name})
+	if err != nil {
+		return fmt.Errorf("failed to list traefik services: %w", err)
+	}
+	for _, svc := range existing {
+		if weighted[svc.Name] {
+			continue
+		}
+		if err := kclient.DeleteTraefikService(ctx, svc.Name, namespace); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to remove traefik service %q: %w", svc.Name, err)
+		}
+	}
+	return nil
+}
+
+// routeService is a Service a route sends a share of its requests to.
+type routeService struct {
+	service string
+	port    int32
+	weight  int
+}
+
+// routeServices returns the Services of the backends of a route. A backend
+// whose app has a release is split between the app's Service and that of the
+// release, dropping the version which gets no requests.
+func routeServices(backends []routeBackend, releases []store.Releases) []routeService {
+	var res []routeService
+	for _, b := range backends {
+		stable := routeService{k8sclient.ServiceName(b.AppID), b.Port, b.Weight}
+		i := slices.IndexFunc(releases, func(r store.Releases) bool { return r.AppID == b.AppID })
+		if i < 0 {
+			res = append(res, stable)
+			continue
+		}
+		weight := int(releases[i].Weight)
+		if weight < 100 {
+			stable.weight = b.Weight * (100 - weight)
+			res = append(res, stable)
+		}
+		if weight > 0 {
+			release := k8sclient.ReleaseResourceName(releases[i].Uuid)
+			res = append(res, routeService{release, b.Port, b.Weight * weight})
+		}
+	}
+	return res
+}
+
+// routePriority returns the traefik priority of the i-th of n routes. Routes
+// are ranked by their order unless given a priority. Routes of wildcard
+// domains stay below the routes of specific domains, which traefik ranks by
+// the length of their rule or from orderedPriority up, so explicit wildcard
+// priorities are capped below orderedPriority.
+func routePriority(i, n, priority int, wildcard bool) int {
+	if wildcard {
+		if priority > 0 {
+			return min(priority, orderedPriority-1)
+		}
+		return n - i
+	}
+	if priority > 0 {
+		return priority
+	}
+	return orderedPriority + n - i
+}
+
+// orderedPriority is the lowest priority given to the ordered routes of a
+// specific domain.
+const orderedPriority = 1000
+
+// domainTLS describes how a domain is served over HTTPS.
+type domainTLS struct {
+	issuer          string
+	certificateType string
+	// secret holds the certificate.
+	secret string
+	// issue is set when the domain needs a Certificate of its own rather
+	// than sharing the certificate of a wildcard domain.
+	issue bool
+}
+
+// domainTLS decides how a new domain is served over HTTPS. Domains below a
+// wildcard domain of the team reuse its certificate unless they choose an
+// issuer; all others are issued their own.
+func (s *domainssrvc) domainTLS(
+	ctx context.Context,
+	teamID, domain string,
+	payload *domains.CreateDomainPayload,
+) (domainTLS, error) {
+	isIP := net.ParseIP(domain) != nil
+	if wildcard := k8sclient.WildcardDomain(domain); wildcard != "" && !isIP && payload.Issuer == nil {
+		w, err := s.db.GetDomain(ctx, store.GetDomainParams{TeamID: teamID, Name: wildcard})
+		if err == nil {
+			return domainTLS{issuer: w.Issuer, certificateType: w.CertificateType, secret: w.TlsSecret}, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			s.logger.Error().Err(err).Str("domain", wildcard).Msg("error retrieving wildcard domain")
+			return domainTLS{}, domainsServerError()
+		}
+	}
+	issuer, certificateType, err := s.resolveIssuer(ctx, teamID, domain, payload)
+	if err != nil {
+		return domainTLS{}, err
+	}
+	return domainTLS{
+		issuer:          issuer,
+		certificateType: certificateType,
+		secret:          k8sclient.CreateCertSecretName(k8sclient.DomainResourceName(domain)),
+		issue:           true,
+	}, nil
+}
+
+// resolveIssuer returns the issuer and certificate type of a new domain.
+// Issuers owned by other teams are reported as unknown. Wildcard domains can
+// only be issued through DNS-01 or a CA, and IP addresses only by a CA, which
+// is the internal CA unless another issuer is named.
+func (s *domainssrvc) resolveIssuer(
+	ctx context.Context,
+	teamID, domain string,
+	payload *domains.CreateDomainPayload,
+) (string, string, error) {
+	wildcard := k8sclient.IsWildcardDomain(domain)
+	isIP := net.ParseIP(domain) != nil
+	var name string
+	switch {
+	case payload.Issuer != nil:
+		name = *payload.Issuer
+	case isIP || payload.CertificateType == k8sclient.CertificateTypeInternal:
+		name = k8sclient.InternalCAIssuerName
+	case wildcard:
+		return "", "", &domains.BadRequest{
+			Name:    "bad request",
+			Message: "dns01 issuer required",
+			Detail:  "wildcard domains must name an issuer with a dns01 solver for the domain",
+		}
+	default:
+		return k8sclient.DefaultClusterIssuerName(payload.CertificateType), payload.CertificateType, nil
+	}
+	i, err := s.kclient.GetClusterIssuer(ctx, name)
+	if err != nil && !apierrors.IsNotFound(err) {
+		s.logger.Error().Err(err).Str("issuer", name).Msg("error retrieving cluster issuer")
+		return "", "", domainsServerError()
+	}
+	if err != nil && payload.Issuer == nil {
+		return "", "", &domains.BadRequest{
+			Name:    "bad request",
+			Message: "internal CA not enabled",
+			Detail:  "IP addresses and internal certificates require the internal CA to be enabled",
+		}
+	}
+	if err != nil || !issuerVisible(i.Labels, teamID) {
+		return "", "", &domains.BadRequest{
+			Name:    "bad request",
+			Message: "unknown issuer",
+			Detail:  fmt.Sprintf("issuer %q does not exist", name),
+		}
+	}
+	isCA := i.Spec.CA != nil
+	if isIP && !isCA {
+		return "", "", &domains.BadRequest{
+			Name:    "bad request",
+			Message: "ca issuer required",
+			Detail:  fmt.Sprintf("issuer %q cannot issue certificates for IP addresses", i.Name),
+		}
+	}
+	if wildcard && !isCA && !k8sclient.ClusterIssuerSolvesDNS01(i, domain) {
+		return "", "", &domains.BadRequest{
+			Name:    "bad request",
+			Message: "dns01 issuer required",
+			Detail:  fmt.Sprintf("issuer %q has no dns01 solver for %s", i.Name, domain),
+		}
+	}
+	certificateType := payload.CertificateType
+	if isCA {
+		certificateType = k8sclient.CertificateTypeInternal
+	}
+	if i.Spec.ACME != nil {
+		if t := k8sclient.CertificateType(i.Spec.ACME.Server); t != "" {
+			certificateType = t
+		}
+	}
+	return i.Name, certificateType, nil
+}
+
+// appPort returns port when set, otherwise the first port of the app's
+// Service. The Service must be owned by teamID so teams cannot route requests
+// to the apps of others.
+func (s *domainssrvc) appPort(ctx context.Context, teamID, appID string, port *int32) (int32, error) {
+	svc, err := s.kclient.GetTeamService(ctx, k8sclient.ServiceName(appID), k8sclient.DefaultNamespace, teamID)
+	if err != nil && !apierrors.IsNotFound(err) {
+		s.logger.Error().Err(err).Str("app", appID).Msg("error retrieving service")
+		return 0, domainsServerError()
+	}
+	if err != nil {
+		return 0, &domains.NotFound{
+			Name:    "not found",
+			Message: "app not found",
+			Detail:  fmt.Sprintf("%s has no service owned by the team", appID),
+		}
+	}
+	if port != nil {
+		return *port, nil
+	}
+	if len(svc.Spec.Ports) == 0 {
+		return 0, &domains.BadRequest{
+			Name:    "bad request",
+			Message: "port is required",
+			Detail:  "the app's service has no ports to take the port from; specify the port",
+		}
+	}
+	return svc.Spec.Ports[0].Port, nil
+}
+
+// createDomainResources creates the Certificate of a domain, when it needs
+// one, and the IngressRoute serving it. The Certificate is removed again when
+// the route cannot be created.
+func (s *domainssrvc) createDomainResources(
+	ctx context.Context,
+	teamID, name, namespace, domain, service string,
+	port int32,
+	tls domainTLS,
+) error {
+	if tls.issue {
+		san := k8sclient.WithCertificateDomain(domain)
+		if net.ParseIP(domain) != nil {
+			san = k8sclient.WithCertificateIPAddress(domain)
+		}
+		_, err := s.kclient.CreateCertificate(ctx, name, namespace,
+			san,
+			k8sclient.WithCertificateName(tls.issuer),
+			k8sclient.WithCertificateKind("ClusterIssuer"),
+			k8sclient.WithCertificateTeam(teamID),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create certificate: %w", err)
+		}
+	}
+	opts := []k8sclient.IngressRouteOption{
+		k8sclient.WithIngressRouteEntryPoint(k8sclient.EntryPointWebSecure),
+		k8sclient.WithIngressRouteTLS(tls.secret),
+		k8sclient.WithIngressRouteRule(domain, service, namespace, nil, port),
+		k8sclient.WithIngressRouteTeam(teamID),
+	}
+	// Routes for specific subdomains take precedence over the wildcard.
+	if k8sclient.IsWildcardDomain(domain) {
+		opts = append(opts, k8sclient.WithIngressRoutePriority(1))
+	}
+	if _, err := s.kclient.CreateIngress(ctx, name, namespace, opts...); err != nil {
+		if tls.issue {
+			if err := s.kclient.DeleteCertificate(ctx, name, namespace); err != nil {
+				s.logger.Error().Err(err).Str("certificate", name).Msg("error removing certificate")
+			}
+		}
+		return fmt.Errorf("failed to create ingress route: %w", err)
+	}
+	return nil
+}
+
+// issuerVisible reports whether an issuer with labels may be used by teamID.
+// Only issuers managed by tawny are visible, those without a team are shared.
+func issuerVisible(labels map[string]string, teamID string) bool {
+	if !k8sclient.ManagedClusterIssuer(labels) {
+		return false
+	}
+	team, ok := labels[k8sclient.LabelTeam]
+	return !ok || team == teamID
+}
+
+func domainResult(d store.Domains) *domains.DomainResult {
+	res := &domains.DomainResult{
+		DomainName:      &d.Name,
+		Project:         &d.AppID,
+		Protocol:        "https",
+		CertificateType: &d.CertificateType,
+		Issuer:          &d.Issuer,
+	}
+	if d.CertificateExpiresAt.Valid {
+		res.CertificateExpiresAt = ptr.Ptr(d.CertificateExpiresAt.Time.String())
+	}
+	if routes, err := storedRoutes(d); err == nil {
+		for _, r := range routes {
+			route := &domains.DomainRoute{Headers: r.Headers}
+			if r.PathPrefix != "" {
+				route.PathPrefix = ptr.Ptr(r.PathPrefix)
+			}
+			if r.Priority > 0 {
+				route.Priority = ptr.Ptr(r.Priority)
+			}
+			for _, b := range r.Backends {
+				route.Backends = append(route.Backends, &domains.RouteBackend{
+					AppID:  b.AppID,
+					Port:   ptr.Ptr(b.Port),
+					Weight: b.Weight,
+				})
+			}
+			res.Routes = append(res.Routes, route)
+		}
+	}
+	return res
+}
+
+func domainsForbidden(err error) *domains.Forbidden {
+	return &domains.Forbidden{
+		Name:    "forbidden",
+		Message: "permission denied",
+		Detail:  err.Error(),
+	}
+}
+
+func domainsServerError() *domains.ServerError {
+	return &domains.ServerError{
+		Name:    "internal server error",
+		Message: "an unknown error occurred",
+	}
 }
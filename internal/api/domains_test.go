@@ -0,0 +1,26 @@
+package api
+
+import "testing"
+
+func TestRoutePriority(t *testing.T) {
+	tests := []struct {
+		name     string
+		i, n     int
+		priority int
+		wildcard bool
+		want     int
+	}{
+		{"ordered specific route", 0, 3, 0, false, orderedPriority + 3},
+		{"explicit specific priority", 0, 3, 5000, false, 5000},
+		{"ordered wildcard route", 1, 3, 0, true, 2},
+		{"explicit wildcard priority", 0, 3, 100, true, 100},
+		{"wildcard priority is capped below specific routes", 0, 3, 5000, true, orderedPriority - 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := routePriority(tt.i, tt.n, tt.priority, tt.wildcard); got != tt.want {
+				t.Errorf("routePriority() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeDB implements store.DBTX by dispatching on the sqlc query name. Each
+// handler returns the rows a query produces, in the order the generated code
+// scans its columns, or the rows affected for :exec queries.
+type fakeDB map[string]func(args []any) ([][]any, error)
+
+func queryName(sql string) string {
+	name, _, _ := strings.Cut(strings.TrimPrefix(sql, "-- name: "), " ")
+	return name
+}
+
+func (db fakeDB) handle(sql string, args []any) ([][]any, error) {
+	h, ok := db[queryName(sql)]
+	if !ok {
+		return nil, fmt.Errorf("fakeDB: unexpected query %s", queryName(sql))
+	}
+	return h(args)
+}
+
+func (db fakeDB) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	rows, err := db.handle(sql, args)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return pgconn.NewCommandTag(fmt.Sprintf("DELETE %d", len(rows))), nil
+}
+
+func (db fakeDB) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	rows, err := db.handle(sql, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{rows: rows, i: -1}, nil
+}
+
+func (db fakeDB) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	rows, err := db.handle(sql, args)
+	if err == nil && len(rows) == 0 {
+		err = pgx.ErrNoRows
+	}
+	if err != nil {
+		return fakeRow{err: err}
+	}
+	return fakeRow{values: rows[0]}
+}
+
+type fakeRow struct {
+	values []any
+	err    error
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	return scanValues(r.values, dest)
+}
+
+type fakeRows struct {
+	pgx.Rows
+	rows [][]any
+	i    int
+}
+
+func (r *fakeRows) Next() bool {
+	r.i++
+	return r.i < len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...any) error { return scanValues(r.rows[r.i], dest) }
+func (r *fakeRows) Close()                 {}
+func (r *fakeRows) Err() error             { return nil }
+
+// scanValues assigns values to dest, leaving nil values as the zero value.
+func scanValues(values, dest []any) error {
+	if len(values) != len(dest) {
+		return fmt.Errorf("fakeDB: scanning %d values into %d destinations", len(values), len(dest))
+	}
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+		d := reflect.ValueOf(dest[i]).Elem()
+		src := reflect.ValueOf(v)
+		if !src.Type().AssignableTo(d.Type()) {
+			if !src.Type().ConvertibleTo(d.Type()) {
+				return fmt.Errorf("fakeDB: cannot scan %T into %s", v, d.Type())
+			}
+			src = src.Convert(d.Type())
+		}
+		d.Set(src)
+	}
+	return nil
+}
@@ -3,11 +3,18 @@ package api
 import (
 	"context"
 	"errors"
-	"math"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/danielmichaels/tawny/design"
 	"github.com/danielmichaels/tawny/gen/identity"
+	"github.com/danielmichaels/tawny/internal/account"
 	"github.com/danielmichaels/tawny/internal/auth"
+	"github.com/danielmichaels/tawny/internal/authz"
+	"github.com/danielmichaels/tawny/internal/k8sclient"
 	"github.com/danielmichaels/tawny/internal/logger"
 	"github.com/danielmichaels/tawny/internal/ptr"
 	"github.com/danielmichaels/tawny/internal/store"
@@ -19,13 +26,29 @@ import (
 // identity service example implementation.
 // The example methods log the requests and return zero values.
 type identitysrvc struct {
-	logger *logger.Logger
-	db     *store.Queries
+	logger   *logger.Logger
+	db       *store.Queries
+	accounts *account.Service
+	kclient  *k8sclient.K8sClient
+}
+
+// twoFactorEnrolmentMethods may be called by users who have not enrolled in
+// two-factor authentication even if their team requires it, either to enrol
+// or to move to another team.
+var twoFactorEnrolmentMethods = map[string]bool{
+	"enableTwoFactor":  true,
+	"confirmTwoFactor": true,
+	"switchTeam":       true,
 }
 
 // NewIdentity returns the identity service implementation.
-func NewIdentity(logger *logger.Logger, db *store.Queries) identity.Service {
-	return &identitysrvc{logger, db}
+func NewIdentity(
+	logger *logger.Logger,
+	db *store.Queries,
+	accounts *account.Service,
+	kclient *k8sclient.K8sClient,
+) identity.Service {
+	return &identitysrvc{logger, db, accounts, kclient}
 }
 
 // APIKeyAuth implements the authorization logic for service "identity" for the
@@ -35,13 +58,7 @@ func (s *identitysrvc) APIKeyAuth(
 	key string,
 	scheme *security.APIKeyScheme,
 ) (context.Context, error) {
-	ak := auth.NewApiKey()
-	ctx, err := ak.Validate(ctx, key, scheme, s.db)
-	if err != nil {
-		s.logger.Error().Err(err).Msg("token invalid")
-		return ctx, &identity.Unauthorized{Message: "token invalid"}
-	}
-	return ctx, nil
+	return validateAPIKey[identity.InvalidScopes, identity.Forbidden, identity.Unauthorized](ctx, s.logger, s.db, key, scheme, twoFactorEnrolmentMethods)
 }
 
 // Create a new user. This will also generate a new team for that user.
@@ -49,9 +66,52 @@ func (s *identitysrvc) CreateUser(
 	ctx context.Context,
 	p *identity.CreateUserPayload,
 ) (res *identity.UserResult, err error) {
-	res = &identity.UserResult{}
-	s.logger.Print("identity.createUser")
-	return
+	ut := auth.CtxAuthInfo(ctx)
+	if err := authz.AuthorizePlatform(ut.PlatformAdmin, authz.ResourceUser, authz.ActionCreate); err != nil {
+		return nil, identityForbidden(err)
+	}
+	hash, err := store.HashPassword(p.User.Password)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error hashing password")
+		return nil, &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	u, err := s.db.CreateUserWithNewTeam(ctx, store.CreateUserWithNewTeamParams{
+		Column1:  pgtype.Text{String: p.User.Name, Valid: true},
+		Name:     pgtype.Text{String: p.User.Name, Valid: true},
+		Email:    pgtype.Text{String: strings.ToLower(p.User.Email), Valid: true},
+		Password: pgtype.Text{String: hash, Valid: true},
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.As(err, &pgErr) && pgErr.Code == "23505":
+			return nil, &identity.BadRequest{
+				Name:    "bad request",
+				Message: "email already exists",
+				Detail:  "a user with this email already exists",
+			}
+		default:
+			s.logger.Error().Err(err).Msg("error creating user")
+			return nil, &identity.ServerError{
+				Name:    "internal server error",
+				Message: "an unknown error occurred",
+			}
+		}
+	}
+	// The user exists at this point so a mail failure is not fatal, the user
+	// can request another link.
+	if err := s.accounts.SendVerification(ctx, u.UserID); err != nil {
+		s.logger.Error().Err(err).Str("user", u.UserID).Msg("error sending verification email")
+	}
+	return &identity.UserResult{
+		UserUUID: &u.UserID,
+		Name:     p.User.Name,
+		Email:    strings.ToLower(p.User.Email),
+		Role:     string(store.UserRoleOwner),
+	}, nil
 }
 
 // Retrieve a single user. Can only retrieve users from an associated team.
@@ -60,6 +120,9 @@ func (s *identitysrvc) RetrieveUser(
 	p *identity.RetrieveUserPayload,
 ) (res *identity.UserResult, err error) {
 	ut := auth.CtxAuthInfo(ctx)
+	if err := authz.Authorize(authz.Role(ut.Role), authz.ResourceUser, authz.ActionRead); err != nil {
+		return nil, identityForbidden(err)
+	}
 	u, err := s.db.GetUserByID(ctx, store.GetUserByIDParams{
 		Uuid:   p.UserID,
 		UserID: pgtype.Text{String: ut.UserUUID, Valid: true},
@@ -71,15 +134,7 @@ func (s *identitysrvc) RetrieveUser(
 			Detail:  "resource not found",
 		}
 	}
-	user := &identity.UserResult{
-		UserUUID:  &u.Uuid,
-		Name:      u.Name.String,
-		Email:     u.Email.String,
-		Role:      string(u.Role),
-		CreatedAt: ptr.Ptr(u.CreatedAt.Time.String()),
-		UpdatedAt: ptr.Ptr(u.UpdatedAt.Time.String()),
-	}
-	return user, nil
+	return userResultFromRow(u), nil
 }
 
 // Retrieve all users that this user can see from associated teams.
@@ -88,11 +143,30 @@ func (s *identitysrvc) ListUsers(
 	p *identity.ListUsersPayload,
 ) (res *identity.Users, err error) {
 	ut := auth.CtxAuthInfo(ctx)
-	ps, pn := design.PaginationQueryParams(p.PageSize, p.PageNumber)
+	if err := authz.Authorize(authz.Role(ut.Role), authz.ResourceMember, authz.ActionRead); err != nil {
+		return nil, identityForbidden(err)
+	}
+	pg, err := listPage[identity.BadRequest](p.PageSize, p.PageNumber, p.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	teamID := pgtype.Text{String: ut.TeamUUID, Valid: true}
+	// Pages are anchored to the newest membership when paging started so
+	// that members joining meanwhile do not shift later pages.
+	count, err := s.db.CountUsers(ctx, store.CountUsersParams{TeamID: teamID, MaxID: maxID(pg)})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error counting users")
+		return nil, &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	pg = pg.Anchored(count.MaxID)
 	u, err := s.db.ListUsers(ctx, store.ListUsersParams{
-		TeamID: pgtype.Text{String: ut.TeamUUID, Valid: true},
-		Limit:  ps,
-		Offset: pn,
+		TeamID: teamID,
+		MaxID:  maxID(pg),
+		Lim:    pg.Limit(),
+		Off:    pg.Offset(),
 	})
 	if err != nil {
 		return nil, &identity.NotFound{
@@ -101,13 +175,10 @@ func (s *identitysrvc) ListUsers(
 			Detail:  "resource not found",
 		}
 	}
-	count, err := s.db.CountUsers(ctx, pgtype.Text{String: ut.TeamUUID, Valid: true})
-	if err != nil {
-		count = 0
-	}
-	var users = &identity.Users{}
+	var users = &identity.Users{Users: identity.UserResultCollection{}}
 	for _, user := range u {
 		users.Users = append(users.Users, &identity.UserResult{
+			UserUUID:  &user.Uuid,
 			Name:      user.Name.String,
 			Email:     user.Email.String,
 			Role:      string(user.Role),
@@ -115,16 +186,96 @@ func (s *identitysrvc) ListUsers(
 			UpdatedAt: ptr.Ptr(user.UpdatedAt.Time.String()),
 		})
 	}
-	users.Metadata = CalculateIdentityMetadata(int(count), p.PageNumber, p.PageSize)
+	users.Metadata = listMetadata[identity.PaginationMetadata](ctx, pg, count.Count)
 	return users, nil
 }
 
+// Update a user's name, email or password.
+func (s *identitysrvc) UpdateUser(
+	ctx context.Context,
+	p *identity.UpdateUserPayload,
+) (res *identity.UserResult, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	if err := s.authorizeUser(ctx, ut, p.UserID, authz.ActionUpdate); err != nil {
+		return nil, err
+	}
+	if p.Email != nil {
+		// The email address is where password resets are sent, so only the
+		// user or a platform admin may change it. The new address must be
+		// verified again either way.
+		if err := selfOrPlatformAdmin(ut, p.UserID, "email addresses can only be changed by their owner or a platform admin"); err != nil {
+			return nil, err
+		}
+	}
+	if p.Password != nil {
+		// Admins reset other users' passwords through the reset flow instead.
+		if p.UserID != ut.UserUUID {
+			return nil, &identity.Forbidden{
+				Name:    "forbidden",
+				Message: "permission denied",
+				Detail:  "passwords can only be changed by their owner",
+			}
+		}
+		if p.CurrentPassword == nil {
+			return nil, &identity.BadRequest{
+				Name:    "bad request",
+				Message: "current password is required",
+				Detail:  "current_password must be provided to change the password",
+			}
+		}
+		if err := s.accounts.ChangePassword(ctx, p.UserID, *p.CurrentPassword, *p.Password); err != nil {
+			return nil, s.accountError(err)
+		}
+	}
+	u, err := s.accounts.UpdateProfile(ctx, p.UserID, p.Name, p.Email)
+	if err != nil {
+		return nil, s.accountError(err)
+	}
+	return s.userResult(ctx, ut, u.Uuid)
+}
+
+// Delete a user along with their tokens, sessions and any teams they are the
+// only member of.
+func (s *identitysrvc) DeleteUser(ctx context.Context, p *identity.DeleteUserPayload) error {
+	ut := auth.CtxAuthInfo(ctx)
+	if err := s.authorizeUser(ctx, ut, p.UserID, authz.ActionDelete); err != nil {
+		return err
+	}
+	if err := selfOrPlatformAdmin(ut, p.UserID, "users can only be deleted by themselves or a platform admin"); err != nil {
+		return err
+	}
+	if err := s.accounts.DeleteUser(ctx, p.UserID); err != nil {
+		return s.accountError(err)
+	}
+	return nil
+}
+
+// Upload a profile photo.
+func (s *identitysrvc) UploadProfilePhoto(
+	ctx context.Context,
+	p *identity.UploadProfilePhotoPayload,
+	body io.ReadCloser,
+) (res *identity.UserResult, err error) {
+	defer body.Close()
+	ut := auth.CtxAuthInfo(ctx)
+	if err := s.authorizeUser(ctx, ut, p.UserID, authz.ActionUpdate); err != nil {
+		return nil, err
+	}
+	if _, err := s.accounts.SetProfilePhoto(ctx, p.UserID, body); err != nil {
+		return nil, s.accountError(err)
+	}
+	return s.userResult(ctx, ut, p.UserID)
+}
+
 // Create a new team
 func (s *identitysrvc) CreateTeam(
 	ctx context.Context,
 	p *identity.CreateTeamPayload,
 ) (res *identity.Team, err error) {
 	ut := auth.CtxAuthInfo(ctx)
+	if err := authz.AuthorizePlatform(ut.PlatformAdmin, authz.ResourceTeam, authz.ActionCreate); err != nil {
+		return nil, identityForbidden(err)
+	}
 	t, err := s.db.CreateTeam(ctx, store.CreateTeamParams{
 		UserID: pgtype.Text{String: ut.UserUUID, Valid: true},
 		Name:   p.Team.Name,
@@ -132,24 +283,12 @@ func (s *identitysrvc) CreateTeam(
 	if err != nil {
 		var pgErr *pgconn.PgError
 		switch {
-		case errors.As(err, &pgErr) && pgErr.Code == "P0001":
-			return nil, &identity.Unauthorized{
-				Message: "user does not have permission to create team",
-			}
 		case errors.As(err, &pgErr) && pgErr.Code == "23505":
 			s.logger.Error().Err(err).Msg("error creating team")
 			return nil, &identity.BadRequest{
 				Name:    "bad request",
 				Message: "team name or email already exists",
 			}
-		case errors.As(err, &pgErr):
-			s.logger.Error().Err(err).Interface("pgError", pgErr).Msg("error creating team")
-			return nil, &identity.ServerError{
-				Name:    "internal server error",
-				Message: "an unknown error occurred",
-			}
-		case len(t.Uuid) == 0:
-			return nil, &identity.Unauthorized{Message: "user not authorised to create teams"}
 		default:
 			s.logger.Error().Err(err).Msg("error creating team")
 			return nil, &identity.ServerError{
@@ -166,37 +305,822 @@ func (s *identitysrvc) CreateTeam(
 	}, nil
 }
 
+// List the teams the calling user belongs to.
+func (s *identitysrvc) ListTeams(
+	ctx context.Context,
+	p *identity.ListTeamsPayload,
+) (res *identity.Teams, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	if err := authz.Authorize(authz.Role(ut.Role), authz.ResourceTeam, authz.ActionRead); err != nil {
+		return nil, identityForbidden(err)
+	}
+	userID := pgtype.Text{String: ut.UserUUID, Valid: true}
+	pg, err := listPage[identity.BadRequest](p.PageSize, p.PageNumber, p.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	count, err := s.db.CountTeams(ctx, store.CountTeamsParams{UserID: userID, MaxID: maxID(pg)})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error counting teams")
+		return nil, &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	pg = pg.Anchored(count.MaxID)
+	rows, err := s.db.ListTeams(ctx, store.ListTeamsParams{
+		UserID: userID,
+		MaxID:  maxID(pg),
+		Lim:    pg.Limit(),
+		Off:    pg.Offset(),
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error listing teams")
+		return nil, &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	res = &identity.Teams{Teams: []*identity.Team{}}
+	for _, t := range rows {
+		res.Teams = append(res.Teams, &identity.Team{
+			UUID:             t.Uuid,
+			Name:             t.Name,
+			PersonalTeam:     t.PersonalTeam.Bool,
+			RequireTwoFactor: ptr.Ptr(t.RequireTwoFactor),
+			Role:             ptr.Ptr(string(t.Role)),
+			CreatedAt:        ptr.Ptr(t.CreatedAt.Time.String()),
+			UpdatedAt:        ptr.Ptr(t.UpdatedAt.Time.String()),
+		})
+	}
+	res.Metadata = listMetadata[identity.PaginationMetadata](ctx, pg, count.Count)
+	return res, nil
+}
+
+// Retrieve a team the calling user belongs to, including its members.
+func (s *identitysrvc) RetrieveTeam(
+	ctx context.Context,
+	p *identity.RetrieveTeamPayload,
+) (res *identity.Team, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	role, err := s.teamRole(ctx, ut, p.TeamID)
+	if err != nil {
+		return nil, teamNotFound()
+	}
+	if err := authz.Authorize(role, authz.ResourceTeam, authz.ActionRead); err != nil {
+		return nil, identityForbidden(err)
+	}
+	res, err = s.teamResult(ctx, p.TeamID, role)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.db.ListTeamMembers(ctx, pgtype.Text{String: p.TeamID, Valid: true})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error listing team members")
+		return nil, &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	for _, m := range members {
+		res.Members = append(res.Members, &identity.TeamMember{
+			UserUUID: m.Uuid,
+			Name:     m.Name.String,
+			Email:    m.Email.String,
+			Role:     string(m.Role),
+			JoinedAt: ptr.Ptr(m.CreatedAt.Time.String()),
+		})
+	}
+	return res, nil
+}
+
+// Rename a team
+func (s *identitysrvc) UpdateTeam(
+	ctx context.Context,
+	p *identity.UpdateTeamPayload,
+) (res *identity.Team, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	role, err := s.teamRole(ctx, ut, p.TeamID)
+	if err != nil {
+		return nil, teamNotFound()
+	}
+	if err := authz.Authorize(role, authz.ResourceTeam, authz.ActionUpdate); err != nil {
+		return nil, identityForbidden(err)
+	}
+	t, err := s.db.UpdateTeam(ctx, store.UpdateTeamParams{
+		Uuid: p.TeamID,
+		Name: strings.TrimSpace(p.Name),
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error updating team")
+		return nil, &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	return &identity.Team{
+		UUID:             t.Uuid,
+		Name:             t.Name,
+		PersonalTeam:     t.PersonalTeam.Bool,
+		RequireTwoFactor: ptr.Ptr(t.RequireTwoFactor),
+		Role:             ptr.Ptr(string(role)),
+		CreatedAt:        ptr.Ptr(t.CreatedAt.Time.String()),
+		UpdatedAt:        ptr.Ptr(t.UpdatedAt.Time.String()),
+	}, nil
+}
+
+// Delete a team after removing its domains and cluster resources.
+func (s *identitysrvc) DeleteTeam(ctx context.Context, p *identity.DeleteTeamPayload) error {
+	ut := auth.CtxAuthInfo(ctx)
+	role, err := s.teamRole(ctx, ut, p.TeamID)
+	if err != nil {
+		return teamNotFound()
+	}
+	if err := authz.Authorize(role, authz.ResourceTeam, authz.ActionDelete); err != nil {
+		return identityForbidden(err)
+	}
+	t, err := s.db.GetTeam(ctx, p.TeamID)
+	if err != nil {
+		return teamNotFound()
+	}
+	if t.PersonalTeam.Bool {
+		return &identity.BadRequest{
+			Name:    "bad request",
+			Message: "personal teams cannot be deleted",
+			Detail:  "personal teams are removed when their user is deleted",
+		}
+	}
+	domains, err := s.db.ListTeamDomains(ctx, p.TeamID)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error listing team domains")
+		return &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	// Cluster resources are removed before the rows which reference them so
+	// that a failure can be retried rather than orphaning resources.
+	if err := s.kclient.DeleteTeamResources(ctx, p.TeamID); err != nil {
+		s.logger.Error().Err(err).Str("team", p.TeamID).Msg("error deleting team cluster resources")
+		return &identity.ServerError{
+			Name:    "internal server error",
+			Message: "failed to remove the team's cluster resources",
+		}
+	}
+	// Domains and memberships are removed by ON DELETE CASCADE.
+	if err := s.db.DeleteTeams(ctx, []string{p.TeamID}); err != nil {
+		s.logger.Error().Err(err).Msg("error deleting team")
+		return &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	s.logger.Info().Str("team", p.TeamID).Int("domains", len(domains)).Str("user", ut.UserUUID).Msg("team deleted")
+	return nil
+}
+
+// Make another member the owner of a team.
+func (s *identitysrvc) TransferTeamOwnership(
+	ctx context.Context,
+	p *identity.TransferTeamOwnershipPayload,
+) (res *identity.Team, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	role, err := s.teamRole(ctx, ut, p.TeamID)
+	if err != nil {
+		return nil, teamNotFound()
+	}
+	if err := authz.Authorize(role, authz.ResourceTeam, authz.ActionTransfer); err != nil {
+		return nil, identityForbidden(err)
+	}
+	if p.UserID == ut.UserUUID {
+		return nil, &identity.BadRequest{
+			Name:    "bad request",
+			Message: "you already own this team",
+			Detail:  "choose another member to transfer ownership to",
+		}
+	}
+	t, err := s.db.GetTeam(ctx, p.TeamID)
+	if err != nil {
+		return nil, teamNotFound()
+	}
+	if t.PersonalTeam.Bool {
+		return nil, &identity.BadRequest{
+			Name:    "bad request",
+			Message: "personal teams cannot be transferred",
+			Detail:  "personal teams always belong to their user",
+		}
+	}
+	n, err := s.db.TransferTeamOwnership(ctx, store.TransferTeamOwnershipParams{
+		NewOwnerID: pgtype.Text{String: p.UserID, Valid: true},
+		TeamID:     pgtype.Text{String: p.TeamID, Valid: true},
+		OwnerID:    pgtype.Text{String: ut.UserUUID, Valid: true},
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error transferring team ownership")
+		return nil, &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	if n == 0 {
+		return nil, &identity.NotFound{
+			Name:    "not found",
+			Message: "resource not found",
+			Detail:  "user is not a member of this team",
+		}
+	}
+	// The role resolved during authentication predates the transfer.
+	role, err = s.teamRole(ctx, auth.CtxInfo{UserUUID: ut.UserUUID}, p.TeamID)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error retrieving team role")
+		return nil, &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	return s.teamResult(ctx, p.TeamID, role)
+}
+
+// Select the team the calling user acts within by default.
+func (s *identitysrvc) SwitchTeam(
+	ctx context.Context,
+	p *identity.SwitchTeamPayload,
+) (res *identity.Team, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	n, err := s.db.SwitchTeam(ctx, store.SwitchTeamParams{
+		UserID: ut.UserUUID,
+		TeamID: p.TeamID,
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error switching team")
+		return nil, &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	if n == 0 {
+		return nil, teamNotFound()
+	}
+	role, err := s.teamRole(ctx, ut, p.TeamID)
+	if err != nil {
+		return nil, teamNotFound()
+	}
+	return s.teamResult(ctx, p.TeamID, role)
+}
+
+// Add a user to a team
 func (s *identitysrvc) AddTeamMember(
 	ctx context.Context,
-	payload *identity.AddTeamMemberPayload,
+	p *identity.AddTeamMemberPayload,
 ) (res *identity.Team, err error) {
-	//TODO implement me
-	// must be admin and should only be able to invite members
-	res = &identity.Team{}
-	s.logger.Print("identity.addTeamMember not implemented")
-	//merge this branch and then start on https://kanban.infra.ptco.rocks/task/17
-	return
+	ut := auth.CtxAuthInfo(ctx)
+	role, err := s.teamRole(ctx, ut, p.TeamID)
+	if err != nil {
+		return nil, identityForbidden(err)
+	}
+	if err := authz.AuthorizeMember(role, authz.Role(p.Role), authz.ActionCreate); err != nil {
+		return nil, identityForbidden(err)
+	}
+	err = s.db.AddTeamMember(ctx, store.AddTeamMemberParams{
+		TeamID: pgtype.Text{String: p.TeamID, Valid: true},
+		UserID: pgtype.Text{String: p.UserID, Valid: true},
+		Role:   store.UserRole(p.Role),
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.As(err, &pgErr) && pgErr.Code == "23505":
+			return nil, &identity.BadRequest{
+				Name:    "bad request",
+				Message: "user is already a member of this team",
+				Detail:  "user is already a member of this team",
+			}
+		case errors.As(err, &pgErr) && pgErr.Code == "23503":
+			return nil, &identity.NotFound{
+				Name:    "not found",
+				Message: "resource not found",
+				Detail:  "user does not exist",
+			}
+		default:
+			s.logger.Error().Err(err).Msg("error adding team member")
+			return nil, &identity.ServerError{
+				Name:    "internal server error",
+				Message: "an unknown error occurred",
+			}
+		}
+	}
+	t, err := s.db.GetTeamByUUID(ctx, p.TeamID)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error retrieving team")
+		return nil, &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	return &identity.Team{
+		UUID:         t.Uuid,
+		Name:         t.Name,
+		PersonalTeam: t.PersonalTeam.Bool,
+		CreatedAt:    ptr.Ptr(t.CreatedAt.Time.String()),
+		UpdatedAt:    ptr.Ptr(t.UpdatedAt.Time.String()),
+	}, nil
 }
 
+// Remove a team member from a team
 func (s *identitysrvc) RemoveTeamMember(
 	ctx context.Context,
-	payload *identity.RemoveTeamMemberPayload,
+	p *identity.RemoveTeamMemberPayload,
+) error {
+	ut := auth.CtxAuthInfo(ctx)
+	role, err := s.teamRole(ctx, ut, p.TeamID)
+	if err != nil {
+		return identityForbidden(err)
+	}
+	if err := authz.Authorize(role, authz.ResourceMember, authz.ActionDelete); err != nil {
+		return identityForbidden(err)
+	}
+	target, err := s.teamRole(ctx, auth.CtxInfo{UserUUID: p.UserID}, p.TeamID)
+	if err != nil {
+		return &identity.NotFound{
+			Name:    "not found",
+			Message: "resource not found",
+			Detail:  "user is not a member of this team",
+		}
+	}
+	// Those who can remove members may leave themselves, unless the last owner.
+	if p.UserID != ut.UserUUID {
+		if err := authz.AuthorizeMember(role, target, authz.ActionDelete); err != nil {
+			return identityForbidden(err)
+		}
+	}
+	if target == authz.RoleOwner {
+		owners, err := s.db.CountTeamOwners(ctx, pgtype.Text{String: p.TeamID, Valid: true})
+		if err != nil || owners <= 1 {
+			return &identity.BadRequest{
+				Name:    "bad request",
+				Message: "cannot remove the last owner of a team",
+				Detail:  "transfer ownership before removing this user",
+			}
+		}
+	}
+	_, err = s.db.RemoveTeamMember(ctx, store.RemoveTeamMemberParams{
+		TeamID: pgtype.Text{String: p.TeamID, Valid: true},
+		UserID: pgtype.Text{String: p.UserID, Valid: true},
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error removing team member")
+		return &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	return nil
+}
+
+// Verify the email address of a user with the token they were emailed.
+func (s *identitysrvc) VerifyEmail(ctx context.Context, p *identity.VerifyEmailPayload) error {
+	if _, err := s.accounts.VerifyEmail(ctx, p.Token); err != nil {
+		return s.accountError(err)
+	}
+	return nil
+}
+
+// Email a new verification link to the calling user.
+func (s *identitysrvc) ResendVerification(
+	ctx context.Context,
+	p *identity.ResendVerificationPayload,
 ) error {
-	//TODO implement me
-	// must be admin
-	s.logger.Print("identity.removeTeamMember not implemented")
+	ut := auth.CtxAuthInfo(ctx)
+	if err := s.accounts.SendVerification(ctx, ut.UserUUID); err != nil {
+		return s.accountError(err)
+	}
+	return nil
+}
+
+// Email a password reset link. The response is the same whether or not the
+// email belongs to a user.
+func (s *identitysrvc) ForgotPassword(ctx context.Context, p *identity.ForgotPasswordPayload) error {
+	if err := s.accounts.RequestPasswordReset(ctx, strings.ToLower(p.Email)); err != nil {
+		return s.accountError(err)
+	}
+	return nil
+}
+
+// Set a new password with the token from a password reset email.
+func (s *identitysrvc) ResetPassword(ctx context.Context, p *identity.ResetPasswordPayload) error {
+	if err := s.accounts.ResetPassword(ctx, p.Token, p.Password); err != nil {
+		return s.accountError(err)
+	}
 	return nil
 }
 
-func CalculateIdentityMetadata(totalRecords, page, pageSize int) *identity.PaginationMetadata {
-	if totalRecords == 0 {
-		return &identity.PaginationMetadata{}
+// Start two-factor enrolment for the calling user.
+func (s *identitysrvc) EnableTwoFactor(
+	ctx context.Context,
+	p *identity.EnableTwoFactorPayload,
+) (res *identity.TwoFactorEnrollment, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	secret, uri, err := s.accounts.EnrollTwoFactor(ctx, ut.UserUUID)
+	if err != nil {
+		return nil, s.accountError(err)
+	}
+	return &identity.TwoFactorEnrollment{Secret: secret, ProvisioningURI: uri}, nil
+}
+
+// Activate two-factor authentication and return recovery codes.
+func (s *identitysrvc) ConfirmTwoFactor(
+	ctx context.Context,
+	p *identity.ConfirmTwoFactorPayload,
+) (res *identity.RecoveryCodes, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	codes, err := s.accounts.ConfirmTwoFactor(ctx, ut.UserUUID, p.Code)
+	if err != nil {
+		return nil, s.accountError(err)
+	}
+	return &identity.RecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+// Disable two-factor authentication.
+func (s *identitysrvc) DisableTwoFactor(
+	ctx context.Context,
+	p *identity.DisableTwoFactorPayload,
+) error {
+	ut := auth.CtxAuthInfo(ctx)
+	if err := s.accounts.DisableTwoFactor(ctx, ut.UserUUID, p.Code); err != nil {
+		return s.accountError(err)
+	}
+	return nil
+}
+
+// Replace all recovery codes with a new set.
+func (s *identitysrvc) RegenerateRecoveryCodes(
+	ctx context.Context,
+	p *identity.RegenerateRecoveryCodesPayload,
+) (res *identity.RecoveryCodes, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	codes, err := s.accounts.RegenerateRecoveryCodes(ctx, ut.UserUUID, p.Code)
+	if err != nil {
+		return nil, s.accountError(err)
+	}
+	return &identity.RecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+// Require every member of a team to use two-factor authentication.
+func (s *identitysrvc) SetTeamTwoFactorPolicy(
+	ctx context.Context,
+	p *identity.SetTeamTwoFactorPolicyPayload,
+) (res *identity.Team, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	role, err := s.teamRole(ctx, ut, p.TeamID)
+	if err != nil {
+		return nil, identityForbidden(err)
+	}
+	if err := authz.Authorize(role, authz.ResourceTeam, authz.ActionUpdate); err != nil {
+		return nil, identityForbidden(err)
+	}
+	t, err := s.db.SetTeamTwoFactorPolicy(ctx, store.SetTeamTwoFactorPolicyParams{
+		Uuid:             p.TeamID,
+		RequireTwoFactor: p.Required,
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error updating team two-factor policy")
+		return nil, &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	return &identity.Team{
+		UUID:             t.Uuid,
+		Name:             t.Name,
+		PersonalTeam:     t.PersonalTeam.Bool,
+		RequireTwoFactor: &t.RequireTwoFactor,
+		CreatedAt:        ptr.Ptr(t.CreatedAt.Time.String()),
+		UpdatedAt:        ptr.Ptr(t.UpdatedAt.Time.String()),
+	}, nil
+}
+
+// List audit events for the current team, newest first.
+func (s *identitysrvc) ListAuditEvents(
+	ctx context.Context,
+	p *identity.ListAuditEventsPayload,
+) (res *identity.AuditEvents, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	if err := authz.Authorize(authz.Role(ut.Role), authz.ResourceAudit, authz.ActionRead); err != nil {
+		return nil, identityForbidden(err)
+	}
+	filter := store.CountAuditEventsParams{
+		TeamID: pgtype.Text{String: ut.TeamUUID, Valid: true},
+	}
+	if p.Actor != nil {
+		filter.ActorUserID = pgtype.Text{String: *p.Actor, Valid: true}
+	}
+	if p.Resource != nil {
+		filter.ResourceType = pgtype.Text{String: *p.Resource, Valid: true}
+	}
+	// The formats were validated by the generated decoder.
+	if p.Since != nil {
+		t, _ := time.Parse(time.RFC3339, *p.Since)
+		filter.Since = pgtype.Timestamptz{Time: t, Valid: true}
+	}
+	if p.Until != nil {
+		t, _ := time.Parse(time.RFC3339, *p.Until)
+		filter.Until = pgtype.Timestamptz{Time: t, Valid: true}
+	}
+	pg, err := listPage[identity.BadRequest](p.PageSize, p.PageNumber, p.Cursor)
+	if err != nil {
+		return nil, err
 	}
-	return &identity.PaginationMetadata{
-		CurrentPage: int32(page),
-		PageSize:    int32(pageSize),
-		FirstPage:   1,
-		LastPage:    int32(int(math.Ceil(float64(totalRecords) / float64(pageSize)))),
-		Total:       int32(totalRecords),
+	// Pages are anchored to the newest event when paging started so that
+	// events recorded meanwhile do not shift later pages.
+	filter.MaxID = maxID(pg)
+	count, err := s.db.CountAuditEvents(ctx, filter)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error counting audit events")
+		return nil, &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	pg = pg.Anchored(count.MaxID)
+	events, err := s.db.ListAuditEvents(ctx, store.ListAuditEventsParams{
+		TeamID:       filter.TeamID,
+		ActorUserID:  filter.ActorUserID,
+		ResourceType: filter.ResourceType,
+		Since:        filter.Since,
+		Until:        filter.Until,
+		MaxID:        maxID(pg),
+		Lim:          pg.Limit(),
+		Off:          pg.Offset(),
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error listing audit events")
+		return nil, &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	res = &identity.AuditEvents{Events: []*identity.AuditEvent{}}
+	for _, e := range events {
+		ev := &identity.AuditEvent{
+			ID:           e.ID,
+			Action:       e.Action,
+			ResourceType: e.ResourceType,
+			ResourceID:   e.ResourceID,
+			RequestID:    e.RequestID,
+			Outcome:      e.Outcome,
+			CreatedAt:    e.CreatedAt.Time.String(),
+		}
+		if e.ActorUserID.Valid {
+			ev.ActorUserID = &e.ActorUserID.String
+		}
+		if e.ActorServiceAccountID.Valid {
+			ev.ActorServiceAccountID = &e.ActorServiceAccountID.String
+		}
+		if e.ActorTokenID.Valid {
+			ev.ActorTokenID = &e.ActorTokenID.Int64
+		}
+		if e.TeamID.Valid {
+			ev.TeamID = &e.TeamID.String
+		}
+		if e.IpAddress.Valid {
+			ev.IPAddress = &e.IpAddress.String
+		}
+		res.Events = append(res.Events, ev)
+	}
+	res.Metadata = listMetadata[identity.PaginationMetadata](ctx, pg, count.Count)
+	return res, nil
+}
+
+// Mint a new API key. The requested scopes must be a subset of the scopes held
+// by the calling key.
+func (s *identitysrvc) CreateToken(
+	ctx context.Context,
+	p *identity.CreateTokenPayload,
+) (res *identity.TokenResult, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	if err := authz.Authorize(authz.Role(ut.Role), authz.ResourceToken, authz.ActionCreate); err != nil {
+		return nil, identityForbidden(err)
+	}
+	if err := auth.ValidateSubset(p.Token.Scopes, ut.Scopes); err != nil {
+		return nil, identity.InvalidScopes(err.Error())
+	}
+	var teamID pgtype.Text
+	if p.Token.TeamID != nil {
+		_, err := s.db.GetTeamMembership(ctx, store.GetTeamMembershipParams{
+			TeamID: pgtype.Text{String: *p.Token.TeamID, Valid: true},
+			UserID: pgtype.Text{String: ut.UserUUID, Valid: true},
+		})
+		if err != nil {
+			return nil, &identity.Forbidden{
+				Name:    "forbidden",
+				Message: "team access denied",
+				Detail:  "keys can only be bound to teams the user belongs to",
+			}
+		}
+		teamID = pgtype.Text{String: *p.Token.TeamID, Valid: true}
+	}
+	abilities, err := auth.EncodeAbilities(p.Token.Scopes)
+	if err != nil {
+		return nil, &identity.BadRequest{
+			Name:    "bad request",
+			Message: "invalid scopes",
+			Detail:  err.Error(),
+		}
+	}
+	t, err := s.db.CreatePersonalAccessToken(ctx, store.CreatePersonalAccessTokenParams{
+		TokenableID: ut.UserUUID,
+		Name:        p.Token.Name,
+		Abilities:   pgtype.Text{String: abilities, Valid: true},
+		TeamID:      teamID,
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error creating token")
+		return nil, &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	scopes, err := auth.ParseAbilities(t.Abilities.String)
+	if err != nil {
+		scopes = p.Token.Scopes
+	}
+	res = &identity.TokenResult{
+		ID:        &t.ID,
+		Name:      t.Name,
+		Key:       t.Token,
+		Scopes:    scopes,
+		CreatedAt: ptr.Ptr(t.CreatedAt.Time.String()),
+	}
+	if t.TeamID.Valid {
+		res.TeamID = &t.TeamID.String
+	}
+	return res, nil
+}
+
+// teamRole returns the role held by the user in ut within teamID. The role
+// resolved during authentication is reused when teamID is the current team.
+func (s *identitysrvc) teamRole(
+	ctx context.Context,
+	ut auth.CtxInfo,
+	teamID string,
+) (authz.Role, error) {
+	if teamID == ut.TeamUUID && ut.Role != "" {
+		return authz.Role(ut.Role), nil
+	}
+	m, err := s.db.GetTeamMembership(ctx, store.GetTeamMembershipParams{
+		TeamID: pgtype.Text{String: teamID, Valid: true},
+		UserID: pgtype.Text{String: ut.UserUUID, Valid: true},
+	})
+	if err != nil {
+		return "", fmt.Errorf("%w: not a member of team %q", authz.ErrForbidden, teamID)
+	}
+	return authz.Role(m.Role), nil
+}
+
+// authorizeUser checks that the caller in ut may act on userID. Users may
+// always act on themselves. Acting on another user requires the identity:admin
+// scope and a role permitting action in a team the user belongs to.
+func (s *identitysrvc) authorizeUser(ctx context.Context, ut auth.CtxInfo, userID string, action authz.Action) error {
+	if userID == ut.UserUUID {
+		return nil
+	}
+	if !slices.Contains(ut.Scopes, design.ScopeIdentityAdmin) {
+		return identity.InvalidScopes(fmt.Sprintf("%s is required to manage other users", design.ScopeIdentityAdmin))
+	}
+	_, err := s.db.GetTeamMembership(ctx, store.GetTeamMembershipParams{
+		TeamID: pgtype.Text{String: ut.TeamUUID, Valid: true},
+		UserID: pgtype.Text{String: userID, Valid: true},
+	})
+	if err != nil {
+		return &identity.NotFound{
+			Name:    "not found",
+			Message: "resource not found",
+			Detail:  "user is not a member of this team",
+		}
+	}
+	if err := authz.Authorize(authz.Role(ut.Role), authz.ResourceUser, action); err != nil {
+		return identityForbidden(err)
+	}
+	return nil
+}
+
+// selfOrPlatformAdmin allows changes to userID which a team role alone does
+// not grant, such as replacing the email address of another member.
+func selfOrPlatformAdmin(ut auth.CtxInfo, userID, detail string) error {
+	if userID == ut.UserUUID || ut.PlatformAdmin {
+		return nil
+	}
+	return &identity.Forbidden{
+		Name:    "forbidden",
+		Message: "permission denied",
+		Detail:  detail,
+	}
+}
+
+// userResult loads userID as seen by the caller in ut.
+func (s *identitysrvc) userResult(ctx context.Context, ut auth.CtxInfo, userID string) (*identity.UserResult, error) {
+	u, err := s.db.GetUserByID(ctx, store.GetUserByIDParams{
+		Uuid:   userID,
+		UserID: pgtype.Text{String: ut.UserUUID, Valid: true},
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Str("user", userID).Msg("error retrieving user")
+		return nil, &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	return userResultFromRow(u), nil
+}
+
+func userResultFromRow(u store.GetUserByIDRow) *identity.UserResult {
+	res := &identity.UserResult{
+		UserUUID:  &u.Uuid,
+		Name:      u.Name.String,
+		Email:     u.Email.String,
+		Role:      string(u.Role),
+		CreatedAt: ptr.Ptr(u.CreatedAt.Time.String()),
+		UpdatedAt: ptr.Ptr(u.UpdatedAt.Time.String()),
+	}
+	if u.ProfilePhotoPath.Valid {
+		res.ProfilePhotoURL = &u.ProfilePhotoPath.String
+	}
+	return res
+}
+
+// teamResult loads teamID for a caller holding role.
+func (s *identitysrvc) teamResult(ctx context.Context, teamID string, role authz.Role) (*identity.Team, error) {
+	t, err := s.db.GetTeam(ctx, teamID)
+	if err != nil {
+		return nil, teamNotFound()
+	}
+	return &identity.Team{
+		UUID:             t.Uuid,
+		Name:             t.Name,
+		PersonalTeam:     t.PersonalTeam.Bool,
+		RequireTwoFactor: ptr.Ptr(t.RequireTwoFactor),
+		Role:             ptr.Ptr(string(role)),
+		CreatedAt:        ptr.Ptr(t.CreatedAt.Time.String()),
+		UpdatedAt:        ptr.Ptr(t.UpdatedAt.Time.String()),
+	}, nil
+}
+
+// accountError maps errors from the account flows to service errors.
+func (s *identitysrvc) accountError(err error) error {
+	switch {
+	case errors.Is(err, account.ErrInvalidToken),
+		errors.Is(err, account.ErrPasswordTooWeak),
+		errors.Is(err, account.ErrAlreadyVerified),
+		errors.Is(err, account.ErrInvalidCode),
+		errors.Is(err, account.ErrTwoFactorEnabled),
+		errors.Is(err, account.ErrTwoFactorNotEnrolled),
+		errors.Is(err, account.ErrTwoFactorUnavailable),
+		errors.Is(err, account.ErrEmailTaken),
+		errors.Is(err, account.ErrIncorrectPassword),
+		errors.Is(err, account.ErrSoleAdmin),
+		errors.Is(err, account.ErrStorageDisabled),
+		errors.Is(err, account.ErrPhotoTooLarge),
+		errors.Is(err, account.ErrPhotoUnsupported):
+		return &identity.BadRequest{
+			Name:    "bad request",
+			Message: err.Error(),
+			Detail:  err.Error(),
+		}
+	case errors.Is(err, account.ErrUserNotFound):
+		return &identity.NotFound{
+			Name:    "not found",
+			Message: "resource not found",
+			Detail:  err.Error(),
+		}
+	case errors.Is(err, account.ErrTwoFactorRequired):
+		return &identity.Forbidden{
+			Name:    "forbidden",
+			Message: err.Error(),
+			Detail:  err.Error(),
+		}
+	default:
+		s.logger.Error().Err(err).Msg("account flow failed")
+		return &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+}
+
+// teamNotFound is returned for teams which do not exist or which the caller
+// is not a member of, so that team IDs cannot be probed.
+func teamNotFound() *identity.NotFound {
+	return &identity.NotFound{
+		Name:    "not found",
+		Message: "resource not found",
+		Detail:  "team not found",
+	}
+}
+
+func identityForbidden(err error) *identity.Forbidden {
+	return &identity.Forbidden{
+		Name:    "forbidden",
+		Message: "permission denied",
+		Detail:  err.Error(),
 	}
 }
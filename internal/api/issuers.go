@@ -0,0 +1,341 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	cm "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	assets "github.com/danielmichaels/tawny"
+	"github.com/danielmichaels/tawny/gen/issuers"
+	"github.com/danielmichaels/tawny/internal/auth"
+	"github.com/danielmichaels/tawny/internal/authz"
+	"github.com/danielmichaels/tawny/internal/k8sclient"
+	"github.com/danielmichaels/tawny/internal/logger"
+	"github.com/danielmichaels/tawny/internal/ptr"
+	"github.com/danielmichaels/tawny/internal/store"
+	"goa.design/goa/v3/security"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// issuers service implementation. Issuers are cert-manager ClusterIssuers;
+// shared issuers have no team label and are managed by tawny itself.
+type issuerssrvc struct {
+	logger  *logger.Logger
+	db      *store.Queries
+	kclient *k8sclient.K8sClient
+	// secretNamespace is where cert-manager reads issuer credentials from.
+	secretNamespace string
+}
+
+// NewIssuers returns the issuers service implementation.
+func NewIssuers(
+	logger *logger.Logger,
+	db *store.Queries,
+	kclient *k8sclient.K8sClient,
+	secretNamespace string,
+) issuers.Service {
+	return &issuerssrvc{logger, db, kclient, secretNamespace}
+}
+
+// APIKeyAuth implements the authorization logic for service "issuers" for the
+// "api_key" security scheme.
+func (s *issuerssrvc) APIKeyAuth(
+	ctx context.Context,
+	key string,
+	scheme *security.APIKeyScheme,
+) (context.Context, error) {
+	return validateAPIKey[issuers.InvalidScopes, issuers.Forbidden, issuers.Unauthorized](ctx, s.logger, s.db, key, scheme, nil)
+}
+
+// List the shared issuers and those owned by the caller's team.
+func (s *issuerssrvc) ListIssuers(
+	ctx context.Context,
+	p *issuers.ListIssuersPayload,
+) (res *issuers.IssuersResult, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	if err := s.authorize(ut, authz.ActionRead); err != nil {
+		return nil, err
+	}
+	pg, err := listPage[issuers.BadRequest](p.PageSize, p.PageNumber, p.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	all, err := s.kclient.ListClusterIssuers(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error listing cluster issuers")
+		return nil, issuersServerError()
+	}
+	var visible []cm.ClusterIssuer
+	for _, i := range all {
+		if issuerVisible(i.Labels, ut.TeamUUID) {
+			visible = append(visible, i)
+		}
+	}
+	// Issuers have no row IDs so pages are anchored to the newest creation
+	// time instead.
+	pg, page, total := pageOf(pg, visible, func(i cm.ClusterIssuer) int64 { return i.CreationTimestamp.Unix() })
+	res = &issuers.IssuersResult{Issuers: issuers.IssuerResultCollection{}}
+	for _, i := range page {
+		res.Issuers = append(res.Issuers, issuerResult(&i))
+	}
+	res.Metadata = listMetadata[issuers.PaginationMetadata](ctx, pg, total)
+	return res, nil
+}
+
+// Create an issuer owned by the caller's team.
+func (s *issuerssrvc) CreateIssuer(
+	ctx context.Context,
+	p *issuers.CreateIssuerPayload,
+) (res *issuers.IssuerResult, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	if err := s.authorize(ut, authz.ActionCreate); err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(p.Issuer, assets.AppName+"-") {
+		return nil, &issuers.BadRequest{
+			Name:    "bad request",
+			Message: "invalid issuer name",
+			Detail:  "names starting with " + assets.AppName + "- are reserved",
+		}
+	}
+	solver, credentials, err := s.solver(ctx, p.Issuer, p.Email, p.CertificateType, p.Solver, p.DNS, false)
+	if err != nil {
+		return nil, err
+	}
+	// The ClusterIssuer claims the name before credentials are stored under
+	// it, so concurrent creates cannot overwrite each other's credentials.
+	i, err := s.kclient.CreateClusterIssuer(ctx,
+		k8sclient.WithClusterIssuerCustomName(p.Issuer),
+		k8sclient.WithClusterIssuerTeam(ut.TeamUUID),
+		solver,
+	)
+	if apierrors.IsAlreadyExists(err) {
+		return nil, issuerExists()
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Str("issuer", p.Issuer).Msg("error creating cluster issuer")
+		return nil, issuersServerError()
+	}
+	if err := s.storeCredentials(ctx, ut.TeamUUID, p.Issuer, credentials); err != nil {
+		if err := s.kclient.DeleteClusterIssuer(ctx, p.Issuer); err != nil && !apierrors.IsNotFound(err) {
+			s.logger.Error().Err(err).Str("issuer", p.Issuer).Msg("error removing cluster issuer")
+		}
+		return nil, err
+	}
+	return issuerResult(i), nil
+}
+
+// Replace the configuration of an issuer owned by the caller's team.
+func (s *issuerssrvc) UpdateIssuer(
+	ctx context.Context,
+	p *issuers.UpdateIssuerPayload,
+) (res *issuers.IssuerResult, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	if err := s.authorize(ut, authz.ActionUpdate); err != nil {
+		return nil, err
+	}
+	existing, err := s.kclient.GetClusterIssuer(ctx, p.Issuer)
+	if apierrors.IsNotFound(err) {
+		return nil, issuerNotFound()
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Str("issuer", p.Issuer).Msg("error retrieving cluster issuer")
+		return nil, issuersServerError()
+	}
+	team, owned := existing.Labels[k8sclient.LabelTeam]
+	if !owned {
+		return nil, &issuers.Forbidden{
+			Name:    "forbidden",
+			Message: "permission denied",
+			Detail:  "shared issuers are managed by " + assets.AppName,
+		}
+	}
+	if team != ut.TeamUUID {
+		return nil, issuerNotFound()
+	}
+	solver, credentials, err := s.solver(ctx, p.Issuer, p.Email, p.CertificateType, p.Solver, p.DNS, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.storeCredentials(ctx, ut.TeamUUID, p.Issuer, credentials); err != nil {
+		return nil, err
+	}
+	i, err := s.kclient.UpdateClusterIssuer(ctx, p.Issuer, solver)
+	if err != nil {
+		s.logger.Error().Err(err).Str("issuer", p.Issuer).Msg("error updating cluster issuer")
+		return nil, issuersServerError()
+	}
+	if p.Solver != k8sclient.SolverDNS01 {
+		err := s.kclient.DeleteSecret(ctx, k8sclient.DNSCredentialsSecretName(p.Issuer), s.secretNamespace)
+		if err != nil && !apierrors.IsNotFound(err) {
+			s.logger.Error().Err(err).Str("issuer", p.Issuer).Msg("error removing dns credentials")
+		}
+	}
+	return issuerResult(i), nil
+}
+
+// solver returns the option configuring how an issuer solves challenges and
+// the DNS provider credentials to store with storeCredentials, if any. When
+// updating, the stored credentials are kept if none are given.
+func (s *issuerssrvc) solver(
+	ctx context.Context,
+	issuer, email, certificateType, solver string,
+	dns *issuers.DNS01,
+	update bool,
+) (k8sclient.ClusterIssuerOption, map[string]string, error) {
+	server, err := k8sclient.ACMEServer(certificateType)
+	if err != nil {
+		return nil, nil, issuersBadRequest("invalid certificate type", err)
+	}
+	if solver != k8sclient.SolverDNS01 {
+		if dns != nil {
+			return nil, nil, issuersBadRequest("invalid solver", errors.New("dns settings require the dns01 solver"))
+		}
+		return k8sclient.WithClusterIssuerACMEHTTP01(email, server, issuer), nil, nil
+	}
+	if dns == nil {
+		return nil, nil, issuersBadRequest("invalid solver", errors.New("the dns01 solver requires dns settings"))
+	}
+	provider, err := k8sclient.GetDNSProvider(dns.Provider)
+	if err != nil {
+		return nil, nil, issuersBadRequest("invalid dns provider", err)
+	}
+	credentials := dns.Credentials
+	if credentials == nil && !update {
+		credentials = map[string]string{}
+	}
+	if err := provider.Validate(dns.Settings, credentials); err != nil {
+		return nil, nil, issuersBadRequest("invalid dns settings", err)
+	}
+
+	secret := k8sclient.DNSCredentialsSecretName(issuer)
+	var keys []string
+	if credentials == nil {
+		keys, err = s.storedCredentials(ctx, secret)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, k := range provider.Credentials {
+			if !slices.Contains(keys, k) {
+				return nil, nil, issuersBadRequest("invalid dns settings", fmt.Errorf("credential %q is required", k))
+			}
+		}
+	}
+	for k := range credentials {
+		keys = append(keys, k)
+	}
+	return k8sclient.WithClusterIssuerACMEDNS01(
+		email, server, issuer, provider.Solver(dns.Settings, secret, keys), dns.Zones,
+	), credentials, nil
+}
+
+// storeCredentials stores the DNS provider credentials of an issuer owned by
+// teamID in its Secret. Nothing is stored when there are none.
+func (s *issuerssrvc) storeCredentials(ctx context.Context, teamID, issuer string, credentials map[string]string) error {
+	if len(credentials) == 0 {
+		return nil
+	}
+	_, err := s.kclient.ApplySecret(ctx, k8sclient.DNSCredentialsSecretName(issuer), s.secretNamespace,
+		k8sclient.WithSecretData(credentials),
+		k8sclient.WithSecretTeam(teamID),
+	)
+	if err != nil {
+		s.logger.Error().Err(err).Str("issuer", issuer).Msg("error storing dns credentials")
+		return issuersServerError()
+	}
+	return nil
+}
+
+// storedCredentials returns the keys of the DNS credentials Secret of an
+// issuer, or none when there is no Secret.
+func (s *issuerssrvc) storedCredentials(ctx context.Context, secret string) ([]string, error) {
+	res, err := s.kclient.GetSecret(ctx, secret, s.secretNamespace)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Str("secret", secret).Msg("error retrieving dns credentials")
+		return nil, issuersServerError()
+	}
+	keys := make([]string, 0, len(res.Data))
+	for k := range res.Data {
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func (s *issuerssrvc) authorize(ut auth.CtxInfo, action authz.Action) error {
+	if err := authz.Authorize(authz.Role(ut.Role), authz.ResourceIssuer, action); err != nil {
+		return &issuers.Forbidden{
+			Name:    "forbidden",
+			Message: "permission denied",
+			Detail:  err.Error(),
+		}
+	}
+	return nil
+}
+
+func issuerResult(i *cm.ClusterIssuer) *issuers.IssuerResult {
+	res := &issuers.IssuerResult{
+		Issuer: i.Name,
+		Ready:  k8sclient.ClusterIssuerReady(i),
+	}
+	if i.Spec.CA != nil {
+		res.CertificateType = ptr.Ptr(k8sclient.CertificateTypeInternal)
+	}
+	if acme := i.Spec.ACME; acme != nil {
+		res.Email = &acme.Email
+		if t := k8sclient.CertificateType(acme.Server); t != "" {
+			res.CertificateType = &t
+		}
+		for _, solver := range acme.Solvers {
+			if solver.DNS01 == nil {
+				res.Solver = ptr.Ptr(k8sclient.SolverHTTP01)
+				continue
+			}
+			res.Solver = ptr.Ptr(k8sclient.SolverDNS01)
+			res.DNSProvider = ptr.Ptr(k8sclient.DNSProviderOf(solver.DNS01))
+			if solver.Selector != nil {
+				res.DNSZones = solver.Selector.DNSZones
+			}
+		}
+	}
+	if team, ok := i.Labels[k8sclient.LabelTeam]; ok {
+		res.TeamID = &team
+	}
+	return res
+}
+
+func issuerNotFound() *issuers.NotFound {
+	return &issuers.NotFound{
+		Name:    "not found",
+		Message: "resource not found",
+		Detail:  "issuer not found",
+	}
+}
+
+func issuerExists() *issuers.BadRequest {
+	return &issuers.BadRequest{
+		Name:    "bad request",
+		Message: "issuer already exists",
+		Detail:  "issuer names are unique across all teams",
+	}
+}
+
+func issuersBadRequest(message string, err error) *issuers.BadRequest {
+	return &issuers.BadRequest{
+		Name:    "bad request",
+		Message: message,
+		Detail:  err.Error(),
+	}
+}
+
+func issuersServerError() *issuers.ServerError {
+	return &issuers.ServerError{
+		Name:    "internal server error",
+		Message: "an unknown error occurred",
+	}
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/danielmichaels/tawny/internal/k8sclient"
+)
+
+func TestIssuerVisible(t *testing.T) {
+	managed := func(extra ...string) map[string]string {
+		l := k8sclient.NewClusterIssuer().Labels
+		for i := 0; i+1 < len(extra); i += 2 {
+			l[extra[i]] = extra[i+1]
+		}
+		return l
+	}
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   bool
+	}{
+		{"shared", managed(), true},
+		{"owned by the team", managed(k8sclient.LabelTeam, "team_1"), true},
+		{"owned by another team", managed(k8sclient.LabelTeam, "team_2"), false},
+		{"not managed by tawny", map[string]string{}, false},
+		{"unmanaged with the team label", map[string]string{k8sclient.LabelTeam: "team_1"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := issuerVisible(tt.labels, "team_1"); got != tt.want {
+				t.Errorf("issuerVisible(%v) = %v, want %v", tt.labels, got, tt.want)
+			}
+		})
+	}
+}
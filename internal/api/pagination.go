@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+
+	"github.com/danielmichaels/tawny/gen/certificates"
+	"github.com/danielmichaels/tawny/gen/domains"
+	"github.com/danielmichaels/tawny/gen/identity"
+	"github.com/danielmichaels/tawny/gen/issuers"
+	"github.com/danielmichaels/tawny/gen/ports"
+	"github.com/danielmichaels/tawny/internal/pagination"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// badRequest is the BadRequest error of a paginated service. goa generates
+// one per service but they share a shape.
+type badRequest interface {
+	identity.BadRequest | domains.BadRequest | issuers.BadRequest | certificates.BadRequest | ports.BadRequest
+}
+
+// paginationMetadata is the PaginationMetadata of a paginated service.
+type paginationMetadata interface {
+	identity.PaginationMetadata | domains.PaginationMetadata | issuers.PaginationMetadata |
+		certificates.PaginationMetadata | ports.PaginationMetadata
+}
+
+// listPage validates the paging parameters of a list method, reporting
+// invalid cursors as the BadRequest B of the calling service.
+func listPage[B badRequest, E interface {
+	*B
+	error
+}](size, number int, cursor *string) (pagination.Page, error) {
+	pg, err := pagination.New(size, number, cursor)
+	if err != nil {
+		e := B(struct{ Name, Message, Detail string }{
+			Name:    "bad request",
+			Message: err.Error(),
+			Detail:  "request the first page again to obtain a new cursor",
+		})
+		return pg, E(&e)
+	}
+	return pg, nil
+}
+
+// listMetadata describes pg within a list of total results as the
+// PaginationMetadata M of the calling service.
+func listMetadata[M paginationMetadata](ctx context.Context, pg pagination.Page, total int64) *M {
+	m := pg.Metadata(ctx, int(total))
+	res := M(struct {
+		Total       int32
+		CurrentPage int32
+		FirstPage   int32
+		LastPage    int32
+		PageSize    int32
+		Next        *string
+		Prev        *string
+		NextCursor  *string
+		PrevCursor  *string
+	}{
+		Total:       int32(m.Total),
+		CurrentPage: int32(m.Current),
+		FirstPage:   int32(m.First),
+		LastPage:    int32(m.Last),
+		PageSize:    int32(m.Size),
+		Next:        optional(m.Next),
+		Prev:        optional(m.Prev),
+		NextCursor:  optional(m.NextCursor),
+		PrevCursor:  optional(m.PrevCursor),
+	})
+	return &res
+}
+
+// maxID is the row ID bound of an anchored page, NULL before the list is
+// anchored.
+func maxID(pg pagination.Page) pgtype.Int8 {
+	return pgtype.Int8{Int64: pg.Anchor, Valid: pg.Anchor != 0}
+}
+
+// pageOf returns the page of items, which are in list order, after dropping
+// those created since paging started. created returns the creation time of an
+// item in Unix seconds. The page is anchored to the newest item and the
+// number of items which remain is returned.
+func pageOf[T any](pg pagination.Page, items []T, created func(T) int64) (pagination.Page, []T, int64) {
+	var newest int64
+	for _, i := range items {
+		newest = max(newest, created(i))
+	}
+	pg = pg.Anchored(newest)
+	var kept []T
+	for _, i := range items {
+		if pg.Anchor == 0 || created(i) <= pg.Anchor {
+			kept = append(kept, i)
+		}
+	}
+	start := min(int(pg.Offset()), len(kept))
+	end := min(start+int(pg.Limit()), len(kept))
+	return pg, kept[start:end], int64(len(kept))
+}
+
+// optional returns nil for empty strings so they are omitted from responses.
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
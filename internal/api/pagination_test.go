@@ -0,0 +1,213 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/danielmichaels/tawny/gen/domains"
+	"github.com/danielmichaels/tawny/internal/auth"
+	"github.com/danielmichaels/tawny/internal/logger"
+	"github.com/danielmichaels/tawny/internal/pagination"
+	"github.com/danielmichaels/tawny/internal/ptr"
+	"github.com/danielmichaels/tawny/internal/store"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// domainsTable holds the domains of a single app, ordered by name as
+// ListDomains returns them.
+type domainsTable struct {
+	rows []store.Domains
+}
+
+func (t *domainsTable) add(name string) {
+	t.rows = append(t.rows, store.Domains{ID: int64(len(t.rows) + 1), Name: name, AppID: "app"})
+	slices.SortFunc(t.rows, func(a, b store.Domains) int { return cmp.Compare(a.Name, b.Name) })
+}
+
+// visible returns the rows bounded by the max_id argument at i.
+func (t *domainsTable) visible(args []any, i int) []store.Domains {
+	bound := args[i].(pgtype.Int8)
+	var res []store.Domains
+	for _, d := range t.rows {
+		if !bound.Valid || d.ID <= bound.Int64 {
+			res = append(res, d)
+		}
+	}
+	return res
+}
+
+func (t *domainsTable) db() fakeDB {
+	return fakeDB{
+		"CountDomains": func(args []any) ([][]any, error) {
+			var newest int64
+			rows := t.visible(args, 2)
+			for _, d := range rows {
+				newest = max(newest, d.ID)
+			}
+			return [][]any{{int64(len(rows)), newest}}, nil
+		},
+		"ListDomains": func(args []any) ([][]any, error) {
+			rows := t.visible(args, 2)
+			lim, off := int(args[3].(int32)), int(args[4].(int32))
+			rows = rows[min(off, len(rows)):min(off+lim, len(rows))]
+			var res [][]any
+			for _, d := range rows {
+				res = append(res, []any{d.ID, d.Uuid, d.TeamID, d.AppID, d.Name, d.CertificateType,
+					nil, nil, d.Issuer, d.TlsSecret, nil, d.Routes})
+			}
+			return res, nil
+		},
+	}
+}
+
+func TestListDomainsPagination(t *testing.T) {
+	tests := []struct {
+		name string
+		// page is requested by number unless a cursor from page cursorFrom
+		// is used.
+		size, number int
+		cursorFrom   int
+		// added are inserted after the cursor was issued.
+		added     []string
+		cursor    *string
+		want      []string
+		wantTotal int32
+		wantNext  bool
+		wantErr   bool
+	}{
+		{
+			name:      "first page",
+			size:      2,
+			number:    1,
+			want:      []string{"a.example.com", "b.example.com"},
+			wantTotal: 5,
+			wantNext:  true,
+		},
+		{
+			name:      "last page",
+			size:      2,
+			number:    3,
+			want:      []string{"e.example.com"},
+			wantTotal: 5,
+		},
+		{
+			name:      "beyond the last page",
+			size:      2,
+			number:    4,
+			want:      []string{},
+			wantTotal: 5,
+		},
+		{
+			name:       "cursor ignores domains added since paging started",
+			size:       2,
+			cursorFrom: 1,
+			added:      []string{"0.example.com", "c1.example.com"},
+			want:       []string{"c.example.com", "d.example.com"},
+			wantTotal:  5,
+			wantNext:   true,
+		},
+		{
+			name:    "malformed cursor",
+			cursor:  ptr.Ptr("not a cursor"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := &domainsTable{}
+			for _, n := range []string{"e", "c", "a", "d", "b"} {
+				table.add(n + ".example.com")
+			}
+			s := &domainssrvc{logger: logger.New("test", false, false), db: store.New(table.db())}
+			ctx := auth.CtxSetAuthInfo(context.Background(), auth.CtxInfo{TeamUUID: testTeam, Role: store.UserRoleViewer})
+			p := &domains.ListDomainsPayload{AppID: "app", PageSize: tt.size, PageNumber: tt.number, Cursor: tt.cursor}
+			if tt.cursorFrom > 0 {
+				first, err := s.ListDomains(ctx, &domains.ListDomainsPayload{AppID: "app", PageSize: tt.size, PageNumber: tt.cursorFrom})
+				if err != nil {
+					t.Fatal(err)
+				}
+				p = &domains.ListDomainsPayload{AppID: "app", Cursor: first.Metadata.NextCursor}
+			}
+			for _, n := range tt.added {
+				table.add(n)
+			}
+
+			res, err := s.ListDomains(ctx, p)
+			if tt.wantErr {
+				var bad *domains.BadRequest
+				if !errors.As(err, &bad) {
+					t.Fatalf("ListDomains() error = %v, want bad request", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ListDomains() error = %v", err)
+			}
+			got := []string{}
+			for _, d := range res.Domains {
+				got = append(got, *d.DomainName)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("domains = %v, want %v", got, tt.want)
+			}
+			if res.Metadata.Total != tt.wantTotal {
+				t.Errorf("total = %d, want %d", res.Metadata.Total, tt.wantTotal)
+			}
+			if (res.Metadata.NextCursor != nil) != tt.wantNext {
+				t.Errorf("next cursor = %v, want present %v", res.Metadata.NextCursor, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestPageOf(t *testing.T) {
+	// Items are their own creation times, in list order.
+	items := []int64{30, 10, 20, 40}
+	created := func(i int64) int64 { return i }
+	tests := []struct {
+		name       string
+		page       pagination.Page
+		want       []int64
+		wantAnchor int64
+		wantTotal  int64
+	}{
+		{
+			name:       "first page anchors to the newest item",
+			page:       pagination.Page{Size: 3, Number: 1},
+			want:       []int64{30, 10, 20},
+			wantAnchor: 40,
+			wantTotal:  4,
+		},
+		{
+			name:       "anchored page drops newer items",
+			page:       pagination.Page{Size: 1, Number: 2, Anchor: 25},
+			want:       []int64{20},
+			wantAnchor: 25,
+			wantTotal:  2,
+		},
+		{
+			name:       "page beyond the end is empty",
+			page:       pagination.Page{Size: 2, Number: 5},
+			want:       []int64{},
+			wantAnchor: 40,
+			wantTotal:  4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pg, got, total := pageOf(tt.page, items, created)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("items = %v, want %v", got, tt.want)
+			}
+			if pg.Anchor != tt.wantAnchor {
+				t.Errorf("anchor = %d, want %d", pg.Anchor, tt.wantAnchor)
+			}
+			if total != tt.wantTotal {
+				t.Errorf("total = %d, want %d", total, tt.wantTotal)
+			}
+		})
+	}
+}
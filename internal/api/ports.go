@@ -0,0 +1,284 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/danielmichaels/tawny/gen/ports"
+	"github.com/danielmichaels/tawny/internal/auth"
+	"github.com/danielmichaels/tawny/internal/authz"
+	"github.com/danielmichaels/tawny/internal/k8sclient"
+	"github.com/danielmichaels/tawny/internal/logger"
+	"github.com/danielmichaels/tawny/internal/ptr"
+	"github.com/danielmichaels/tawny/internal/store"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"goa.design/goa/v3/security"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// ports service implementation. A port mapping is stored in the database and
+// served by an IngressRouteTCP or IngressRouteUDP named after it.
+type portssrvc struct {
+	logger  *logger.Logger
+	db      *store.Queries
+	kclient *k8sclient.K8sClient
+	// entryPoints are the traefik entrypoints ports may be mapped on.
+	entryPoints []k8sclient.EntryPoint
+}
+
+// NewPorts returns the ports service implementation.
+func NewPorts(
+	logger *logger.Logger,
+	db *store.Queries,
+	kclient *k8sclient.K8sClient,
+	entryPoints []k8sclient.EntryPoint,
+) ports.Service {
+	return &portssrvc{logger, db, kclient, entryPoints}
+}
+
+// APIKeyAuth implements the authorization logic for service "ports" for the
+// "api_key" security scheme.
+func (s *portssrvc) APIKeyAuth(
+	ctx context.Context,
+	key string,
+	scheme *security.APIKeyScheme,
+) (context.Context, error) {
+	return validateAPIKey[ports.InvalidScopes, ports.Forbidden, ports.Unauthorized](ctx, s.logger, s.db, key, scheme, nil)
+}
+
+// List the port mappings of an app.
+func (s *portssrvc) ListPorts(
+	ctx context.Context,
+	p *ports.ListPortsPayload,
+) (res *ports.PortsResult, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	if err := authz.Authorize(authz.Role(ut.Role), authz.ResourceDomain, authz.ActionRead); err != nil {
+		return nil, portsForbidden(err)
+	}
+	pg, err := listPage[ports.BadRequest](p.PageSize, p.PageNumber, p.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	count, err := s.db.CountPortMappings(ctx, store.CountPortMappingsParams{
+		TeamID: ut.TeamUUID,
+		AppID:  p.AppID,
+		MaxID:  maxID(pg),
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error counting port mappings")
+		return nil, portsServerError()
+	}
+	pg = pg.Anchored(count.MaxID)
+	rows, err := s.db.ListPortMappings(ctx, store.ListPortMappingsParams{
+		TeamID: ut.TeamUUID,
+		AppID:  p.AppID,
+		MaxID:  maxID(pg),
+		Lim:    pg.Limit(),
+		Off:    pg.Offset(),
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error listing port mappings")
+		return nil, portsServerError()
+	}
+	res = &ports.PortsResult{Ports: ports.PortResultCollection{}}
+	for _, m := range rows {
+		res.Ports = append(res.Ports, s.portResult(m))
+	}
+	res.Metadata = listMetadata[ports.PaginationMetadata](ctx, pg, count.Count)
+	return res, nil
+}
+
+// Map an entrypoint port to a port of the app's service.
+func (s *portssrvc) CreatePort(
+	ctx context.Context,
+	p *ports.CreatePortPayload,
+) (res *ports.PortResult, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	if err := authz.Authorize(authz.Role(ut.Role), authz.ResourceDomain, authz.ActionCreate); err != nil {
+		return nil, portsForbidden(err)
+	}
+	ep, ok := s.entryPoint(p.Port, p.Protocol)
+	if !ok {
+		return nil, portsBadRequest("no entrypoint", fmt.Errorf("no %s entrypoint listens on port %d", p.Protocol, p.Port))
+	}
+	var hostname string
+	if p.Hostname != nil {
+		hostname = strings.ToLower(*p.Hostname)
+	}
+	if p.Passthrough && ep.Protocol != k8sclient.ProtocolTCP {
+		return nil, portsBadRequest("invalid passthrough", errors.New("TLS passthrough requires a tcp entrypoint"))
+	}
+	if hostname != "" && !p.Passthrough {
+		return nil, portsBadRequest("invalid hostname", errors.New("hostnames can only be routed with passthrough"))
+	}
+	_, err = s.kclient.GetTeamService(ctx, k8sclient.ServiceName(p.AppID), k8sclient.DefaultNamespace, ut.TeamUUID)
+	if err != nil && !apierrors.IsNotFound(err) {
+		s.logger.Error().Err(err).Str("app", p.AppID).Msg("error retrieving service")
+		return nil, portsServerError()
+	}
+	if err != nil {
+		return nil, &ports.NotFound{
+			Name:    "not found",
+			Message: "app not found",
+			Detail:  fmt.Sprintf("%s has no service owned by the team", p.AppID),
+		}
+	}
+
+	m, err := s.db.CreatePortMapping(ctx, store.CreatePortMappingParams{
+		TeamID:      ut.TeamUUID,
+		AppID:       p.AppID,
+		Entrypoint:  ep.Name,
+		Protocol:    ep.Protocol,
+		Hostname:    hostname,
+		Passthrough: p.Passthrough,
+		ServicePort: p.ServicePort,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, &ports.BadRequest{
+				Name:    "bad request",
+				Message: "port already mapped",
+				Detail:  fmt.Sprintf("port %d is already mapped for %s", p.Port, orAny(hostname)),
+			}
+		}
+		s.logger.Error().Err(err).Msg("error creating port mapping")
+		return nil, portsServerError()
+	}
+	if err := s.createPortResources(ctx, m); err != nil {
+		s.logger.Error().Err(err).Str("port", m.Uuid).Msg("error creating port resources")
+		if err := s.db.DeletePortMapping(ctx, store.DeletePortMappingParams{TeamID: ut.TeamUUID, Uuid: m.Uuid}); err != nil {
+			s.logger.Error().Err(err).Str("port", m.Uuid).Msg("error removing port mapping")
+		}
+		return nil, portsServerError()
+	}
+	return s.portResult(m), nil
+}
+
+// Remove a port mapping of an app.
+func (s *portssrvc) DeletePort(ctx context.Context, p *ports.DeletePortPayload) error {
+	ut := auth.CtxAuthInfo(ctx)
+	if err := authz.Authorize(authz.Role(ut.Role), authz.ResourceDomain, authz.ActionDelete); err != nil {
+		return portsForbidden(err)
+	}
+	m, err := s.db.GetPortMapping(ctx, store.GetPortMappingParams{TeamID: ut.TeamUUID, Uuid: p.PortID})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		s.logger.Error().Err(err).Str("port", p.PortID).Msg("error retrieving port mapping")
+		return portsServerError()
+	}
+	if err != nil || m.AppID != p.AppID {
+		return &ports.NotFound{
+			Name:    "not found",
+			Message: "port not found",
+			Detail:  fmt.Sprintf("%s is not a port of %s", p.PortID, p.AppID),
+		}
+	}
+	name := k8sclient.PortResourceName(m.Uuid)
+	namespace := k8sclient.DefaultNamespace
+	if m.Protocol == k8sclient.ProtocolUDP {
+		err = s.kclient.DeleteIngressUDP(ctx, name, namespace)
+	} else {
+		err = s.kclient.DeleteIngressTCP(ctx, name, namespace)
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		s.logger.Error().Err(err).Str("port", m.Uuid).Msg("error removing port route")
+		return portsServerError()
+	}
+	if err := s.db.DeletePortMapping(ctx, store.DeletePortMappingParams{TeamID: ut.TeamUUID, Uuid: m.Uuid}); err != nil {
+		s.logger.Error().Err(err).Str("port", m.Uuid).Msg("error removing port mapping")
+		return portsServerError()
+	}
+	return nil
+}
+
+// createPortResources creates the route forwarding the entrypoint of m to the
+// app's Service.
+func (s *portssrvc) createPortResources(ctx context.Context, m store.PortMappings) error {
+	name := k8sclient.PortResourceName(m.Uuid)
+	namespace := k8sclient.DefaultNamespace
+	service := k8sclient.ServiceName(m.AppID)
+	if m.Protocol == k8sclient.ProtocolUDP {
+		_, err := s.kclient.CreateIngressUDP(ctx, name, namespace,
+			k8sclient.WithIngressRouteUDPEntryPoint(m.Entrypoint),
+			k8sclient.WithIngressRouteUDPService(service, namespace, m.ServicePort),
+			k8sclient.WithIngressRouteUDPTeam(m.TeamID),
+		)
+		return err
+	}
+	opts := []k8sclient.IngressRouteTCPOption{
+		k8sclient.WithIngressRouteTCPEntryPoint(m.Entrypoint),
+		k8sclient.WithIngressRouteTCPRule(m.Hostname, service, namespace, m.ServicePort),
+		k8sclient.WithIngressRouteTCPTeam(m.TeamID),
+	}
+	if m.Passthrough {
+		opts = append(opts, k8sclient.WithIngressRouteTCPPassthrough())
+	}
+	_, err := s.kclient.CreateIngressTCP(ctx, name, namespace, opts...)
+	return err
+}
+
+// entryPoint returns the entrypoint listening on port with protocol.
+func (s *portssrvc) entryPoint(port int32, protocol string) (k8sclient.EntryPoint, bool) {
+	for _, ep := range s.entryPoints {
+		if ep.Port == port && ep.Protocol == protocol {
+			return ep, true
+		}
+	}
+	return k8sclient.EntryPoint{}, false
+}
+
+func (s *portssrvc) portResult(m store.PortMappings) *ports.PortResult {
+	res := &ports.PortResult{
+		PortID:      m.Uuid,
+		AppID:       m.AppID,
+		Entrypoint:  m.Entrypoint,
+		Protocol:    m.Protocol,
+		ServicePort: m.ServicePort,
+		Passthrough: m.Passthrough,
+		CreatedAt:   ptr.Ptr(m.CreatedAt.Time.String()),
+	}
+	for _, ep := range s.entryPoints {
+		if ep.Name == m.Entrypoint {
+			res.Port = ep.Port
+		}
+	}
+	if m.Hostname != "" {
+		res.Hostname = &m.Hostname
+	}
+	return res
+}
+
+// orAny describes a hostname of a port mapping, which is every hostname when
+// empty.
+func orAny(hostname string) string {
+	if hostname == "" {
+		return "every hostname"
+	}
+	return hostname
+}
+
+func portsForbidden(err error) *ports.Forbidden {
+	return &ports.Forbidden{
+		Name:    "forbidden",
+		Message: "permission denied",
+		Detail:  err.Error(),
+	}
+}
+
+func portsBadRequest(message string, err error) *ports.BadRequest {
+	return &ports.BadRequest{
+		Name:    "bad request",
+		Message: message,
+		Detail:  err.Error(),
+	}
+}
+
+func portsServerError() *ports.ServerError {
+	return &ports.ServerError{
+		Name:    "internal server error",
+		Message: "an unknown error occurred",
+	}
+}
@@ -0,0 +1,502 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/danielmichaels/tawny/gen/releases"
+	"github.com/danielmichaels/tawny/internal/auth"
+	"github.com/danielmichaels/tawny/internal/authz"
+	"github.com/danielmichaels/tawny/internal/k8sclient"
+	"github.com/danielmichaels/tawny/internal/logger"
+	"github.com/danielmichaels/tawny/internal/ptr"
+	"github.com/danielmichaels/tawny/internal/store"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"goa.design/goa/v3/security"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Release strategies. Canary releases send a share of requests to the new
+// version from the start; blue-green releases switch them over at once.
+const (
+	strategyCanary    = "canary"
+	strategyBlueGreen = "blue_green"
+)
+
+// Release statuses. A release is active until promoted or aborted.
+const (
+	releaseActive   = "active"
+	releasePromoted = "promoted"
+	releaseAborted  = "aborted"
+)
+
+// defaultCanaryWeight is the percentage of requests a canary release gets
+// when started without a weight.
+const defaultCanaryWeight = 10
+
+// releases service implementation. A release is stored in the database and
+// runs as a Deployment and Service named after it; the routes of its domain
+// share the app's requests between the app's Service and the release's.
+type releasessrvc struct {
+	logger  *logger.Logger
+	db      *store.Queries
+	kclient *k8sclient.K8sClient
+}
+
+// NewReleases returns the releases service implementation.
+func NewReleases(
+	logger *logger.Logger,
+	db *store.Queries,
+	kclient *k8sclient.K8sClient,
+) releases.Service {
+	return &releasessrvc{logger, db, kclient}
+}
+
+// APIKeyAuth implements the authorization logic for service "releases" for
+// the "api_key" security scheme.
+func (s *releasessrvc) APIKeyAuth(
+	ctx context.Context,
+	key string,
+	scheme *security.APIKeyScheme,
+) (context.Context, error) {
+	return validateAPIKey[releases.InvalidScopes, releases.Forbidden, releases.Unauthorized](ctx, s.logger, s.db, key, scheme, nil)
+}
+
+// Show the active release of an app.
+func (s *releasessrvc) GetRelease(
+	ctx context.Context,
+	p *releases.GetReleasePayload,
+) (res *releases.ReleaseResult, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	if err := authz.Authorize(authz.Role(ut.Role), authz.ResourceApp, authz.ActionRead); err != nil {
+		return nil, releasesForbidden(err)
+	}
+	r, err := s.activeRelease(ctx, ut.TeamUUID, p.AppID)
+	if err != nil {
+		return nil, err
+	}
+	return releaseResult(r), nil
+}
+
+// Stand up a new version of an app next to the running one.
+func (s *releasessrvc) StartRelease(
+	ctx context.Context,
+	p *releases.StartReleasePayload,
+) (res *releases.ReleaseResult, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	if err := authz.Authorize(authz.Role(ut.Role), authz.ResourceApp, authz.ActionCreate); err != nil {
+		return nil, releasesForbidden(err)
+	}
+	var weight int32
+	switch p.Strategy {
+	case strategyCanary:
+		weight = defaultCanaryWeight
+	case strategyBlueGreen:
+		weight = 0
+	}
+	if p.Weight != nil {
+		weight = *p.Weight
+	}
+	if err := validWeight(p.Strategy, weight); err != nil {
+		return nil, err
+	}
+
+	domain := strings.ToLower(p.Domain)
+	d, err := s.db.GetDomain(ctx, store.GetDomainParams{TeamID: ut.TeamUUID, Name: domain})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		s.logger.Error().Err(err).Str("domain", domain).Msg("error retrieving domain")
+		return nil, releasesServerError()
+	}
+	if err != nil {
+		return nil, &releases.NotFound{
+			Name:    "not found",
+			Message: "domain not found",
+			Detail:  fmt.Sprintf("%s is not a domain of the team", domain),
+		}
+	}
+	routes, err := storedRoutes(d)
+	if err != nil {
+		s.logger.Error().Err(err).Str("domain", domain).Msg("error decoding domain routes")
+		return nil, releasesServerError()
+	}
+	if !routesApp(routes, p.AppID) {
+		return nil, releasesBadRequest("app not routed", fmt.Errorf(
+			"no route of %s sends requests to %s; set the routes of the domain first", domain, p.AppID))
+	}
+
+	svc, err := s.appService(ctx, ut.TeamUUID, p.AppID)
+	if err != nil {
+		return nil, err
+	}
+	stable, err := s.kclient.ServiceDeployment(ctx, svc)
+	if err != nil && !apierrors.IsNotFound(err) {
+		s.logger.Error().Err(err).Str("app", p.AppID).Msg("error retrieving deployment")
+		return nil, releasesServerError()
+	}
+	if err != nil {
+		return nil, releasesBadRequest("no deployment", fmt.Errorf("no deployment runs the pods of %s", p.AppID))
+	}
+	if !k8sclient.OwnedByTeam(stable.Labels, ut.TeamUUID) {
+		return nil, releasesForbidden(fmt.Errorf("deployment %s is not owned by the team", stable.Name))
+	}
+	container, err := releaseContainer(stable, p.Container)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := s.db.CreateRelease(ctx, store.CreateReleaseParams{
+		TeamID:           ut.TeamUUID,
+		AppID:            p.AppID,
+		Domain:           domain,
+		Strategy:         p.Strategy,
+		Image:            p.Image,
+		Container:        container,
+		StableDeployment: stable.Name,
+		Weight:           weight,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, releasesBadRequest("release in progress",
+				fmt.Errorf("%s has an active release; promote or abort it first", p.AppID))
+		}
+		s.logger.Error().Err(err).Msg("error creating release")
+		return nil, releasesServerError()
+	}
+	if err := s.createReleaseResources(ctx, r, stable, svc.Spec.Selector, svc.Spec.Ports); err != nil {
+		s.logger.Error().Err(err).Str("release", r.Uuid).Msg("error creating release resources")
+		if err := s.db.DeleteRelease(ctx, store.DeleteReleaseParams{TeamID: ut.TeamUUID, Uuid: r.Uuid}); err != nil {
+			s.logger.Error().Err(err).Str("release", r.Uuid).Msg("error removing release")
+		}
+		s.removeReleaseResources(ctx, r)
+		return nil, releasesServerError()
+	}
+	if err := s.applyRoutes(ctx, ut.TeamUUID, domain); err != nil {
+		s.logger.Error().Err(err).Str("release", r.Uuid).Msg("error routing release")
+		if err := s.db.DeleteRelease(ctx, store.DeleteReleaseParams{TeamID: ut.TeamUUID, Uuid: r.Uuid}); err != nil {
+			s.logger.Error().Err(err).Str("release", r.Uuid).Msg("error removing release")
+		}
+		if err := s.applyRoutes(ctx, ut.TeamUUID, domain); err != nil {
+			s.logger.Error().Err(err).Str("domain", domain).Msg("error restoring domain routes")
+		}
+		s.removeReleaseResources(ctx, r)
+		return nil, releasesServerError()
+	}
+	return releaseResult(r), nil
+}
+
+// Change the percentage of requests sent to the new version.
+func (s *releasessrvc) ShiftRelease(
+	ctx context.Context,
+	p *releases.ShiftReleasePayload,
+) (res *releases.ReleaseResult, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	if err := authz.Authorize(authz.Role(ut.Role), authz.ResourceApp, authz.ActionUpdate); err != nil {
+		return nil, releasesForbidden(err)
+	}
+	r, err := s.activeRelease(ctx, ut.TeamUUID, p.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validWeight(r.Strategy, p.Weight); err != nil {
+		return nil, err
+	}
+	updated, err := s.db.SetReleaseWeight(ctx, store.SetReleaseWeightParams{
+		TeamID: ut.TeamUUID,
+		Uuid:   r.Uuid,
+		Weight: p.Weight,
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Str("release", r.Uuid).Msg("error updating release weight")
+		return nil, releasesServerError()
+	}
+	if err := s.applyRoutes(ctx, ut.TeamUUID, r.Domain); err != nil {
+		s.logger.Error().Err(err).Str("release", r.Uuid).Msg("error routing release")
+		_, err := s.db.SetReleaseWeight(ctx, store.SetReleaseWeightParams{
+			TeamID: ut.TeamUUID,
+			Uuid:   r.Uuid,
+			Weight: r.Weight,
+		})
+		if err != nil {
+			s.logger.Error().Err(err).Str("release", r.Uuid).Msg("error restoring release weight")
+		}
+		return nil, releasesServerError()
+	}
+	return releaseResult(updated), nil
+}
+
+// Make the new version the app's and remove the old one. The app's Service
+// is pointed at the pods of the release before the domain's routes are sent
+// to it alone, so requests are served by the new version throughout.
+func (s *releasessrvc) PromoteRelease(
+	ctx context.Context,
+	p *releases.PromoteReleasePayload,
+) (res *releases.ReleaseResult, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	if err := authz.Authorize(authz.Role(ut.Role), authz.ResourceApp, authz.ActionUpdate); err != nil {
+		return nil, releasesForbidden(err)
+	}
+	r, err := s.activeRelease(ctx, ut.TeamUUID, p.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.appService(ctx, ut.TeamUUID, r.AppID); err != nil {
+		return nil, err
+	}
+	name := k8sclient.ReleaseResourceName(r.Uuid)
+	namespace := k8sclient.DefaultNamespace
+	stable, err := s.kclient.GetDeployment(ctx, r.StableDeployment, namespace)
+	if err != nil && !apierrors.IsNotFound(err) {
+		s.logger.Error().Err(err).Str("deployment", r.StableDeployment).Msg("error retrieving previous version")
+		return nil, releasesServerError()
+	}
+	if err == nil && !k8sclient.OwnedByTeam(stable.Labels, ut.TeamUUID) {
+		return nil, releasesForbidden(fmt.Errorf("deployment %s is not owned by the team", stable.Name))
+	}
+	selector := map[string]string{k8sclient.LabelRelease: name}
+	if _, err := s.kclient.SetServiceSelector(ctx, k8sclient.ServiceName(r.AppID), namespace, selector); err != nil {
+		s.logger.Error().Err(err).Str("release", r.Uuid).Msg("error pointing app service at release")
+		return nil, releasesServerError()
+	}
+	if r, err = s.finishRelease(ctx, r, releasePromoted); err != nil {
+		return nil, err
+	}
+
+	if stable != nil {
+		err = s.kclient.DeleteDeployment(ctx, r.StableDeployment, namespace)
+		if err != nil && !apierrors.IsNotFound(err) {
+			s.logger.Error().Err(err).Str("deployment", r.StableDeployment).Msg("error removing previous version")
+		}
+	}
+	err = s.kclient.DeleteService(ctx, name, namespace)
+	if err != nil && !apierrors.IsNotFound(err) {
+		s.logger.Error().Err(err).Str("service", name).Msg("error removing release service")
+	}
+	return releaseResult(r), nil
+}
+
+// Send every request back to the running version and remove the new one.
+func (s *releasessrvc) AbortRelease(
+	ctx context.Context,
+	p *releases.AbortReleasePayload,
+) (res *releases.ReleaseResult, err error) {
+	ut := auth.CtxAuthInfo(ctx)
+	if err := authz.Authorize(authz.Role(ut.Role), authz.ResourceApp, authz.ActionUpdate); err != nil {
+		return nil, releasesForbidden(err)
+	}
+	r, err := s.activeRelease(ctx, ut.TeamUUID, p.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if r, err = s.finishRelease(ctx, r, releaseAborted); err != nil {
+		return nil, err
+	}
+	s.removeReleaseResources(ctx, r)
+	return releaseResult(r), nil
+}
+
+// activeRelease returns the active release of an app.
+func (s *releasessrvc) activeRelease(ctx context.Context, teamID, appID string) (store.Releases, error) {
+	r, err := s.db.GetActiveRelease(ctx, store.GetActiveReleaseParams{TeamID: teamID, AppID: appID})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		s.logger.Error().Err(err).Str("app", appID).Msg("error retrieving release")
+		return r, releasesServerError()
+	}
+	if err != nil {
+		return r, &releases.NotFound{
+			Name:    "not found",
+			Message: "release not found",
+			Detail:  fmt.Sprintf("%s has no active release", appID),
+		}
+	}
+	return r, nil
+}
+
+// appService returns the Service of appID owned by teamID.
+func (s *releasessrvc) appService(ctx context.Context, teamID, appID string) (*corev1.Service, error) {
+	svc, err := s.kclient.GetTeamService(ctx, k8sclient.ServiceName(appID), k8sclient.DefaultNamespace, teamID)
+	if err != nil && !apierrors.IsNotFound(err) {
+		s.logger.Error().Err(err).Str("app", appID).Msg("error retrieving service")
+		return nil, releasesServerError()
+	}
+	if err != nil {
+		return nil, releasesBadRequest("no service", fmt.Errorf("%s has no service owned by the team", appID))
+	}
+	return svc, nil
+}
+
+// finishRelease ends r with status and routes the domain's requests for the
+// app to the app's Service alone. r stays active should the routes fail to
+// apply.
+func (s *releasessrvc) finishRelease(ctx context.Context, r store.Releases, status string) (store.Releases, error) {
+	res, err := s.db.SetReleaseStatus(ctx, store.SetReleaseStatusParams{
+		TeamID: r.TeamID,
+		Uuid:   r.Uuid,
+		Status: status,
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Str("release", r.Uuid).Msg("error updating release status")
+		return r, releasesServerError()
+	}
+	if err := s.applyRoutes(ctx, r.TeamID, r.Domain); err != nil {
+		s.logger.Error().Err(err).Str("release", r.Uuid).Msg("error routing domain")
+		_, err := s.db.SetReleaseStatus(ctx, store.SetReleaseStatusParams{
+			TeamID: r.TeamID,
+			Uuid:   r.Uuid,
+			Status: releaseActive,
+		})
+		if err != nil {
+			s.logger.Error().Err(err).Str("release", r.Uuid).Msg("error restoring release status")
+		}
+		return r, releasesServerError()
+	}
+	return res, nil
+}
+
+// applyRoutes applies the stored routes of a domain with its active releases.
+// Domains removed since a release started are skipped.
+func (s *releasessrvc) applyRoutes(ctx context.Context, teamID, domain string) error {
+	d, err := s.db.GetDomain(ctx, store.GetDomainParams{TeamID: teamID, Name: domain})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to retrieve domain: %w", err)
+	}
+	routes, err := storedRoutes(d)
+	if err != nil {
+		return fmt.Errorf("failed to decode domain routes: %w", err)
+	}
+	active, err := s.db.ListActiveDomainReleases(ctx, store.ListActiveDomainReleasesParams{
+		TeamID: teamID,
+		Domain: domain,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list domain releases: %w", err)
+	}
+	return applyDomainRoutes(ctx, s.kclient, d, routes, active)
+}
+
+// createReleaseResources creates the Deployment of r, a copy of stable
+// running the release image, and the Service exposing it on the ports of the
+// app's Service. The stable selector is kept off the release pods so the
+// app's Service only reaches the running version.
+func (s *releasessrvc) createReleaseResources(
+	ctx context.Context,
+	r store.Releases,
+	stable *appsv1.Deployment,
+	stableSelector map[string]string,
+	ports []corev1.ServicePort,
+) error {
+	name := k8sclient.ReleaseResourceName(r.Uuid)
+	namespace := k8sclient.DefaultNamespace
+	_, err := s.kclient.CreateDeployment(ctx, name, namespace,
+		k8sclient.WithDeploymentSpec(stable.Spec),
+		k8sclient.WithDeploymentImage(r.Container, r.Image),
+		k8sclient.WithDeploymentRelease(name, stableSelector),
+		k8sclient.WithDeploymentTeam(r.TeamID),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create deployment: %w", err)
+	}
+	_, err = s.kclient.CreateService(ctx, name, namespace,
+		k8sclient.WithServicePorts(ports),
+		k8sclient.WithServiceSelector(map[string]string{k8sclient.LabelRelease: name}),
+		k8sclient.WithServiceTeam(r.TeamID),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create service: %w", err)
+	}
+	return nil
+}
+
+// removeReleaseResources removes the Deployment and Service of r, logging
+// any failure.
+func (s *releasessrvc) removeReleaseResources(ctx context.Context, r store.Releases) {
+	name := k8sclient.ReleaseResourceName(r.Uuid)
+	namespace := k8sclient.DefaultNamespace
+	if err := s.kclient.DeleteService(ctx, name, namespace); err != nil && !apierrors.IsNotFound(err) {
+		s.logger.Error().Err(err).Str("service", name).Msg("error removing release service")
+	}
+	if err := s.kclient.DeleteDeployment(ctx, name, namespace); err != nil && !apierrors.IsNotFound(err) {
+		s.logger.Error().Err(err).Str("deployment", name).Msg("error removing release deployment")
+	}
+}
+
+// releaseContainer returns the container of d running the release image: the
+// named one, or the first when no name is given.
+func releaseContainer(d *appsv1.Deployment, name *string) (string, error) {
+	containers := d.Spec.Template.Spec.Containers
+	if name == nil {
+		if len(containers) == 0 {
+			return "", releasesBadRequest("no container", fmt.Errorf("%s has no containers", d.Name))
+		}
+		return containers[0].Name, nil
+	}
+	for _, c := range containers {
+		if c.Name == *name {
+			return c.Name, nil
+		}
+	}
+	return "", releasesBadRequest("unknown container", fmt.Errorf("%s has no container %q", d.Name, *name))
+}
+
+// routesApp reports whether a route sends requests to appID.
+func routesApp(routes []domainRoute, appID string) bool {
+	return slices.ContainsFunc(routes, func(r domainRoute) bool {
+		return slices.ContainsFunc(r.Backends, func(b routeBackend) bool { return b.AppID == appID })
+	})
+}
+
+// validWeight rejects weights blue-green releases cannot take: requests go
+// to one version or the other.
+func validWeight(strategy string, weight int32) error {
+	if strategy == strategyBlueGreen && weight != 0 && weight != 100 {
+		return releasesBadRequest("invalid weight",
+			fmt.Errorf("blue-green releases take a weight of 0 or 100, not %d", weight))
+	}
+	return nil
+}
+
+func releaseResult(r store.Releases) *releases.ReleaseResult {
+	return &releases.ReleaseResult{
+		ReleaseID: r.Uuid,
+		AppID:     r.AppID,
+		Domain:    r.Domain,
+		Strategy:  r.Strategy,
+		Image:     r.Image,
+		Container: r.Container,
+		Weight:    r.Weight,
+		Status:    r.Status,
+		CreatedAt: ptr.Ptr(r.CreatedAt.Time.String()),
+	}
+}
+
+func releasesForbidden(err error) *releases.Forbidden {
+	return &releases.Forbidden{
+		Name:    "forbidden",
+		Message: "permission denied",
+		Detail:  err.Error(),
+	}
+}
+
+func releasesBadRequest(message string, err error) *releases.BadRequest {
+	return &releases.BadRequest{
+		Name:    "bad request",
+		Message: message,
+		Detail:  err.Error(),
+	}
+}
+
+func releasesServerError() *releases.ServerError {
+	return &releases.ServerError{
+		Name:    "internal server error",
+		Message: "an unknown error occurred",
+	}
+}
@@ -0,0 +1,304 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/danielmichaels/tawny/design"
+	"github.com/danielmichaels/tawny/gen/identity"
+	"github.com/danielmichaels/tawny/internal/auth"
+	"github.com/danielmichaels/tawny/internal/authz"
+	"github.com/danielmichaels/tawny/internal/ptr"
+	"github.com/danielmichaels/tawny/internal/store"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// List the service accounts of a team.
+func (s *identitysrvc) ListServiceAccounts(
+	ctx context.Context,
+	p *identity.ListServiceAccountsPayload,
+) (res *identity.ServiceAccounts, err error) {
+	if err := s.authorizeServiceAccounts(ctx, p.TeamID, authz.ActionRead); err != nil {
+		return nil, err
+	}
+	pg, err := listPage[identity.BadRequest](p.PageSize, p.PageNumber, p.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	count, err := s.db.CountServiceAccounts(ctx, store.CountServiceAccountsParams{TeamID: p.TeamID, MaxID: maxID(pg)})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error counting service accounts")
+		return nil, &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	pg = pg.Anchored(count.MaxID)
+	rows, err := s.db.ListServiceAccounts(ctx, store.ListServiceAccountsParams{
+		TeamID: p.TeamID,
+		MaxID:  maxID(pg),
+		Lim:    pg.Limit(),
+		Off:    pg.Offset(),
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error listing service accounts")
+		return nil, &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	res = &identity.ServiceAccounts{ServiceAccounts: identity.ServiceAccountResultCollection{}}
+	for _, sa := range rows {
+		res.ServiceAccounts = append(res.ServiceAccounts, serviceAccountResult(sa))
+	}
+	res.Metadata = listMetadata[identity.PaginationMetadata](ctx, pg, count.Count)
+	return res, nil
+}
+
+// Create a service account owned by a team.
+func (s *identitysrvc) CreateServiceAccount(
+	ctx context.Context,
+	p *identity.CreateServiceAccountPayload,
+) (res *identity.ServiceAccountResult, err error) {
+	if err := s.authorizeServiceAccounts(ctx, p.TeamID, authz.ActionCreate); err != nil {
+		return nil, err
+	}
+	ut := auth.CtxAuthInfo(ctx)
+	var description string
+	if p.ServiceAccount.Description != nil {
+		description = *p.ServiceAccount.Description
+	}
+	sa, err := s.db.CreateServiceAccount(ctx, store.CreateServiceAccountParams{
+		TeamID:      p.TeamID,
+		Name:        p.ServiceAccount.Name,
+		Description: description,
+		Role:        store.UserRole(p.ServiceAccount.Role),
+		CreatedBy:   pgtype.Text{String: ut.UserUUID, Valid: ut.UserUUID != ""},
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, &identity.BadRequest{
+				Name:    "bad request",
+				Message: "service account already exists",
+				Detail:  "a service account with this name already exists in the team",
+			}
+		}
+		s.logger.Error().Err(err).Msg("error creating service account")
+		return nil, &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	return serviceAccountResult(sa), nil
+}
+
+// Delete a service account and revoke its API keys.
+func (s *identitysrvc) DeleteServiceAccount(
+	ctx context.Context,
+	p *identity.DeleteServiceAccountPayload,
+) error {
+	if err := s.authorizeServiceAccounts(ctx, p.TeamID, authz.ActionDelete); err != nil {
+		return err
+	}
+	n, err := s.db.DeleteServiceAccount(ctx, store.DeleteServiceAccountParams{
+		TeamID: p.TeamID,
+		Uuid:   p.ServiceAccountID,
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error deleting service account")
+		return &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	if n == 0 {
+		return serviceAccountNotFound()
+	}
+	return nil
+}
+
+// List the API keys of a service account.
+func (s *identitysrvc) ListServiceAccountTokens(
+	ctx context.Context,
+	p *identity.ListServiceAccountTokensPayload,
+) (res []*identity.ServiceAccountToken, err error) {
+	if err := s.authorizeServiceAccounts(ctx, p.TeamID, authz.ActionRead); err != nil {
+		return nil, err
+	}
+	if _, err := s.serviceAccount(ctx, p.TeamID, p.ServiceAccountID); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.ListServiceAccountTokens(ctx, pgtype.Text{String: p.ServiceAccountID, Valid: true})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error listing service account tokens")
+		return nil, &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	res = []*identity.ServiceAccountToken{}
+	for _, t := range rows {
+		scopes, err := auth.ParseAbilities(t.Abilities.String)
+		if err != nil {
+			scopes = []string{}
+		}
+		tok := &identity.ServiceAccountToken{
+			ID:        t.ID,
+			Name:      t.Name,
+			Scopes:    scopes,
+			CreatedAt: t.CreatedAt.Time.String(),
+		}
+		if t.LastUsedAt.Valid {
+			tok.LastUsedAt = ptr.Ptr(t.LastUsedAt.Time.String())
+		}
+		res = append(res, tok)
+	}
+	return res, nil
+}
+
+// Mint an API key for a service account. The key is bound to the account's
+// team and may only hold scopes which both a service account may have and
+// the calling key holds.
+func (s *identitysrvc) CreateServiceAccountToken(
+	ctx context.Context,
+	p *identity.CreateServiceAccountTokenPayload,
+) (res *identity.TokenResult, err error) {
+	if err := s.authorizeServiceAccounts(ctx, p.TeamID, authz.ActionUpdate); err != nil {
+		return nil, err
+	}
+	for _, scope := range p.Scopes {
+		if !slices.Contains(design.ServiceAccountScopes, scope) {
+			return nil, identity.InvalidScopes(fmt.Sprintf("scope %q cannot be granted to service accounts", scope))
+		}
+	}
+	if err := auth.ValidateSubset(p.Scopes, auth.CtxAuthInfo(ctx).Scopes); err != nil {
+		return nil, identity.InvalidScopes(err.Error())
+	}
+	abilities, err := auth.EncodeAbilities(p.Scopes)
+	if err != nil {
+		return nil, &identity.BadRequest{
+			Name:    "bad request",
+			Message: "invalid scopes",
+			Detail:  err.Error(),
+		}
+	}
+	t, err := s.db.CreateServiceAccountToken(ctx, store.CreateServiceAccountTokenParams{
+		TeamID:    p.TeamID,
+		Uuid:      p.ServiceAccountID,
+		Name:      p.Name,
+		Abilities: pgtype.Text{String: abilities, Valid: true},
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, serviceAccountNotFound()
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error creating service account token")
+		return nil, &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	scopes, err := auth.ParseAbilities(t.Abilities.String)
+	if err != nil {
+		scopes = p.Scopes
+	}
+	return &identity.TokenResult{
+		ID:        &t.ID,
+		Name:      t.Name,
+		Key:       t.Token,
+		Scopes:    scopes,
+		TeamID:    &t.TeamID.String,
+		CreatedAt: ptr.Ptr(t.CreatedAt.Time.String()),
+	}, nil
+}
+
+// Revoke an API key of a service account.
+func (s *identitysrvc) RevokeServiceAccountToken(
+	ctx context.Context,
+	p *identity.RevokeServiceAccountTokenPayload,
+) error {
+	if err := s.authorizeServiceAccounts(ctx, p.TeamID, authz.ActionUpdate); err != nil {
+		return err
+	}
+	if _, err := s.serviceAccount(ctx, p.TeamID, p.ServiceAccountID); err != nil {
+		return err
+	}
+	n, err := s.db.DeleteServiceAccountToken(ctx, store.DeleteServiceAccountTokenParams{
+		ServiceAccountID: pgtype.Text{String: p.ServiceAccountID, Valid: true},
+		ID:               p.TokenID,
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error revoking service account token")
+		return &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	if n == 0 {
+		return &identity.NotFound{
+			Name:    "not found",
+			Message: "resource not found",
+			Detail:  "token not found",
+		}
+	}
+	return nil
+}
+
+// authorizeServiceAccounts checks that the caller may perform action on the
+// service accounts of teamID. Teams the caller is not a member of are
+// reported as not found.
+func (s *identitysrvc) authorizeServiceAccounts(ctx context.Context, teamID string, action authz.Action) error {
+	role, err := s.teamRole(ctx, auth.CtxAuthInfo(ctx), teamID)
+	if err != nil {
+		return teamNotFound()
+	}
+	if err := authz.Authorize(role, authz.ResourceServiceAccount, action); err != nil {
+		return identityForbidden(err)
+	}
+	return nil
+}
+
+// serviceAccount loads a service account of teamID.
+func (s *identitysrvc) serviceAccount(ctx context.Context, teamID, id string) (store.ServiceAccounts, error) {
+	sa, err := s.db.GetServiceAccount(ctx, store.GetServiceAccountParams{TeamID: teamID, Uuid: id})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return sa, serviceAccountNotFound()
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Msg("error retrieving service account")
+		return sa, &identity.ServerError{
+			Name:    "internal server error",
+			Message: "an unknown error occurred",
+		}
+	}
+	return sa, nil
+}
+
+func serviceAccountResult(sa store.ServiceAccounts) *identity.ServiceAccountResult {
+	res := &identity.ServiceAccountResult{
+		UUID:        sa.Uuid,
+		TeamID:      sa.TeamID,
+		Name:        sa.Name,
+		Description: sa.Description,
+		Role:        string(sa.Role),
+		CreatedAt:   ptr.Ptr(sa.CreatedAt.Time.String()),
+		UpdatedAt:   ptr.Ptr(sa.UpdatedAt.Time.String()),
+	}
+	if sa.CreatedBy.Valid {
+		res.CreatedBy = &sa.CreatedBy.String
+	}
+	return res
+}
+
+func serviceAccountNotFound() *identity.NotFound {
+	return &identity.NotFound{
+		Name:    "not found",
+		Message: "resource not found",
+		Detail:  "service account not found",
+	}
+}
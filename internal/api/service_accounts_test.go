@@ -0,0 +1,151 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/danielmichaels/tawny/design"
+	"github.com/danielmichaels/tawny/gen/identity"
+	"github.com/danielmichaels/tawny/internal/auth"
+	"github.com/danielmichaels/tawny/internal/logger"
+	"github.com/danielmichaels/tawny/internal/store"
+	"github.com/jackc/pgx/v5/pgtype"
+	"goa.design/goa/v3/security"
+)
+
+const (
+	testTeam           = "team_1"
+	testServiceAccount = "sa_1"
+	testKey            = "key_service"
+)
+
+// serviceAccountDB serves a single service account key holding abilities
+// until it is revoked.
+func serviceAccountDB(abilities string) fakeDB {
+	revoked := false
+	return fakeDB{
+		"RetrieveUserByAPIKEY": func([]any) ([][]any, error) { return nil, nil },
+		"RetrieveServiceAccountByAPIKEY": func(args []any) ([][]any, error) {
+			if revoked || args[0] != testKey {
+				return nil, nil
+			}
+			return [][]any{{
+				testServiceAccount,
+				testTeam,
+				store.UserRoleMaintainer,
+				pgtype.Text{String: abilities, Valid: true},
+				int64(7),
+			}}, nil
+		},
+		"GetServiceAccount": func([]any) ([][]any, error) {
+			return [][]any{{int64(1), testServiceAccount, testTeam, "deployer", "", store.UserRoleMaintainer, nil, nil, nil}}, nil
+		},
+		"DeleteServiceAccountToken": func(args []any) ([][]any, error) {
+			if revoked || args[1] != int64(7) {
+				return nil, nil
+			}
+			revoked = true
+			return [][]any{{}}, nil
+		},
+	}
+}
+
+func newTestIdentity(db fakeDB) *identitysrvc {
+	return &identitysrvc{logger: logger.New("test", false, false), db: store.New(db)}
+}
+
+func TestServiceAccountAPIKeyAuth(t *testing.T) {
+	tests := []struct {
+		name      string
+		abilities string
+		required  []string
+		team      string
+		want      []string
+		wantErr   any
+	}{
+		{
+			name:      "wildcard is clamped to service account scopes",
+			abilities: `["*"]`,
+			want:      design.ServiceAccountScopes,
+		},
+		{
+			name:      "identity scopes stored against the key are dropped",
+			abilities: `["identity:admin","domains:read"]`,
+			want:      []string{design.ScopeDomainsRead},
+		},
+		{
+			name:      "clamped scopes do not satisfy identity methods",
+			abilities: `["*"]`,
+			required:  []string{design.ScopeIdentityAdmin},
+			wantErr:   new(identity.InvalidScopes),
+		},
+		{
+			name:      "own team may be requested",
+			abilities: `["domains:read"]`,
+			team:      testTeam,
+			want:      []string{design.ScopeDomainsRead},
+		},
+		{
+			name:      "another team is rejected",
+			abilities: `["*"]`,
+			team:      "team_2",
+			wantErr:   new(*identity.Forbidden),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestIdentity(serviceAccountDB(tt.abilities))
+			ctx := context.Background()
+			if tt.team != "" {
+				ctx = auth.CtxSetRequestedTeam(ctx, tt.team)
+			}
+			scheme := &security.APIKeyScheme{Name: "api_key", Scopes: design.Scopes, RequiredScopes: tt.required}
+			ctx, err := s.APIKeyAuth(ctx, testKey, scheme)
+			if tt.wantErr != nil {
+				if !errors.As(err, tt.wantErr) {
+					t.Fatalf("APIKeyAuth() error = %v, want %T", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("APIKeyAuth() error = %v", err)
+			}
+			ut := auth.CtxAuthInfo(ctx)
+			if ut.ServiceAccountUUID != testServiceAccount || ut.UserUUID != "" || ut.TeamUUID != testTeam {
+				t.Errorf("auth info = %+v, want service account %s in %s", ut, testServiceAccount, testTeam)
+			}
+			if !slices.Equal(ut.Scopes, tt.want) {
+				t.Errorf("scopes = %v, want %v", ut.Scopes, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevokeServiceAccountToken(t *testing.T) {
+	s := newTestIdentity(serviceAccountDB(`["apps:deploy"]`))
+	scheme := &security.APIKeyScheme{Name: "api_key", Scopes: design.Scopes}
+	if _, err := s.APIKeyAuth(context.Background(), testKey, scheme); err != nil {
+		t.Fatalf("APIKeyAuth() before revocation error = %v", err)
+	}
+
+	admin := auth.CtxSetAuthInfo(context.Background(), auth.CtxInfo{
+		UserUUID: "user_1",
+		TeamUUID: testTeam,
+		Role:     store.UserRoleAdmin,
+	})
+	p := &identity.RevokeServiceAccountTokenPayload{TeamID: testTeam, ServiceAccountID: testServiceAccount, TokenID: 7}
+	if err := s.RevokeServiceAccountToken(admin, p); err != nil {
+		t.Fatalf("RevokeServiceAccountToken() error = %v", err)
+	}
+	var nf *identity.NotFound
+	if err := s.RevokeServiceAccountToken(admin, p); !errors.As(err, &nf) {
+		t.Errorf("second RevokeServiceAccountToken() error = %v, want not found", err)
+	}
+
+	var unauthorized *identity.Unauthorized
+	if _, err := s.APIKeyAuth(context.Background(), testKey, scheme); !errors.As(err, &unauthorized) {
+		t.Errorf("APIKeyAuth() after revocation error = %v, want unauthorized", err)
+	}
+}
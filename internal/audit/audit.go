@@ -0,0 +1,98 @@
+// Package audit records who performed which mutating action, against which
+// resource and with what outcome. Events are captured by middleware around
+// goa endpoints and web handlers so that services do not record them by hand.
+package audit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/danielmichaels/tawny/internal/logger"
+	"github.com/danielmichaels/tawny/internal/store"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Outcome of an audited action.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	// OutcomeDenied is used when authentication or authorization failed.
+	OutcomeDenied = "denied"
+)
+
+// recordTimeout bounds how long writing an event may delay a response.
+const recordTimeout = 5 * time.Second
+
+// Event is a single audited action.
+type Event struct {
+	ActorUserID string
+	// ActorServiceAccountID is set instead of ActorUserID for service
+	// accounts.
+	ActorServiceAccountID string
+	ActorTokenID          int64
+	TeamID                string
+	// Action is "<service>.<method>" for the API or "web.<METHOD> <route>"
+	// for the web UI.
+	Action       string
+	ResourceType string
+	ResourceID   string
+	RequestID    string
+	IP           string
+	Outcome      string
+}
+
+// Recorder persists events. Failures are logged rather than returned as the
+// action being audited has already happened.
+type Recorder struct {
+	DB     *store.Queries
+	Logger *logger.Logger
+}
+
+// Record stores ev. The write is not tied to the request context so that
+// events are kept for requests the client abandoned.
+func (r *Recorder) Record(ctx context.Context, ev Event) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordTimeout)
+	defer cancel()
+	err := r.DB.CreateAuditEvent(ctx, store.CreateAuditEventParams{
+		ActorUserID:  pgtype.Text{String: ev.ActorUserID, Valid: ev.ActorUserID != ""},
+		ActorTokenID: pgtype.Int8{Int64: ev.ActorTokenID, Valid: ev.ActorTokenID != 0},
+		TeamID:       pgtype.Text{String: ev.TeamID, Valid: ev.TeamID != ""},
+		Action:       ev.Action,
+		ResourceType: ev.ResourceType,
+		ResourceID:   ev.ResourceID,
+		RequestID:    ev.RequestID,
+		IpAddress:    pgtype.Text{String: ev.IP, Valid: ev.IP != ""},
+		Outcome:      ev.Outcome,
+		ActorServiceAccountID: pgtype.Text{
+			String: ev.ActorServiceAccountID,
+			Valid:  ev.ActorServiceAccountID != "",
+		},
+	})
+	if err != nil {
+		r.Logger.Error().Err(err).Str("action", ev.Action).Msg("failed to record audit event")
+	}
+}
+
+type ctxKey int
+
+const ctxKeyIP ctxKey = iota
+
+// ClientIP stores the client address on the request context for events
+// recorded further down the chain. Place it after any middleware which
+// rewrites RemoteAddr from trusted proxy headers.
+func ClientIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := r.RemoteAddr
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKeyIP, ip)))
+	})
+}
+
+func ctxIP(ctx context.Context) string {
+	ip, _ := ctx.Value(ctxKeyIP).(string)
+	return ip
+}
@@ -0,0 +1,102 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestMutating(t *testing.T) {
+	tests := map[string]bool{
+		"createTeam":      true,
+		"deleteUser":      true,
+		"switchTeam":      true,
+		"listTeams":       false,
+		"retrieveUser":    false,
+		"getCertificates": false,
+		"":                false,
+	}
+	for method, want := range tests {
+		if got := Mutating(method); got != want {
+			t.Errorf("Mutating(%q) = %v, want %v", method, got, want)
+		}
+	}
+}
+
+func TestResourceType(t *testing.T) {
+	tests := map[string]string{
+		"deleteTeam":             "team",
+		"removeTeamMember":       "team_member",
+		"setTeamTwoFactorPolicy": "team_two_factor_policy",
+		"createToken":            "token",
+		"logout":                 "",
+	}
+	for method, want := range tests {
+		if got := ResourceType(method); got != want {
+			t.Errorf("ResourceType(%q) = %q, want %q", method, got, want)
+		}
+	}
+}
+
+type namedError struct{ name string }
+
+func (e *namedError) Error() string        { return e.name }
+func (e *namedError) GoaErrorName() string { return e.name }
+
+func TestOutcome(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, OutcomeSuccess},
+		{&namedError{"forbidden"}, OutcomeDenied},
+		{fmt.Errorf("wrapped: %w", &namedError{"unauthorized"}), OutcomeDenied},
+		{&namedError{"invalid-scopes"}, OutcomeDenied},
+		{&namedError{"not-found"}, OutcomeFailure},
+		{errors.New("boom"), OutcomeFailure},
+	}
+	for _, tt := range tests {
+		if got := Outcome(tt.err); got != tt.want {
+			t.Errorf("Outcome(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestTarget(t *testing.T) {
+	id := "team_1234567"
+	type payload struct {
+		UserID string
+		TeamID string
+		Key    string
+	}
+	type requestData struct{ Payload *payload }
+	type view struct{ UUID *string }
+	type viewed struct {
+		Projected *view
+		View      string
+	}
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{"user before team", &payload{UserID: "user_1", TeamID: "team_1", Key: "key_1"}, "user_1"},
+		{"team only", &payload{TeamID: "team_1"}, "team_1"},
+		{"request data", &requestData{Payload: &payload{UserID: "user_2"}}, "user_2"},
+		{"viewed result", &viewed{Projected: &view{UUID: &id}}, id},
+		{"numeric id", &struct {
+			TeamID  string
+			TokenID int64
+		}{"team_1", 42}, "42"},
+		{"nil pointer", (*payload)(nil), ""},
+		{"no fields", &struct{ Name string }{"x"}, ""},
+		{"not a struct", "user_1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Target(tt.v); got != tt.want {
+				t.Errorf("Target() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
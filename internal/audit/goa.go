@@ -0,0 +1,139 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/danielmichaels/tawny/internal/auth"
+	"goa.design/goa/v3/middleware"
+	goa "goa.design/goa/v3/pkg"
+)
+
+// readPrefixes identify methods which do not change state and are not
+// audited.
+var readPrefixes = []string{"list", "retrieve", "get"}
+
+// targetFields are payload and result fields which identify the resource
+// acted upon, most specific first.
+var targetFields = []string{"TokenID", "ServiceAccountID", "PortID", "UserID", "TeamID", "AppID", "Domain", "Issuer", "UUID", "UserUUID"}
+
+// deniedErrors are goa error names returned for failed authentication or
+// authorization.
+var deniedErrors = map[string]bool{
+	"unauthorized":   true,
+	"forbidden":      true,
+	"invalid-scopes": true,
+}
+
+// Endpoint is goa endpoint middleware recording every mutating method. It
+// must wrap the endpoint, which authenticates the caller, so the actor is
+// read back through auth.CtxTrackAuthInfo.
+func (r *Recorder) Endpoint(e goa.Endpoint) goa.Endpoint {
+	return func(ctx context.Context, req any) (any, error) {
+		method, _ := ctx.Value(goa.MethodKey).(string)
+		if !Mutating(method) {
+			return e(ctx, req)
+		}
+		ctx, actor := auth.CtxTrackAuthInfo(ctx)
+		res, err := e(ctx, req)
+
+		service, _ := ctx.Value(goa.ServiceKey).(string)
+		requestID, _ := ctx.Value(middleware.RequestIDKey).(string)
+		target := Target(req)
+		if target == "" && err == nil {
+			target = Target(res)
+		}
+		r.Record(ctx, Event{
+			ActorUserID:           actor.UserUUID,
+			ActorServiceAccountID: actor.ServiceAccountUUID,
+			ActorTokenID:          actor.TokenID,
+			TeamID:                actor.TeamUUID,
+			Action:                service + "." + method,
+			ResourceType:          ResourceType(method),
+			ResourceID:            target,
+			RequestID:             requestID,
+			IP:                    ctxIP(ctx),
+			Outcome:               Outcome(err),
+		})
+		return res, err
+	}
+}
+
+// Mutating reports whether method changes state, judged by its name.
+func Mutating(method string) bool {
+	for _, p := range readPrefixes {
+		if strings.HasPrefix(method, p) {
+			return false
+		}
+	}
+	return method != ""
+}
+
+// ResourceType derives the resource from a method name by dropping the
+// leading verb, e.g. "removeTeamMember" is a "team_member".
+func ResourceType(method string) string {
+	i := strings.IndexFunc(method, unicode.IsUpper)
+	if i < 0 {
+		return ""
+	}
+	var b strings.Builder
+	for j, c := range method[i:] {
+		if unicode.IsUpper(c) {
+			if j > 0 {
+				b.WriteByte('_')
+			}
+			c = unicode.ToLower(c)
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// Outcome classifies the error returned by an endpoint.
+func Outcome(err error) string {
+	if err == nil {
+		return OutcomeSuccess
+	}
+	var named interface{ GoaErrorName() string }
+	if errors.As(err, &named) && deniedErrors[named.GoaErrorName()] {
+		return OutcomeDenied
+	}
+	return OutcomeFailure
+}
+
+// Target returns the identifier of the resource in a payload or result. goa
+// request data wrappers and viewed results are unwrapped.
+func Target(v any) string {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return ""
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return ""
+	}
+	for _, wrapper := range []string{"Payload", "Projected"} {
+		if f := rv.FieldByName(wrapper); f.IsValid() {
+			return Target(f.Interface())
+		}
+	}
+	for _, name := range targetFields {
+		f := rv.FieldByName(name)
+		if f.Kind() == reflect.Pointer && !f.IsNil() {
+			f = f.Elem()
+		}
+		if f.Kind() == reflect.String && f.String() != "" {
+			return f.String()
+		}
+		if f.CanInt() && f.Int() != 0 {
+			return strconv.FormatInt(f.Int(), 10)
+		}
+	}
+	return ""
+}
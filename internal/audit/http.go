@@ -0,0 +1,51 @@
+package audit
+
+import (
+	"net/http"
+
+	"github.com/danielmichaels/tawny/internal/auth"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// HTTP records every unsafe request handled by a chi router. It must run
+// after the middleware which authenticates the session.
+func (r *Recorder) HTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			next.ServeHTTP(w, req)
+			return
+		}
+		ctx, actor := auth.CtxTrackAuthInfo(req.Context())
+		*actor = auth.CtxAuthInfo(ctx)
+		ww := middleware.NewWrapResponseWriter(w, req.ProtoMajor)
+		next.ServeHTTP(ww, req.WithContext(ctx))
+
+		route := req.URL.Path
+		if rc := chi.RouteContext(req.Context()); rc != nil && rc.RoutePattern() != "" {
+			route = rc.RoutePattern()
+		}
+		r.Record(ctx, Event{
+			ActorUserID:  actor.UserUUID,
+			ActorTokenID: actor.TokenID,
+			TeamID:       actor.TeamUUID,
+			Action:       "web." + req.Method + " " + route,
+			ResourceType: "web",
+			ResourceID:   route,
+			RequestID:    middleware.GetReqID(ctx),
+			IP:           ctxIP(ctx),
+			Outcome:      statusOutcome(ww.Status()),
+		})
+	})
+}
+
+func statusOutcome(status int) string {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return OutcomeDenied
+	case status >= http.StatusBadRequest:
+		return OutcomeFailure
+	}
+	return OutcomeSuccess
+}
@@ -2,13 +2,23 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 
+	"github.com/danielmichaels/tawny/design"
 	"github.com/danielmichaels/tawny/internal/store"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 
 	"goa.design/goa/v3/security"
 )
 
+// ErrTwoFactorRequired is returned when the team requires two-factor
+// authentication and the user has not enrolled. The auth info is still set on
+// the returned context so that services can allow enrolment.
+var ErrTwoFactorRequired = errors.New("team requires two-factor authentication")
+
 type ApiKey struct{}
 
 func NewApiKey() *ApiKey {
@@ -21,31 +31,127 @@ func (a *ApiKey) Validate(
 	scheme *security.APIKeyScheme,
 	db *store.Queries,
 ) (context.Context, error) {
-	u, err := db.RetrieveUserWithTeamInfoByAPIKEY(ctx, key)
+	u, err := db.RetrieveUserByAPIKEY(ctx, key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return validateServiceAccount(ctx, key, scheme, db)
+	}
 	if err != nil {
 		return ctx, fmt.Errorf("no user matches apikey. err: %w", err)
 	}
+	scopes, err := ParseAbilities(u.Abilities.String)
+	if err != nil {
+		return ctx, err
+	}
+	teamID, err := ResolveTeam(CtxRequestedTeam(ctx), u.TokenTeamID, u.CurrentTeamID)
+	if err != nil {
+		return ctx, err
+	}
+	m, err := db.GetTeamMembership(ctx, store.GetTeamMembershipParams{
+		TeamID: pgtype.Text{String: teamID, Valid: true},
+		UserID: pgtype.Text{String: u.Uuid, Valid: true},
+	})
+	if err != nil {
+		return ctx, fmt.Errorf("%w: user is not a member of team %q", ErrTeamAccess, teamID)
+	}
 	ctx = CtxSetAuthInfo(ctx, CtxInfo{
-		UserUUID: u.Uuid,
-		TeamUUID: u.TeamUuid,
+		UserUUID:      u.Uuid,
+		TokenID:       u.TokenID,
+		TeamUUID:      m.TeamID.String,
+		Role:          m.Role,
+		Scopes:        scopes,
+		Verified:      u.EmailVerified,
+		PlatformAdmin: u.PlatformAdmin,
 	})
+	if err := scheme.Validate(scopes); err != nil {
+		return ctx, fmt.Errorf("%w: %w", ErrInvalidScopes, err)
+	}
+	if m.RequireTwoFactor && !u.TwoFactorEnabled {
+		return ctx, fmt.Errorf("%w: team %q", ErrTwoFactorRequired, teamID)
+	}
+	return ctx, nil
+}
+
+// validateServiceAccount authenticates a key owned by a service account. The
+// account acts within its own team only and holds no more than
+// design.ServiceAccountScopes, whatever is stored against the key.
+func validateServiceAccount(
+	ctx context.Context,
+	key string,
+	scheme *security.APIKeyScheme,
+	db *store.Queries,
+) (context.Context, error) {
+	sa, err := db.RetrieveServiceAccountByAPIKEY(ctx, key)
+	if err != nil {
+		return ctx, fmt.Errorf("no user or service account matches apikey. err: %w", err)
+	}
+	abilities, err := ParseAbilities(sa.Abilities.String)
+	if err != nil {
+		return ctx, err
+	}
+	var scopes []string
+	for _, s := range abilities {
+		if slices.Contains(design.ServiceAccountScopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	if requested := CtxRequestedTeam(ctx); requested != "" && requested != sa.TeamID {
+		return ctx, fmt.Errorf("%w: service account belongs to team %q", ErrTeamAccess, sa.TeamID)
+	}
+	ctx = CtxSetAuthInfo(ctx, CtxInfo{
+		ServiceAccountUUID: sa.Uuid,
+		TokenID:            sa.TokenID,
+		TeamUUID:           sa.TeamID,
+		Role:               sa.Role,
+		Scopes:             scopes,
+	})
+	if err := scheme.Validate(scopes); err != nil {
+		return ctx, fmt.Errorf("%w: %w", ErrInvalidScopes, err)
+	}
 	return ctx, nil
 }
 
 type CtxInfo struct {
+	// UserUUID is empty when a service account authenticated.
 	UserUUID string
+	// ServiceAccountUUID is set instead of UserUUID for service accounts.
+	ServiceAccountUUID string
+	// TokenID is the API key used to authenticate, zero for web sessions.
+	TokenID  int64
 	TeamUUID string
+	// Role of the user within TeamUUID.
+	Role store.UserRole
+	// Scopes held by the API key used to authenticate.
+	Scopes []string
+	// Verified is true once the user has verified their email address.
+	Verified bool
+	// PlatformAdmin is true for users who administer the platform as a
+	// whole rather than a team.
+	PlatformAdmin bool
 }
 type ctxValue int
 
 const (
 	ctxValueClaims ctxValue = iota
+	ctxValueRequestedTeam
+	ctxValueAuthSlot
 )
 
 func CtxSetAuthInfo(ctx context.Context, auth CtxInfo) context.Context {
+	if slot, ok := ctx.Value(ctxValueAuthSlot).(*CtxInfo); ok {
+		*slot = auth
+	}
 	return context.WithValue(ctx, ctxValueClaims, auth)
 }
 
+// CtxTrackAuthInfo returns a context in which auth info set further down the
+// call chain is also written to the returned CtxInfo. It lets middleware
+// wrapping a goa endpoint see who authenticated, as goa authenticates inside
+// the endpoint with a derived context.
+func CtxTrackAuthInfo(ctx context.Context) (context.Context, *CtxInfo) {
+	slot := &CtxInfo{}
+	return context.WithValue(ctx, ctxValueAuthSlot, slot), slot
+}
+
 func CtxAuthInfo(ctx context.Context) (auth CtxInfo) {
 	auth, _ = ctx.Value(ctxValueClaims).(CtxInfo)
 	return
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/danielmichaels/tawny/design"
+)
+
+// ScopeAll is stored against keys which hold every scope.
+const ScopeAll = "*"
+
+var ErrInvalidScopes = errors.New("token scopes are invalid")
+
+// ParseAbilities decodes the abilities column of a personal access token into
+// a list of scopes. The ScopeAll wildcard is expanded to every known scope.
+func ParseAbilities(abilities string) ([]string, error) {
+	if abilities == "" {
+		return []string{}, nil
+	}
+	var scopes []string
+	if err := json.Unmarshal([]byte(abilities), &scopes); err != nil {
+		return nil, fmt.Errorf("malformed abilities %q: %w", abilities, err)
+	}
+	if slices.Contains(scopes, ScopeAll) {
+		return slices.Clone(design.Scopes), nil
+	}
+	return scopes, nil
+}
+
+// EncodeAbilities encodes scopes for storage in the abilities column.
+func EncodeAbilities(scopes []string) (string, error) {
+	b, err := json.Marshal(scopes)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// ValidateSubset ensures every requested scope is known and held by granted.
+// It is used when minting keys so a key can never escalate its own access.
+func ValidateSubset(requested, granted []string) error {
+	for _, s := range requested {
+		if !slices.Contains(design.Scopes, s) {
+			return fmt.Errorf("%w: unknown scope %q", ErrInvalidScopes, s)
+		}
+		if !slices.Contains(granted, s) {
+			return fmt.Errorf("%w: scope %q is not held by this key", ErrInvalidScopes, s)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/danielmichaels/tawny/design"
+)
+
+func TestParseAbilities(t *testing.T) {
+	tests := []struct {
+		name      string
+		abilities string
+		want      []string
+		wantErr   bool
+	}{
+		{"empty abilities hold no scopes", "", []string{}, false},
+		{"empty list holds no scopes", "[]", nil, false},
+		{"listed scopes", `["domains:read","apps:deploy"]`, []string{design.ScopeDomainsRead, design.ScopeAppsDeploy}, false},
+		{"wildcard expands to every scope", `["*"]`, design.Scopes, false},
+		{"unknown scopes are kept so they match nothing", `["root"]`, []string{"root"}, false},
+		{"malformed abilities", `domains:read`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAbilities(tt.abilities)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseAbilities() = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAbilities() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseAbilities() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateSubset(t *testing.T) {
+	granted := []string{design.ScopeDomainsRead, design.ScopeDomainsWrite}
+	tests := []struct {
+		name      string
+		requested []string
+		wantErr   bool
+	}{
+		{"no scopes", nil, false},
+		{"subset", []string{design.ScopeDomainsRead}, false},
+		{"all granted scopes", granted, false},
+		{"superset is rejected", []string{design.ScopeDomainsRead, design.ScopeAppsDeploy}, true},
+		{"unknown scope is rejected", []string{"root"}, true},
+		{"wildcard is not a scope", []string{ScopeAll}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSubset(tt.requested, granted)
+			if tt.wantErr && !errors.Is(err, ErrInvalidScopes) {
+				t.Errorf("ValidateSubset() error = %v, want ErrInvalidScopes", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("ValidateSubset() error = %v", err)
+			}
+		})
+	}
+}
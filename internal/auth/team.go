@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// TeamHeader is the request header used to select which team a request acts
+// within when the user belongs to more than one.
+const TeamHeader = "X-Tawny-Team"
+
+var ErrTeamAccess = errors.New("team access denied")
+
+// TeamContext stores the team requested via TeamHeader on the request
+// context so that it can be validated once the caller is authenticated.
+func TeamContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if team := r.Header.Get(TeamHeader); team != "" {
+			r = r.WithContext(CtxSetRequestedTeam(r.Context(), team))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ResolveTeam picks the team a request acts within. A key bound to a team
+// always uses that team, otherwise an explicitly requested team wins over the
+// user's current team. Membership must still be validated by the caller.
+func ResolveTeam(requested string, tokenTeam, currentTeam pgtype.Text) (string, error) {
+	switch {
+	case tokenTeam.Valid && requested != "" && requested != tokenTeam.String:
+		return "", fmt.Errorf("%w: key is bound to team %q", ErrTeamAccess, tokenTeam.String)
+	case tokenTeam.Valid:
+		return tokenTeam.String, nil
+	case requested != "":
+		return requested, nil
+	case currentTeam.Valid:
+		return currentTeam.String, nil
+	}
+	return "", fmt.Errorf("%w: no team selected", ErrTeamAccess)
+}
+
+func CtxSetRequestedTeam(ctx context.Context, team string) context.Context {
+	return context.WithValue(ctx, ctxValueRequestedTeam, team)
+}
+
+func CtxRequestedTeam(ctx context.Context) string {
+	team, _ := ctx.Value(ctxValueRequestedTeam).(string)
+	return team
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestResolveTeam(t *testing.T) {
+	team := func(id string) pgtype.Text { return pgtype.Text{String: id, Valid: true} }
+	tests := []struct {
+		name      string
+		requested string
+		tokenTeam pgtype.Text
+		current   pgtype.Text
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "bound key uses its team",
+			tokenTeam: team("team_key"),
+			current:   team("team_current"),
+			want:      "team_key",
+		},
+		{
+			name:      "bound key accepts its own team as the header",
+			requested: "team_key",
+			tokenTeam: team("team_key"),
+			current:   team("team_current"),
+			want:      "team_key",
+		},
+		{
+			name:      "bound key rejects a conflicting header",
+			requested: "team_header",
+			tokenTeam: team("team_key"),
+			current:   team("team_current"),
+			wantErr:   true,
+		},
+		{
+			name:      "header wins over the current team without a binding",
+			requested: "team_header",
+			current:   team("team_current"),
+			want:      "team_header",
+		},
+		{
+			name:    "falls back to the current team",
+			current: team("team_current"),
+			want:    "team_current",
+		},
+		{
+			name:    "no team selected",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveTeam(tt.requested, tt.tokenTeam, tt.current)
+			if tt.wantErr {
+				if !errors.Is(err, ErrTeamAccess) {
+					t.Fatalf("ResolveTeam() error = %v, want ErrTeamAccess", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveTeam() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveTeam() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+// Package authz decides what a team member may do. Roles are granted per team
+// and checked against a static permission table, keeping authorization out of
+// SQL so that it is consistent across services and testable without a
+// database.
+package authz
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Role is the role a user holds within a team. Values match the user_role
+// database enum.
+type Role string
+
+const (
+	RoleOwner      Role = "owner"
+	RoleAdmin      Role = "admin"
+	RoleMaintainer Role = "maintainer"
+	RoleViewer     Role = "viewer"
+)
+
+// Resource is something a role can act upon.
+type Resource string
+
+const (
+	ResourceTeam   Resource = "team"
+	ResourceMember Resource = "member"
+	ResourceUser   Resource = "user"
+	ResourceToken  Resource = "token"
+	ResourceDomain Resource = "domain"
+	ResourceApp    Resource = "app"
+	ResourceAudit  Resource = "audit"
+	// ResourceServiceAccount is a team owned machine user and its keys.
+	ResourceServiceAccount Resource = "service_account"
+	// ResourceIssuer is a team owned certificate issuer.
+	ResourceIssuer Resource = "issuer"
+)
+
+// Action is an operation on a Resource.
+type Action string
+
+const (
+	ActionRead   Action = "read"
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+	// ActionTransfer hands ownership of a resource to another user.
+	ActionTransfer Action = "transfer"
+)
+
+var ErrForbidden = errors.New("forbidden")
+
+type permission struct {
+	resource Resource
+	action   Action
+}
+
+var (
+	readOnly = []permission{
+		{ResourceTeam, ActionRead},
+		{ResourceMember, ActionRead},
+		{ResourceUser, ActionRead},
+		{ResourceDomain, ActionRead},
+		{ResourceApp, ActionRead},
+		{ResourceToken, ActionCreate},
+		{ResourceServiceAccount, ActionRead},
+		{ResourceIssuer, ActionRead},
+	}
+	maintain = []permission{
+		{ResourceDomain, ActionCreate},
+		{ResourceDomain, ActionUpdate},
+		{ResourceDomain, ActionDelete},
+		{ResourceApp, ActionCreate},
+		{ResourceApp, ActionUpdate},
+		{ResourceApp, ActionDelete},
+	}
+	administer = []permission{
+		{ResourceTeam, ActionUpdate},
+		{ResourceMember, ActionCreate},
+		{ResourceMember, ActionUpdate},
+		{ResourceMember, ActionDelete},
+		{ResourceUser, ActionUpdate},
+		{ResourceUser, ActionDelete},
+		{ResourceAudit, ActionRead},
+		{ResourceServiceAccount, ActionCreate},
+		{ResourceServiceAccount, ActionUpdate},
+		{ResourceServiceAccount, ActionDelete},
+		{ResourceIssuer, ActionCreate},
+		{ResourceIssuer, ActionUpdate},
+	}
+	own = []permission{
+		{ResourceTeam, ActionDelete},
+		{ResourceTeam, ActionTransfer},
+	}
+	// platform permissions are held by platform admins only. No team role
+	// grants them, as every user owns their personal team.
+	platform = grant([]permission{
+		{ResourceTeam, ActionCreate},
+		{ResourceUser, ActionCreate},
+	})
+)
+
+// policy is the permission matrix. Each role inherits the permissions of the
+// roles below it.
+var policy = map[Role]map[permission]bool{
+	RoleViewer:     grant(readOnly),
+	RoleMaintainer: grant(readOnly, maintain),
+	RoleAdmin:      grant(readOnly, maintain, administer),
+	RoleOwner:      grant(readOnly, maintain, administer, own),
+}
+
+func grant(sets ...[]permission) map[permission]bool {
+	m := make(map[permission]bool)
+	for _, set := range sets {
+		for _, p := range set {
+			m[p] = true
+		}
+	}
+	return m
+}
+
+// Can reports whether role may perform action on resource.
+func Can(role Role, resource Resource, action Action) bool {
+	return policy[role][permission{resource, action}]
+}
+
+// Authorize returns an error wrapping ErrForbidden when role may not perform
+// action on resource.
+func Authorize(role Role, resource Resource, action Action) error {
+	if !Can(role, resource, action) {
+		return fmt.Errorf("%w: role %q cannot %s %s", ErrForbidden, role, action, resource)
+	}
+	return nil
+}
+
+// AuthorizeMember returns an error wrapping ErrForbidden unless role may
+// perform action on a team member holding target. Members are managed by
+// those who outrank them, and owners by owners alone.
+func AuthorizeMember(role, target Role, action Action) error {
+	if err := Authorize(role, ResourceMember, action); err != nil {
+		return err
+	}
+	if target == RoleOwner {
+		if !Can(role, ResourceTeam, ActionTransfer) {
+			return fmt.Errorf("%w: role %q cannot %s an owner", ErrForbidden, role, action)
+		}
+		return nil
+	}
+	if !role.Outranks(target) {
+		return fmt.Errorf("%w: role %q cannot %s a member with role %q", ErrForbidden, role, action, target)
+	}
+	return nil
+}
+
+// AuthorizePlatform returns an error wrapping ErrForbidden unless a platform
+// admin performs action on resource.
+func AuthorizePlatform(platformAdmin bool, resource Resource, action Action) error {
+	if !platformAdmin || !platform[permission{resource, action}] {
+		return fmt.Errorf("%w: only platform admins can %s %s", ErrForbidden, action, resource)
+	}
+	return nil
+}
+
+// rank orders the roles, each holding the permissions of those below it.
+var rank = map[Role]int{
+	RoleViewer:     1,
+	RoleMaintainer: 2,
+	RoleAdmin:      3,
+	RoleOwner:      4,
+}
+
+// Outranks reports whether r is a higher role than other.
+func (r Role) Outranks(other Role) bool {
+	return rank[r] > rank[other]
+}
+
+// Valid reports whether role is a known role.
+func (r Role) Valid() bool {
+	_, ok := policy[r]
+	return ok
+}
@@ -0,0 +1,144 @@
+package authz
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     Role
+		resource Resource
+		action   Action
+		want     bool
+	}{
+		{"owner deletes team", RoleOwner, ResourceTeam, ActionDelete, true},
+		{"admin cannot delete team", RoleAdmin, ResourceTeam, ActionDelete, false},
+		{"owner transfers team", RoleOwner, ResourceTeam, ActionTransfer, true},
+		{"admin cannot transfer team", RoleAdmin, ResourceTeam, ActionTransfer, false},
+		{"admin reads audit log", RoleAdmin, ResourceAudit, ActionRead, true},
+		{"maintainer cannot read audit log", RoleMaintainer, ResourceAudit, ActionRead, false},
+		{"admin creates service account", RoleAdmin, ResourceServiceAccount, ActionCreate, true},
+		{"maintainer cannot create service account", RoleMaintainer, ResourceServiceAccount, ActionCreate, false},
+		{"viewer lists service accounts", RoleViewer, ResourceServiceAccount, ActionRead, true},
+		{"admin creates issuer", RoleAdmin, ResourceIssuer, ActionCreate, true},
+		{"maintainer cannot update issuer", RoleMaintainer, ResourceIssuer, ActionUpdate, false},
+		{"viewer lists issuers", RoleViewer, ResourceIssuer, ActionRead, true},
+		{"owner cannot create team", RoleOwner, ResourceTeam, ActionCreate, false},
+		{"admin adds member", RoleAdmin, ResourceMember, ActionCreate, true},
+		{"owner cannot create user", RoleOwner, ResourceUser, ActionCreate, false},
+		{"maintainer cannot create team", RoleMaintainer, ResourceTeam, ActionCreate, false},
+		{"maintainer cannot add member", RoleMaintainer, ResourceMember, ActionCreate, false},
+		{"maintainer cannot create user", RoleMaintainer, ResourceUser, ActionCreate, false},
+		{"admin updates user", RoleAdmin, ResourceUser, ActionUpdate, true},
+		{"admin deletes user", RoleAdmin, ResourceUser, ActionDelete, true},
+		{"maintainer cannot update user", RoleMaintainer, ResourceUser, ActionUpdate, false},
+		{"viewer cannot delete user", RoleViewer, ResourceUser, ActionDelete, false},
+		{"maintainer creates domain", RoleMaintainer, ResourceDomain, ActionCreate, true},
+		{"maintainer deletes app", RoleMaintainer, ResourceApp, ActionDelete, true},
+		{"maintainer reads members", RoleMaintainer, ResourceMember, ActionRead, true},
+		{"viewer reads domains", RoleViewer, ResourceDomain, ActionRead, true},
+		{"viewer cannot create domain", RoleViewer, ResourceDomain, ActionCreate, false},
+		{"viewer cannot remove member", RoleViewer, ResourceMember, ActionDelete, false},
+		{"viewer creates own token", RoleViewer, ResourceToken, ActionCreate, true},
+		{"unknown role", Role("guest"), ResourceDomain, ActionRead, false},
+		{"empty role", Role(""), ResourceTeam, ActionRead, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Can(tt.role, tt.resource, tt.action); got != tt.want {
+				t.Errorf("Can(%q, %q, %q) = %v, want %v", tt.role, tt.resource, tt.action, got, tt.want)
+			}
+			err := Authorize(tt.role, tt.resource, tt.action)
+			if tt.want && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if !tt.want && !errors.Is(err, ErrForbidden) {
+				t.Errorf("expected ErrForbidden, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRolesInherit(t *testing.T) {
+	order := []Role{RoleViewer, RoleMaintainer, RoleAdmin, RoleOwner}
+	for i, lower := range order {
+		for p := range policy[lower] {
+			for _, higher := range order[i+1:] {
+				if !Can(higher, p.resource, p.action) {
+					t.Errorf("%q can %s %s but %q cannot", lower, p.action, p.resource, higher)
+				}
+			}
+		}
+	}
+}
+
+func TestRoleValid(t *testing.T) {
+	for _, r := range []Role{RoleOwner, RoleAdmin, RoleMaintainer, RoleViewer} {
+		if !r.Valid() {
+			t.Errorf("expected %q to be valid", r)
+		}
+	}
+	if Role("root").Valid() {
+		t.Error("expected unknown role to be invalid")
+	}
+}
+
+func TestAuthorizePlatform(t *testing.T) {
+	tests := []struct {
+		name          string
+		platformAdmin bool
+		resource      Resource
+		action        Action
+		want          bool
+	}{
+		{"platform admin creates team", true, ResourceTeam, ActionCreate, true},
+		{"platform admin creates user", true, ResourceUser, ActionCreate, true},
+		{"user cannot create team", false, ResourceTeam, ActionCreate, false},
+		{"user cannot create user", false, ResourceUser, ActionCreate, false},
+		{"platform admin gains no team permissions", true, ResourceTeam, ActionDelete, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := AuthorizePlatform(tt.platformAdmin, tt.resource, tt.action)
+			if tt.want && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if !tt.want && !errors.Is(err, ErrForbidden) {
+				t.Errorf("expected ErrForbidden, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAuthorizeMember(t *testing.T) {
+	tests := []struct {
+		name   string
+		role   Role
+		target Role
+		action Action
+		want   bool
+	}{
+		{"owner removes owner", RoleOwner, RoleOwner, ActionDelete, true},
+		{"owner removes admin", RoleOwner, RoleAdmin, ActionDelete, true},
+		{"admin removes maintainer", RoleAdmin, RoleMaintainer, ActionDelete, true},
+		{"admin removes viewer", RoleAdmin, RoleViewer, ActionDelete, true},
+		{"admin cannot remove owner", RoleAdmin, RoleOwner, ActionDelete, false},
+		{"admin cannot remove admin", RoleAdmin, RoleAdmin, ActionDelete, false},
+		{"admin cannot add owner", RoleAdmin, RoleOwner, ActionCreate, false},
+		{"admin adds maintainer", RoleAdmin, RoleMaintainer, ActionCreate, true},
+		{"maintainer cannot remove viewer", RoleMaintainer, RoleViewer, ActionDelete, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := AuthorizeMember(tt.role, tt.target, tt.action)
+			if tt.want && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if !tt.want && !errors.Is(err, ErrForbidden) {
+				t.Errorf("expected ErrForbidden, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// Package certs validates and inspects X.509 certificates served for
+// domains.
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoCertificate = errors.New("no PEM certificate found")
+	ErrExpired       = errors.New("certificate has expired")
+)
+
+// ParseChain returns the certificates of a PEM chain, leaf first.
+func ParseChain(chain []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, chain = pem.Decode(chain)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate: %w", err)
+		}
+		certs = append(certs, c)
+	}
+	if len(certs) == 0 {
+		return nil, ErrNoCertificate
+	}
+	return certs, nil
+}
+
+// ValidatePair checks that a PEM certificate chain and private key belong
+// together, that each certificate of the chain is signed by the next, and
+// that the leaf is current and covers hostname. It returns the leaf.
+func ValidatePair(chain, key []byte, hostname string, now time.Time) (*x509.Certificate, error) {
+	certs, err := ParseChain(chain)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tls.X509KeyPair(chain, key); err != nil {
+		return nil, fmt.Errorf("certificate does not match the private key: %w", err)
+	}
+	for i := 0; i < len(certs)-1; i++ {
+		if err := certs[i].CheckSignatureFrom(certs[i+1]); err != nil {
+			return nil, fmt.Errorf("certificate %d of the chain is not signed by the next: %w", i, err)
+		}
+	}
+	leaf := certs[0]
+	if now.After(leaf.NotAfter) {
+		return nil, ErrExpired
+	}
+	if err := Covers(leaf, hostname); err != nil {
+		return nil, err
+	}
+	return leaf, nil
+}
+
+// Covers reports an error unless leaf is valid for hostname. A wildcard
+// hostname is only covered by the same wildcard name.
+func Covers(leaf *x509.Certificate, hostname string) error {
+	if !strings.HasPrefix(hostname, "*.") {
+		return leaf.VerifyHostname(hostname)
+	}
+	for _, name := range leaf.DNSNames {
+		if strings.EqualFold(name, hostname) {
+			return nil
+		}
+	}
+	return fmt.Errorf("certificate is not valid for %s", hostname)
+}
@@ -0,0 +1,88 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func newCert(t *testing.T, tmpl *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func keyPEM(t *testing.T, key *ecdsa.PrivateKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+func TestValidatePair(t *testing.T) {
+	now := time.Now()
+	ca, caKey, caPEM := newCert(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Example CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+	_, leafKey, leafPEM := newCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		DNSNames:     []string{"app.example.com", "*.example.com"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+	}, ca, caKey)
+	_, otherKey, _ := newCert(t, &x509.Certificate{SerialNumber: big.NewInt(3), NotAfter: now}, nil, nil)
+	chain := append(append([]byte{}, leafPEM...), caPEM...)
+
+	tests := []struct {
+		name     string
+		chain    []byte
+		key      []byte
+		hostname string
+		now      time.Time
+		wantErr  bool
+	}{
+		{"valid", chain, keyPEM(t, leafKey), "app.example.com", now, false},
+		{"covered by wildcard", chain, keyPEM(t, leafKey), "api.example.com", now, false},
+		{"wildcard", chain, keyPEM(t, leafKey), "*.example.com", now, false},
+		{"other host", chain, keyPEM(t, leafKey), "example.org", now, true},
+		{"other key", chain, keyPEM(t, otherKey), "app.example.com", now, true},
+		{"chain out of order", append(append([]byte{}, caPEM...), leafPEM...), keyPEM(t, leafKey), "app.example.com", now, true},
+		{"expired", chain, keyPEM(t, leafKey), "app.example.com", now.Add(2 * time.Hour), true},
+		{"no certificate", []byte("nope"), keyPEM(t, leafKey), "app.example.com", now, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ValidatePair(tt.chain, tt.key, tt.hostname, tt.now); (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePair() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
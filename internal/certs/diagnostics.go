@@ -0,0 +1,262 @@
+package certs
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	cm "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/danielmichaels/tawny/internal/k8sclient"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ACMELister reads the objects cert-manager creates while issuing a
+// Certificate through ACME.
+type ACMELister interface {
+	GetCertificate(ctx context.Context, name, namespace string) (*cm.Certificate, error)
+	ListCertificateRequests(ctx context.Context, namespace, certificate string) ([]cm.CertificateRequest, error)
+	ListOrders(ctx context.Context, namespace string, owner metav1.Object) ([]cmacme.Order, error)
+	ListChallenges(ctx context.Context, namespace string, owner metav1.Object) ([]cmacme.Challenge, error)
+}
+
+// Resolver looks up the addresses of a host, as net.Resolver does.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// Step is the state of one object in the chain from Certificate to
+// Challenge.
+type Step struct {
+	Kind   string
+	Name   string
+	State  string
+	Reason string
+	// LastTransition is when the state last changed, when known.
+	LastTransition time.Time
+}
+
+// Diagnosis describes how far issuance of a domain's certificate got.
+type Diagnosis struct {
+	Domain      string
+	Certificate Step
+	Requests    []RequestDiagnosis
+	DNS         DNSCheck
+}
+
+type RequestDiagnosis struct {
+	Step
+	Orders []OrderDiagnosis
+}
+
+type OrderDiagnosis struct {
+	Step
+	URL        string
+	Challenges []ChallengeDiagnosis
+}
+
+type ChallengeDiagnosis struct {
+	Step
+	Type      string
+	DNSName   string
+	Token     string
+	Presented bool
+}
+
+// DNSCheck reports whether a domain resolves to the cluster's ingress.
+type DNSCheck struct {
+	Expected []string
+	Resolved []string
+	OK       bool
+	Error    string
+}
+
+// Ingress locates the cluster's ingress controller, which domains must
+// resolve to.
+type Ingress struct {
+	Namespace string
+	Service   string
+	// Addresses are used instead of those of the Service when set.
+	Addresses []string
+}
+
+// Lookup returns the addresses of the ingress.
+func (i Ingress) Lookup(
+	ctx context.Context,
+	k interface {
+		GetService(ctx context.Context, name, namespace string) (*v1.Service, error)
+	},
+) ([]string, error) {
+	if len(i.Addresses) > 0 {
+		return i.Addresses, nil
+	}
+	svc, err := k.GetService(ctx, i.Service, i.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	return k8sclient.ServiceAddresses(svc), nil
+}
+
+// CertificateFor returns the name of the Certificate whose Secret a domain is
+// served from: its own, or that of the wildcard domain it shares.
+func CertificateFor(domain, secret string) string {
+	own := k8sclient.DomainResourceName(domain)
+	if wildcard := k8sclient.WildcardDomain(domain); wildcard != "" && secret != k8sclient.CreateCertSecretName(own) {
+		return k8sclient.DomainResourceName(wildcard)
+	}
+	return own
+}
+
+// Diagnose walks from the named Certificate through its CertificateRequests,
+// Orders and Challenges, then checks that domain resolves to one of the
+// ingress addresses. Domains served with an uploaded certificate, named by
+// an empty certificate, are only checked for DNS.
+func Diagnose(
+	ctx context.Context,
+	l ACMELister,
+	r Resolver,
+	namespace, certificate, domain string,
+	ingress []string,
+) (*Diagnosis, error) {
+	d := &Diagnosis{Domain: domain}
+	if certificate == "" {
+		d.Certificate = Step{
+			Kind:   "Certificate",
+			State:  SourceUploaded,
+			Reason: "the certificate was uploaded and is not issued by cert-manager",
+		}
+		d.DNS = CheckDNS(ctx, r, domain, ingress)
+		return d, nil
+	}
+	c, err := l.GetCertificate(ctx, certificate, namespace)
+	if err != nil {
+		return nil, err
+	}
+	d.Certificate = Step{Kind: "Certificate", Name: c.Name}
+	for _, cond := range c.Status.Conditions {
+		if cond.Type == cm.CertificateConditionReady {
+			d.Certificate.State = string(cond.Status)
+			d.Certificate.Reason = conditionReason(cond.Reason, cond.Message)
+			d.Certificate.LastTransition = timeOf(cond.LastTransitionTime)
+		}
+	}
+
+	requests, err := l.ListCertificateRequests(ctx, namespace, c.Name)
+	if err != nil {
+		return nil, err
+	}
+	for i := range requests {
+		req := &requests[i]
+		rd := RequestDiagnosis{Step: Step{Kind: "CertificateRequest", Name: req.Name}}
+		for _, cond := range req.Status.Conditions {
+			if cond.Type == cm.CertificateRequestConditionReady {
+				rd.State = cond.Reason
+				rd.Reason = cond.Message
+				rd.LastTransition = timeOf(cond.LastTransitionTime)
+			}
+		}
+		orders, err := l.ListOrders(ctx, namespace, req)
+		if err != nil {
+			return nil, err
+		}
+		for j := range orders {
+			o := &orders[j]
+			od := OrderDiagnosis{
+				Step: Step{
+					Kind:           "Order",
+					Name:           o.Name,
+					State:          string(o.Status.State),
+					Reason:         o.Status.Reason,
+					LastTransition: timeOf(o.Status.FailureTime),
+				},
+				URL: o.Status.URL,
+			}
+			challenges, err := l.ListChallenges(ctx, namespace, o)
+			if err != nil {
+				return nil, err
+			}
+			for _, ch := range challenges {
+				od.Challenges = append(od.Challenges, ChallengeDiagnosis{
+					Step: Step{
+						Kind:   "Challenge",
+						Name:   ch.Name,
+						State:  string(ch.Status.State),
+						Reason: ch.Status.Reason,
+					},
+					Type:      string(ch.Spec.Type),
+					DNSName:   ch.Spec.DNSName,
+					Token:     ch.Spec.Token,
+					Presented: ch.Status.Presented,
+				})
+			}
+			rd.Orders = append(rd.Orders, od)
+		}
+		d.Requests = append(d.Requests, rd)
+	}
+	d.DNS = CheckDNS(ctx, r, domain, ingress)
+	return d, nil
+}
+
+// CheckDNS resolves domain and reports whether any of its addresses is one of
+// the ingress addresses. Ingress hostnames, as given to some cloud load
+// balancers, are resolved too. Wildcard domains are checked through a label
+// of their own.
+func CheckDNS(ctx context.Context, r Resolver, domain string, ingress []string) DNSCheck {
+	var check DNSCheck
+	if len(ingress) == 0 {
+		check.Error = "the ingress address is unknown"
+		return check
+	}
+	for _, addr := range ingress {
+		if net.ParseIP(addr) != nil {
+			check.Expected = append(check.Expected, addr)
+			continue
+		}
+		ips, err := r.LookupHost(ctx, addr)
+		if err != nil {
+			check.Error = fmt.Sprintf("resolving ingress %s: %s", addr, err)
+			return check
+		}
+		check.Expected = append(check.Expected, ips...)
+	}
+	host := domain
+	if k8sclient.IsWildcardDomain(domain) {
+		host = "tawny-dns-check" + strings.TrimPrefix(domain, "*")
+	}
+	resolved, err := r.LookupHost(ctx, host)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	check.Resolved = resolved
+	for _, ip := range resolved {
+		if slices.Contains(check.Expected, ip) {
+			check.OK = true
+		}
+	}
+	if !check.OK {
+		check.Error = fmt.Sprintf("%s does not resolve to the ingress", host)
+	}
+	return check
+}
+
+func conditionReason(reason, message string) string {
+	if message == "" {
+		return reason
+	}
+	if reason == "" {
+		return message
+	}
+	return reason + ": " + message
+}
+
+func timeOf(t *metav1.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.Time
+}
@@ -0,0 +1,65 @@
+package certs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/danielmichaels/tawny/internal/k8sclient"
+)
+
+type fakeResolver map[string][]string
+
+func (f fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	if addrs, ok := f[host]; ok {
+		return addrs, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func TestCheckDNS(t *testing.T) {
+	r := fakeResolver{
+		"app.example.com":             {"203.0.113.10"},
+		"other.example.com":           {"198.51.100.1"},
+		"tawny-dns-check.example.com": {"203.0.113.10"},
+		"lb.example.net":              {"203.0.113.10"},
+	}
+	tests := []struct {
+		name    string
+		domain  string
+		ingress []string
+		want    bool
+	}{
+		{"resolves to ingress ip", "app.example.com", []string{"203.0.113.10"}, true},
+		{"resolves to ingress hostname", "app.example.com", []string{"lb.example.net"}, true},
+		{"resolves elsewhere", "other.example.com", []string{"203.0.113.10"}, false},
+		{"does not resolve", "missing.example.com", []string{"203.0.113.10"}, false},
+		{"wildcard", "*.example.com", []string{"203.0.113.10"}, true},
+		{"unknown ingress", "app.example.com", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CheckDNS(context.Background(), r, tt.domain, tt.ingress)
+			if got.OK != tt.want || (got.Error == "") != tt.want {
+				t.Errorf("CheckDNS() = %+v, want OK %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCertificateFor(t *testing.T) {
+	app := k8sclient.DomainResourceName("app.example.com")
+	wildcard := k8sclient.DomainResourceName("*.example.com")
+	tests := []struct {
+		domain, secret, want string
+	}{
+		{"app.example.com", k8sclient.CreateCertSecretName(app), app},
+		{"app.example.com", k8sclient.CreateCertSecretName(wildcard), wildcard},
+		{"*.example.com", k8sclient.CreateCertSecretName(wildcard), wildcard},
+	}
+	for _, tt := range tests {
+		if got := CertificateFor(tt.domain, tt.secret); got != tt.want {
+			t.Errorf("CertificateFor(%q, %q) = %q, want %q", tt.domain, tt.secret, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package certs
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	cm "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	"github.com/danielmichaels/tawny/internal/k8sclient"
+	v1 "k8s.io/api/core/v1"
+)
+
+// Sources of a certificate.
+const (
+	SourceCertManager = "cert-manager"
+	SourceUploaded    = "uploaded"
+)
+
+// Certificate describes a certificate served by tawny, as reported by
+// cert-manager and read back from its TLS Secret.
+type Certificate struct {
+	Name       string
+	Namespace  string
+	Team       string
+	Source     string
+	DNSNames   []string
+	Issuer     string
+	SecretName string
+	Ready      bool
+	// CreatedAt is when the Certificate, or the Secret of an uploaded
+	// certificate, was created.
+	CreatedAt time.Time
+	// NotAfter is the expiry of the certificate. It is taken from the Secret
+	// when cert-manager has not reported it.
+	NotAfter time.Time
+	// RenewalTime is when cert-manager next renews the certificate. It is
+	// zero for uploaded certificates, which are not renewed.
+	RenewalTime time.Time
+	// FailedAt is the time the latest issuance failed, zero unless the
+	// latest issuance failed.
+	FailedAt       time.Time
+	FailureMessage string
+	// Details of the leaf certificate held in the Secret.
+	SerialNumber string
+	IssuedBy     string
+}
+
+// RenewalFailed reports whether the latest issuance of c failed.
+func (c Certificate) RenewalFailed() bool {
+	return !c.FailedAt.IsZero()
+}
+
+// Lister lists the Certificates and TLS Secrets of a namespace.
+type Lister interface {
+	ListManagedCertificates(ctx context.Context, namespace string) ([]cm.Certificate, error)
+	ListTLSSecrets(ctx context.Context, namespace string) ([]v1.Secret, error)
+}
+
+// Inventory returns the certificates served from namespace, sorted by name.
+// These are the Certificates managed by tawny along with uploaded TLS
+// Secrets, which no Certificate refers to.
+func Inventory(ctx context.Context, l Lister, namespace string) ([]Certificate, error) {
+	certificates, err := l.ListManagedCertificates(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	secrets, err := l.ListTLSSecrets(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	bySecret := make(map[string]*v1.Secret, len(secrets))
+	for i := range secrets {
+		bySecret[secrets[i].Name] = &secrets[i]
+	}
+
+	var res []Certificate
+	for _, c := range certificates {
+		entry := fromCertificate(&c)
+		if s, ok := bySecret[c.Spec.SecretName]; ok {
+			readSecret(&entry, s)
+			delete(bySecret, c.Spec.SecretName)
+		}
+		res = append(res, entry)
+	}
+	for _, s := range bySecret {
+		if _, managed := s.Labels[k8sclient.LabelTeam]; !managed {
+			continue
+		}
+		entry := Certificate{
+			Name:       s.Name,
+			Namespace:  s.Namespace,
+			Team:       s.Labels[k8sclient.LabelTeam],
+			Source:     SourceUploaded,
+			SecretName: s.Name,
+			CreatedAt:  s.CreationTimestamp.Time,
+		}
+		readSecret(&entry, s)
+		entry.Ready = time.Now().Before(entry.NotAfter)
+		res = append(res, entry)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res, nil
+}
+
+func fromCertificate(c *cm.Certificate) Certificate {
+	entry := Certificate{
+		Name:       c.Name,
+		Namespace:  c.Namespace,
+		Team:       c.Labels[k8sclient.LabelTeam],
+		Source:     SourceCertManager,
+		DNSNames:   c.Spec.DNSNames,
+		Issuer:     c.Spec.IssuerRef.Name,
+		SecretName: c.Spec.SecretName,
+		CreatedAt:  c.CreationTimestamp.Time,
+	}
+	if c.Status.NotAfter != nil {
+		entry.NotAfter = c.Status.NotAfter.Time
+	}
+	if c.Status.RenewalTime != nil {
+		entry.RenewalTime = c.Status.RenewalTime.Time
+	}
+	if c.Status.LastFailureTime != nil {
+		entry.FailedAt = c.Status.LastFailureTime.Time
+	}
+	for _, cond := range c.Status.Conditions {
+		switch {
+		case cond.Type == cm.CertificateConditionReady:
+			entry.Ready = cond.Status == cmmeta.ConditionTrue
+			if entry.RenewalFailed() && entry.FailureMessage == "" {
+				entry.FailureMessage = cond.Message
+			}
+		case cond.Type == cm.CertificateConditionIssuing && cond.Status == cmmeta.ConditionFalse:
+			if entry.RenewalFailed() {
+				entry.FailureMessage = cond.Message
+			}
+		}
+	}
+	return entry
+}
+
+// readSecret fills in the details of the leaf certificate held in s.
+// Secrets which hold no valid certificate are left unread.
+func readSecret(entry *Certificate, s *v1.Secret) {
+	chain, err := ParseChain(s.Data[v1.TLSCertKey])
+	if err != nil {
+		return
+	}
+	leaf := chain[0]
+	entry.SerialNumber = leaf.SerialNumber.String()
+	entry.IssuedBy = leaf.Issuer.CommonName
+	if entry.NotAfter.IsZero() {
+		entry.NotAfter = leaf.NotAfter
+	}
+	if len(entry.DNSNames) == 0 {
+		entry.DNSNames = leaf.DNSNames
+	}
+}
@@ -0,0 +1,104 @@
+package certs
+
+import (
+	"context"
+	"crypto/x509"
+	"math/big"
+	"testing"
+	"time"
+
+	cm "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	"github.com/danielmichaels/tawny/internal/k8sclient"
+	"github.com/danielmichaels/tawny/internal/notify"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeLister struct {
+	certificates []cm.Certificate
+	secrets      []v1.Secret
+}
+
+func (f fakeLister) ListManagedCertificates(context.Context, string) ([]cm.Certificate, error) {
+	return f.certificates, nil
+}
+
+func (f fakeLister) ListTLSSecrets(context.Context, string) ([]v1.Secret, error) {
+	return f.secrets, nil
+}
+
+func tlsSecret(name string, labels map[string]string, chain []byte) v1.Secret {
+	return v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "tawny", Labels: labels},
+		Type:       v1.SecretTypeTLS,
+		Data:       map[string][]byte{v1.TLSCertKey: chain},
+	}
+}
+
+func TestInventory(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	_, _, issued := newCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(10),
+		DNSNames:     []string{"app.example.com"},
+		NotAfter:     now.Add(48 * time.Hour),
+	}, nil, nil)
+	_, _, uploaded := newCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(11),
+		DNSNames:     []string{"shop.example.com"},
+		NotAfter:     now.Add(time.Hour),
+	}, nil, nil)
+	failedAt := metav1.NewTime(now.Add(-time.Minute))
+	l := fakeLister{
+		certificates: []cm.Certificate{{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "app-example-com",
+				Namespace: "tawny",
+				Labels:    map[string]string{k8sclient.LabelTeam: "team_1234567"},
+			},
+			Spec: cm.CertificateSpec{
+				DNSNames:   []string{"app.example.com"},
+				SecretName: "app-example-com-cert-secret",
+				IssuerRef:  cmmeta.ObjectReference{Name: "tawny-letsencrypt-production"},
+			},
+			Status: cm.CertificateStatus{
+				RenewalTime:     &metav1.Time{Time: now.Add(24 * time.Hour)},
+				LastFailureTime: &failedAt,
+				Conditions: []cm.CertificateCondition{
+					{Type: cm.CertificateConditionReady, Status: cmmeta.ConditionTrue},
+					{Type: cm.CertificateConditionIssuing, Status: cmmeta.ConditionFalse, Message: "rate limited"},
+				},
+			},
+		}},
+		secrets: []v1.Secret{
+			tlsSecret("app-example-com-cert-secret", nil, issued),
+			tlsSecret("shop-example-com-cert-secret", map[string]string{k8sclient.LabelTeam: "team_1234567"}, uploaded),
+			tlsSecret("unrelated", nil, uploaded),
+		},
+	}
+	got, err := Inventory(context.Background(), l, "tawny")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Inventory() returned %d certificates, want 2", len(got))
+	}
+	managed, upload := got[0], got[1]
+	if managed.Source != SourceCertManager || !managed.Ready || managed.SerialNumber != "10" {
+		t.Errorf("managed certificate = %+v", managed)
+	}
+	if !managed.NotAfter.Equal(now.Add(48*time.Hour)) || !managed.RenewalFailed() || managed.FailureMessage != "rate limited" {
+		t.Errorf("managed certificate status = %+v", managed)
+	}
+	if upload.Source != SourceUploaded || upload.DNSNames[0] != "shop.example.com" || !upload.Ready {
+		t.Errorf("uploaded certificate = %+v", upload)
+	}
+
+	alerted := map[string]int{}
+	for _, a := range alerts(got, 24*time.Hour, now) {
+		alerted[a.Kind]++
+	}
+	if alerted[notify.KindCertificateExpiring] != 1 || alerted[notify.KindCertificateRenewalFailed] != 1 {
+		t.Errorf("alerts() = %v, want one expiring and one renewal failure", alerted)
+	}
+}
@@ -0,0 +1,211 @@
+package certs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danielmichaels/tawny/internal/logger"
+	"github.com/danielmichaels/tawny/internal/notify"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	labelNames = []string{"namespace", "name", "team", "source"}
+
+	expiryDesc = prometheus.NewDesc(
+		"tawny_certificate_expiry_timestamp_seconds",
+		"Time the certificate expires, in seconds since the epoch",
+		labelNames, nil,
+	)
+	renewalDesc = prometheus.NewDesc(
+		"tawny_certificate_renewal_timestamp_seconds",
+		"Time cert-manager next renews the certificate, in seconds since the epoch",
+		labelNames, nil,
+	)
+	readyDesc = prometheus.NewDesc(
+		"tawny_certificate_ready",
+		"Whether the certificate is ready to be served",
+		labelNames, nil,
+	)
+	failedDesc = prometheus.NewDesc(
+		"tawny_certificate_renewal_failed",
+		"Whether the latest issuance of the certificate failed",
+		labelNames, nil,
+	)
+)
+
+// Monitor keeps the certificate inventory of a namespace up to date,
+// exposes it as Prometheus gauges and notifies when a certificate is about to
+// expire or fails to renew. Each condition is notified once, however many
+// replicas run and across restarts.
+type Monitor struct {
+	Lister    Lister
+	Namespace string
+	Notifier  notify.Notifier
+	Sent      SentNotifications
+	Logger    *logger.Logger
+	// Threshold is how long before expiry a certificate is notified.
+	Threshold time.Duration
+	// Interval between refreshes of the inventory.
+	Interval time.Duration
+
+	mu    sync.RWMutex
+	certs []Certificate
+}
+
+// SentNotifications records the notifications which were sent. It is
+// implemented by *store.Queries.
+type SentNotifications interface {
+	// ClaimCertificateNotification records key as sent, reporting no rows
+	// affected when it already was.
+	ClaimCertificateNotification(ctx context.Context, key string) (int64, error)
+	// DeleteClearedCertificateNotifications forgets every key but keys.
+	DeleteClearedCertificateNotifications(ctx context.Context, keys []string) error
+}
+
+// Run refreshes the inventory every Interval until ctx is done.
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop()
+	for {
+		if err := m.Refresh(ctx); err != nil {
+			m.Logger.Error().Err(err).Msg("error refreshing certificate inventory")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh reads the inventory and sends notifications for certificates which
+// newly expire within the threshold or failed to renew.
+func (m *Monitor) Refresh(ctx context.Context) error {
+	certs, err := Inventory(ctx, m.Lister, m.Namespace)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.certs = certs
+	m.mu.Unlock()
+
+	due := alerts(certs, m.Threshold, time.Now())
+	keys := make([]string, 0, len(due))
+	for _, a := range due {
+		keys = append(keys, a.key)
+		// Claiming the key first keeps replicas from sending it too.
+		claimed, err := m.Sent.ClaimCertificateNotification(ctx, a.key)
+		if err != nil {
+			return fmt.Errorf("failed to record notification: %w", err)
+		}
+		if claimed == 0 {
+			continue
+		}
+		if err := m.Notifier.Notify(ctx, a.Notification); err != nil {
+			m.Logger.Error().Err(err).Str("kind", a.Kind).Msg("error sending notification")
+		}
+	}
+	// Conditions which cleared may be notified again should they recur.
+	if err := m.Sent.DeleteClearedCertificateNotifications(ctx, keys); err != nil {
+		return fmt.Errorf("failed to clear notifications: %w", err)
+	}
+	return nil
+}
+
+// Certificates returns the inventory as of the latest refresh.
+func (m *Monitor) Certificates() []Certificate {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.certs
+}
+
+// alert is a notification along with the occurrence of the condition it
+// reports, which identifies it across refreshes.
+type alert struct {
+	notify.Notification
+	key string
+}
+
+// alerts returns the notifications due for certs at now.
+func alerts(certs []Certificate, threshold time.Duration, now time.Time) []alert {
+	var res []alert
+	for _, c := range certs {
+		id := c.Namespace + "/" + c.Name
+		details := map[string]string{
+			"certificate": id,
+			"domains":     strings.Join(c.DNSNames, ", "),
+			"source":      c.Source,
+		}
+		if !c.NotAfter.IsZero() {
+			details["expires"] = c.NotAfter.UTC().Format(time.RFC3339)
+		}
+		if !c.NotAfter.IsZero() && c.NotAfter.Sub(now) < threshold {
+			summary := fmt.Sprintf("certificate %s expires in %s", c.Name, c.NotAfter.Sub(now).Round(time.Hour))
+			if !now.Before(c.NotAfter) {
+				summary = fmt.Sprintf("certificate %s has expired", c.Name)
+			}
+			res = append(res, alert{
+				Notification: notify.Notification{
+					Kind:    notify.KindCertificateExpiring,
+					Summary: summary,
+					Team:    c.Team,
+					Details: details,
+					Time:    now,
+				},
+				key: notify.KindCertificateExpiring + "/" + id + "/" + c.NotAfter.String(),
+			})
+		}
+		if c.RenewalFailed() {
+			failed := map[string]string{"error": c.FailureMessage}
+			for k, v := range details {
+				failed[k] = v
+			}
+			res = append(res, alert{
+				Notification: notify.Notification{
+					Kind:    notify.KindCertificateRenewalFailed,
+					Summary: fmt.Sprintf("certificate %s failed to renew", c.Name),
+					Team:    c.Team,
+					Details: failed,
+					Time:    now,
+				},
+				key: notify.KindCertificateRenewalFailed + "/" + id + "/" + c.FailedAt.String(),
+			})
+		}
+	}
+	return res
+}
+
+// Describe implements prometheus.Collector.
+func (m *Monitor) Describe(ch chan<- *prometheus.Desc) {
+	ch <- expiryDesc
+	ch <- renewalDesc
+	ch <- readyDesc
+	ch <- failedDesc
+}
+
+// Collect implements prometheus.Collector, reporting the inventory as of the
+// latest refresh.
+func (m *Monitor) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range m.Certificates() {
+		labels := []string{c.Namespace, c.Name, c.Team, c.Source}
+		if !c.NotAfter.IsZero() {
+			ch <- prometheus.MustNewConstMetric(expiryDesc, prometheus.GaugeValue, float64(c.NotAfter.Unix()), labels...)
+		}
+		if !c.RenewalTime.IsZero() {
+			ch <- prometheus.MustNewConstMetric(renewalDesc, prometheus.GaugeValue, float64(c.RenewalTime.Unix()), labels...)
+		}
+		ch <- prometheus.MustNewConstMetric(readyDesc, prometheus.GaugeValue, boolValue(c.Ready), labels...)
+		ch <- prometheus.MustNewConstMetric(failedDesc, prometheus.GaugeValue, boolValue(c.RenewalFailed()), labels...)
+	}
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,92 @@
+package certs
+
+import (
+	"context"
+	"crypto/x509"
+	"math/big"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/danielmichaels/tawny/internal/k8sclient"
+	"github.com/danielmichaels/tawny/internal/logger"
+	"github.com/danielmichaels/tawny/internal/notify"
+	v1 "k8s.io/api/core/v1"
+)
+
+// fakeSent records sent notifications as the certificate_notifications
+// table does.
+type fakeSent map[string]bool
+
+func (f fakeSent) ClaimCertificateNotification(_ context.Context, key string) (int64, error) {
+	if f[key] {
+		return 0, nil
+	}
+	f[key] = true
+	return 1, nil
+}
+
+func (f fakeSent) DeleteClearedCertificateNotifications(_ context.Context, keys []string) error {
+	for k := range f {
+		if !slices.Contains(keys, k) {
+			delete(f, k)
+		}
+	}
+	return nil
+}
+
+type fakeNotifier struct {
+	sent []notify.Notification
+}
+
+func (f *fakeNotifier) Notify(_ context.Context, n notify.Notification) error {
+	f.sent = append(f.sent, n)
+	return nil
+}
+
+func TestMonitorNotifiesOnce(t *testing.T) {
+	_, _, expiring := newCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(12),
+		DNSNames:     []string{"shop.example.com"},
+		NotAfter:     time.Now().Add(time.Hour),
+	}, nil, nil)
+	l := &fakeLister{secrets: []v1.Secret{
+		tlsSecret("shop-example-com-cert-secret", map[string]string{k8sclient.LabelTeam: "team_1"}, expiring),
+	}}
+	sent := fakeSent{}
+	notifier := &fakeNotifier{}
+	// newMonitor starts a replica, or the same one after a restart, sharing
+	// the record of sent notifications.
+	newMonitor := func() *Monitor {
+		return &Monitor{
+			Lister:    l,
+			Namespace: "tawny",
+			Notifier:  notifier,
+			Sent:      sent,
+			Logger:    logger.New("test", false, false),
+			Threshold: 24 * time.Hour,
+		}
+	}
+	refresh := func(m *Monitor, want int) {
+		t.Helper()
+		if err := m.Refresh(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if len(notifier.sent) != want {
+			t.Fatalf("sent %d notifications, want %d", len(notifier.sent), want)
+		}
+	}
+
+	first := newMonitor()
+	refresh(first, 1)
+	refresh(first, 1)
+	refresh(newMonitor(), 1)
+
+	// Once the condition clears it is notified again should it recur.
+	l.secrets = nil
+	refresh(first, 1)
+	l.secrets = []v1.Secret{
+		tlsSecret("shop-example-com-cert-secret", map[string]string{k8sclient.LabelTeam: "team_1"}, expiring),
+	}
+	refresh(newMonitor(), 2)
+}
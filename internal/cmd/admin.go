@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/danielmichaels/tawny/internal/config"
+	"github.com/danielmichaels/tawny/internal/k8sclient"
+	svclogger "github.com/danielmichaels/tawny/internal/logger"
+	"github.com/danielmichaels/tawny/internal/store"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/spf13/cobra"
+)
+
+// adminPasswordKey is the key within the admin password secret created by
+// `task k3s:up`.
+const adminPasswordKey = "admin-password"
+
+// adminAPIKeyKey is the key within the admin API key secret written when
+// serve bootstraps the admin.
+const adminAPIKeyKey = "api-key"
+
+func AdminCmd(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "admin",
+		Short: "Administrative tasks",
+	}
+	cmd.AddCommand(adminBootstrapCmd(ctx))
+	return cmd
+}
+
+func adminBootstrapCmd(ctx context.Context) *cobra.Command {
+	var isConsole bool
+	var email string
+	var password string
+	cmd := &cobra.Command{
+		Use:   "bootstrap",
+		Args:  cobra.ExactArgs(0),
+		Short: "Create the admin user and team on first boot",
+		Long: "Create the admin user and team and print its initial API key. " +
+			"The password is read from --password, ADMIN_PASSWORD or the admin-password " +
+			"kubernetes secret, in that order. Running it again once the admin exists does nothing.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.AppConfig()
+			logger := svclogger.New("tawny", false, isConsole)
+			if email != "" {
+				cfg.Admin.Email = email
+			}
+			if password != "" {
+				cfg.Admin.Password = password
+			}
+
+			db, err := store.NewDatabasePool(ctx, cfg)
+			if err != nil {
+				return fmt.Errorf("failed to connect to database: %w", err)
+			}
+			defer db.Close()
+			dbx := store.New(db)
+
+			res, err := bootstrapAdmin(ctx, dbx, cfg, logger)
+			if err != nil {
+				return err
+			}
+			if res == nil {
+				fmt.Fprintln(cmd.OutOrStdout(), "admin user already exists, nothing to do")
+				return nil
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "admin user: %s\n", res.UserID)
+			fmt.Fprintf(cmd.OutOrStdout(), "admin team: %s\n", res.TeamID)
+			fmt.Fprintf(cmd.OutOrStdout(), "api key:    %s\n", res.PersonalAccessToken)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&isConsole, "console", false, "Use zerolog ConsoleWriter")
+	cmd.Flags().StringVar(&email, "email", "", "Admin email address (default ADMIN_EMAIL)")
+	cmd.Flags().StringVar(&password, "password", "", "Admin password (default ADMIN_PASSWORD)")
+	return cmd
+}
+
+// bootstrapAdmin creates the admin user, team and API key if they do not exist.
+// A nil result without an error means the admin already exists.
+func bootstrapAdmin(
+	ctx context.Context,
+	dbx *store.Queries,
+	cfg *config.Conf,
+	logger *svclogger.Logger,
+) (*store.BootstrapAdminRow, error) {
+	exists, err := dbx.DoesAdminExist(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for admin user: %w", err)
+	}
+	if exists {
+		return nil, nil
+	}
+	password, err := adminPassword(ctx, cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := store.HashPassword(password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash admin password: %w", err)
+	}
+	res, err := dbx.BootstrapAdmin(ctx, store.BootstrapAdminParams{
+		Email:    pgtype.Text{String: cfg.Admin.Email, Valid: true},
+		Password: pgtype.Text{String: hash, Valid: true},
+	})
+	if err != nil {
+		// Another replica bootstrapped concurrently
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to create admin user: %w", err)
+	}
+	return &res, nil
+}
+
+// storeAdminAPIKey writes the API key of a bootstrapped admin to the admin API
+// key secret, so that it never appears in the logs of serve.
+func storeAdminAPIKey(
+	ctx context.Context,
+	kclient *k8sclient.K8sClient,
+	cfg *config.Conf,
+	res *store.BootstrapAdminRow,
+) error {
+	_, err := kclient.ApplySecret(ctx, cfg.Admin.APIKeySecret, k8sclient.DefaultNamespace,
+		k8sclient.WithSecretData(map[string]string{
+			adminAPIKeyKey: res.PersonalAccessToken,
+			"user":         res.UserID,
+			"team":         res.TeamID,
+		}),
+	)
+	return err
+}
+
+// adminPassword returns the configured admin password, falling back to the
+// admin password secret when running with access to a cluster.
+func adminPassword(
+	ctx context.Context,
+	cfg *config.Conf,
+	logger *svclogger.Logger,
+) (string, error) {
+	if cfg.Admin.Password != "" {
+		return cfg.Admin.Password, nil
+	}
+	missing := errors.New(
+		"no admin password provided. set --password, ADMIN_PASSWORD or create the admin-password secret",
+	)
+	if _, err := k8sclient.GetKubeConfig(); err != nil {
+		return "", missing
+	}
+	kclient := k8sclient.NewK8sClient(false, false)
+	secret, err := kclient.GetSecret(ctx, cfg.Admin.PasswordSecret, k8sclient.DefaultNamespace)
+	if err != nil {
+		logger.Warn().Err(err).Str("secret", cfg.Admin.PasswordSecret).Msg("admin password secret unavailable")
+		return "", missing
+	}
+	password, ok := secret.Data[adminPasswordKey]
+	if !ok || len(password) == 0 {
+		return "", missing
+	}
+	return string(password), nil
+}
@@ -14,6 +14,7 @@ func Execute(ctx context.Context) int {
 	}
 
 	rootCmd.AddCommand(ServeCmd(ctx))
+	rootCmd.AddCommand(AdminCmd(ctx))
 
 	if err := rootCmd.Execute(); err != nil {
 		return 1
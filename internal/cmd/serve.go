@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -13,21 +14,43 @@ import (
 	"time"
 
 	"github.com/danielmichaels/tawny/gen/domains"
+	"github.com/danielmichaels/tawny/internal/account"
+	"github.com/danielmichaels/tawny/internal/audit"
+	"github.com/danielmichaels/tawny/internal/auth"
+	"github.com/danielmichaels/tawny/internal/certs"
+	"github.com/danielmichaels/tawny/internal/crypt"
 	"github.com/danielmichaels/tawny/internal/k8sclient"
 
+	"github.com/danielmichaels/tawny/gen/certificates"
 	"github.com/danielmichaels/tawny/gen/identity"
+	"github.com/danielmichaels/tawny/gen/issuers"
+	"github.com/danielmichaels/tawny/gen/ports"
+	"github.com/danielmichaels/tawny/gen/releases"
 	"github.com/danielmichaels/tawny/internal/config"
+	"github.com/danielmichaels/tawny/internal/mailer"
+	"github.com/danielmichaels/tawny/internal/notify"
+	"github.com/danielmichaels/tawny/internal/pagination"
+	"github.com/danielmichaels/tawny/internal/ratelimit"
+	"github.com/danielmichaels/tawny/internal/realip"
+	"github.com/danielmichaels/tawny/internal/sso"
+	"github.com/danielmichaels/tawny/internal/storage"
 	"github.com/danielmichaels/tawny/internal/store"
 	"github.com/danielmichaels/tawny/internal/webserver"
 
+	certificatesvr "github.com/danielmichaels/tawny/gen/http/certificates/server"
 	domainsvr "github.com/danielmichaels/tawny/gen/http/domains/server"
 	identitysvr "github.com/danielmichaels/tawny/gen/http/identity/server"
+	issuersvr "github.com/danielmichaels/tawny/gen/http/issuers/server"
 	monitoringsvr "github.com/danielmichaels/tawny/gen/http/monitoring/server"
 	openapisvr "github.com/danielmichaels/tawny/gen/http/openapi/server"
+	portsvr "github.com/danielmichaels/tawny/gen/http/ports/server"
+	releasesvr "github.com/danielmichaels/tawny/gen/http/releases/server"
 	"github.com/danielmichaels/tawny/gen/monitoring"
 	"github.com/danielmichaels/tawny/gen/openapi"
 	tawny "github.com/danielmichaels/tawny/internal/api"
 	svclogger "github.com/danielmichaels/tawny/internal/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/cobra"
 	goahttp "goa.design/goa/v3/http"
 	httpmdlwr "goa.design/goa/v3/http/middleware"
@@ -39,6 +62,7 @@ func ServeCmd(ctx context.Context) *cobra.Command {
 	var debugF bool
 	var apiServerOnly bool
 	var webServerOnly bool
+	var bootstrap bool
 	cmd := &cobra.Command{
 		Use:   "serve",
 		Args:  cobra.ExactArgs(0),
@@ -67,10 +91,131 @@ func ServeCmd(ctx context.Context) *cobra.Command {
 			}
 			dbx := store.New(db)
 			// Initialise admin user on first boot; this can be updated after the fact
-			// todo: find a better way to do this
+			if bootstrap {
+				res, err := bootstrapAdmin(ctx, dbx, cfg, logger)
+				switch {
+				case err != nil:
+					logger.Error().Err(err).Msg("failed to bootstrap admin user")
+				case res != nil:
+					if err := storeAdminAPIKey(ctx, kclient, cfg, res); err != nil {
+						logger.Error().Err(err).
+							Str("user", res.UserID).
+							Msg("admin user bootstrapped but its api key could not be stored. " +
+								"sign in with the admin password to create a new key")
+						break
+					}
+					logger.Warn().
+						Str("user", res.UserID).
+						Str("team", res.TeamID).
+						Str("secret", cfg.Admin.APIKeySecret).
+						Msg("admin user bootstrapped. its api key is in the secret; copy it somewhere safe and delete it")
+				}
+			} else if exists, err := dbx.DoesAdminExist(ctx); !exists || err != nil {
+				logger.Warn().Msg("admin user does not exist. run 'tawny admin bootstrap' or serve with --bootstrap")
+			}
+
+			mail, err := mailer.New(mailer.Config{
+				Driver:   cfg.Mail.Driver,
+				From:     cfg.Mail.From,
+				Host:     cfg.Mail.Host,
+				Port:     cfg.Mail.Port,
+				Username: cfg.Mail.Username,
+				Password: cfg.Mail.Password,
+			}, logger)
+			if err != nil {
+				logger.Fatal().Err(err).Msg("failed to configure mailer")
+			}
+			cipher, err := crypt.New(cfg.EncryptionKey)
+			switch {
+			case errors.Is(err, crypt.ErrNoKey):
+				logger.Warn().Msg("ENCRYPTION_KEY is not set. two-factor authentication is unavailable")
+			case err != nil:
+				logger.Fatal().Err(err).Msg("invalid ENCRYPTION_KEY")
+			}
+			files, err := storage.New(storage.Config{
+				Driver:            cfg.Storage.Driver,
+				PublicURL:         cfg.Storage.PublicURL,
+				DiskPath:          cfg.Storage.DiskPath,
+				S3Bucket:          cfg.Storage.S3Bucket,
+				S3Region:          cfg.Storage.S3Region,
+				S3Endpoint:        cfg.Storage.S3Endpoint,
+				S3AccessKeyID:     cfg.Storage.S3AccessKeyID,
+				S3SecretAccessKey: cfg.Storage.S3SecretAccessKey,
+				S3ForcePathStyle:  cfg.Storage.S3ForcePathStyle,
+			})
+			if err != nil {
+				logger.Fatal().Err(err).Msg("failed to configure file storage")
+			}
+			accounts := &account.Service{
+				DB:                   dbx,
+				Mailer:               mail,
+				Logger:               logger,
+				Cipher:               cipher,
+				Issuer:               "Tawny",
+				Files:                files,
+				Teams:                kclient,
+				BaseURL:              cfg.Server.WebURL,
+				VerificationLifetime: cfg.Mail.VerificationLifetime,
+				ResetLifetime:        cfg.Mail.ResetLifetime,
+			}
+
+			if cfg.ACME.Email == "" {
+				logger.Warn().Msg("ACME_EMAIL is not set. shared Let's Encrypt issuers are not managed")
+			} else if err := kclient.EnsureDefaultClusterIssuers(ctx, cfg.ACME.Email); err != nil {
+				logger.Error().Err(err).Msg("failed to ensure shared Let's Encrypt issuers")
+			}
+			if cfg.CA.Enabled {
+				if err := kclient.EnsureInternalCA(ctx, cfg.ACME.Namespace, cfg.CA.CommonName, cfg.CA.Duration); err != nil {
+					logger.Error().Err(err).Msg("failed to ensure internal CA")
+				}
+			}
+
+			recorder := &audit.Recorder{DB: dbx, Logger: logger}
+
+			monitor := &certs.Monitor{
+				Lister:    kclient,
+				Namespace: k8sclient.DefaultNamespace,
+				Notifier: notify.New(notify.Config{
+					WebhookURL: cfg.Certs.NotifyWebhookURL,
+					Email:      cfg.Certs.NotifyEmail,
+				}, mail, logger),
+				Sent:      dbx,
+				Logger:    logger,
+				Threshold: cfg.Certs.ExpiryThreshold,
+				Interval:  cfg.Certs.CheckInterval,
+			}
+			metrics := prometheus.NewRegistry()
+			metrics.MustRegister(monitor)
+			entryPoints, err := k8sclient.ParseEntryPoints(cfg.Ingress.EntryPoints)
+			if err != nil {
+				logger.Fatal().Err(err).Msg("invalid INGRESS_ENTRYPOINTS")
+			}
+			ingress := certs.Ingress{
+				Namespace: cfg.Ingress.ServiceNamespace,
+				Service:   cfg.Ingress.ServiceName,
+				Addresses: cfg.Ingress.Addresses,
+			}
 
-			if exists, err := dbx.DoesAdminExist(ctx); !exists || err != nil {
-				logger.Warn().Msg("admin user does not exist")
+			proxies, err := realip.New(cfg.Server.TrustedProxies)
+			if err != nil {
+				logger.Fatal().Err(err).Msg("invalid TRUSTED_PROXIES")
+			}
+
+			var limiter *ratelimit.Limiter
+			if cfg.RateLimit.Enabled {
+				limiter, err = ratelimit.New(cfg.RateLimit.Store, dbx, logger)
+				if err != nil {
+					logger.Fatal().Err(err).Msg("failed to configure rate limiting")
+				}
+				limiter.CredentialFunc = ratelimit.Header("X-API-KEY")
+				limiter.IP = ratelimit.Limit{Rate: cfg.RateLimit.IPRate, Burst: cfg.RateLimit.IPBurst}
+				limiter.Credential = ratelimit.Limit{Rate: cfg.RateLimit.KeyRate, Burst: cfg.RateLimit.KeyBurst}
+				limiter.Lockout = ratelimit.Lockout{
+					Threshold: cfg.RateLimit.AuthFailureLimit,
+					Window:    cfg.RateLimit.AuthFailureWindow,
+					Base:      cfg.RateLimit.AuthLockout,
+					Max:       cfg.RateLimit.AuthLockoutMax,
+				}
 			}
 
 			// Initialize the services.
@@ -79,12 +224,20 @@ func ServeCmd(ctx context.Context) *cobra.Command {
 				openapiSvc    openapi.Service
 				identitySvc   identity.Service
 				domainsSvc    domains.Service
+				issuersSvc    issuers.Service
+				certsSvc      certificates.Service
+				portsSvc      ports.Service
+				releasesSvc   releases.Service
 			)
 			{
 				monitoringSvc = tawny.NewMonitoring(logger)
 				openapiSvc = tawny.NewOpenapi(logger)
-				identitySvc = tawny.NewIdentity(logger, dbx)
+				identitySvc = tawny.NewIdentity(logger, dbx, accounts, kclient)
 				domainsSvc = tawny.NewDomains(logger, dbx, kclient)
+				issuersSvc = tawny.NewIssuers(logger, dbx, kclient, cfg.ACME.Namespace)
+				certsSvc = tawny.NewCertificates(logger, dbx, kclient, ingress, cfg.ACME.Namespace)
+				portsSvc = tawny.NewPorts(logger, dbx, kclient, entryPoints)
+				releasesSvc = tawny.NewReleases(logger, dbx, kclient)
 			}
 
 			// Wrap the services in endpoints that can be invoked from other services
@@ -94,12 +247,20 @@ func ServeCmd(ctx context.Context) *cobra.Command {
 				openapiEndpoints    *openapi.Endpoints
 				identityEndpoints   *identity.Endpoints
 				domainEndpoints     *domains.Endpoints
+				issuerEndpoints     *issuers.Endpoints
+				certEndpoints       *certificates.Endpoints
+				portEndpoints       *ports.Endpoints
+				releaseEndpoints    *releases.Endpoints
 			)
 			{
 				monitoringEndpoints = monitoring.NewEndpoints(monitoringSvc)
 				openapiEndpoints = openapi.NewEndpoints(openapiSvc)
 				identityEndpoints = identity.NewEndpoints(identitySvc)
 				domainEndpoints = domains.NewEndpoints(domainsSvc)
+				issuerEndpoints = issuers.NewEndpoints(issuersSvc)
+				certEndpoints = certificates.NewEndpoints(certsSvc)
+				portEndpoints = ports.NewEndpoints(portsSvc)
+				releaseEndpoints = releases.NewEndpoints(releasesSvc)
 			}
 
 			// Create channel used by both the signal handler and server goroutines
@@ -116,6 +277,9 @@ func ServeCmd(ctx context.Context) *cobra.Command {
 
 			var wg sync.WaitGroup
 			ctx, cancel := context.WithCancel(ctx)
+			if limiter != nil {
+				go limiter.Run(ctx)
+			}
 
 			if apiServerOnly {
 				port := cfg.Server.APIPort
@@ -124,6 +288,8 @@ func ServeCmd(ctx context.Context) *cobra.Command {
 				if err != nil {
 					logger.Fatal().Msgf("invalid URL %#v: %s\n", addr, err)
 				}
+				go monitor.Run(ctx)
+				handleMetricsServer(ctx, fmt.Sprintf(":%d", cfg.Server.MetricsPort), metrics, &wg, errc, logger)
 				handleHTTPServer(
 					ctx,
 					u,
@@ -131,6 +297,13 @@ func ServeCmd(ctx context.Context) *cobra.Command {
 					openapiEndpoints,
 					identityEndpoints,
 					domainEndpoints,
+					issuerEndpoints,
+					certEndpoints,
+					portEndpoints,
+					releaseEndpoints,
+					recorder,
+					limiter,
+					proxies,
 					&wg,
 					errc,
 					logger,
@@ -139,9 +312,33 @@ func ServeCmd(ctx context.Context) *cobra.Command {
 			}
 			if webServerOnly {
 				app := &webserver.Application{
-					Config: cfg,
-					Logger: logger,
-					DB:     dbx,
+					Config:   cfg,
+					Logger:   logger,
+					DB:       dbx,
+					Accounts: accounts,
+					Audit:    recorder,
+					RealIP:   proxies,
+					Kube:     kclient,
+					Ingress:  ingress,
+				}
+				if limiter != nil {
+					web := *limiter
+					web.CredentialFunc = webserver.LoginEmail
+					app.RateLimit = &web
+				}
+				if cfg.OIDC.Issuer != "" {
+					app.OIDC, err = sso.New(ctx, sso.Config{
+						Name:           cfg.OIDC.ProviderName,
+						Issuer:         cfg.OIDC.Issuer,
+						ClientID:       cfg.OIDC.ClientID,
+						ClientSecret:   cfg.OIDC.ClientSecret,
+						RedirectURL:    cfg.OIDC.RedirectURL,
+						AllowedDomains: cfg.OIDC.AllowedDomains,
+						AutoProvision:  cfg.OIDC.AutoProvision,
+					})
+					if err != nil {
+						logger.Fatal().Err(err).Msg("failed to configure single sign-on")
+					}
 				}
 				go func() {
 					err := app.Serve(ctx)
@@ -167,6 +364,8 @@ func ServeCmd(ctx context.Context) *cobra.Command {
 	cmd.Flags().BoolVar(&isConsole, "console", false, "Use zerolog ConsoleWriter")
 	cmd.Flags().BoolVar(&apiServerOnly, "api", false, "Run the API server")
 	cmd.Flags().BoolVar(&webServerOnly, "web", false, "Run the Web server")
+	cmd.Flags().BoolVar(&bootstrap, "bootstrap", false,
+		"Create the admin user on first boot, writing its API key to the ADMIN_API_KEY_SECRET secret")
 	return cmd
 }
 
@@ -179,6 +378,13 @@ func handleHTTPServer(
 	openapiEndpoints *openapi.Endpoints,
 	identityEndpoints *identity.Endpoints,
 	domainEndpoints *domains.Endpoints,
+	issuerEndpoints *issuers.Endpoints,
+	certEndpoints *certificates.Endpoints,
+	portEndpoints *ports.Endpoints,
+	releaseEndpoints *releases.Endpoints,
+	recorder *audit.Recorder,
+	limiter *ratelimit.Limiter,
+	proxies *realip.Resolver,
 	wg *sync.WaitGroup,
 	errc chan error,
 	logger *svclogger.Logger,
@@ -202,6 +408,14 @@ func handleHTTPServer(
 		enc = goahttp.ResponseEncoder
 	)
 
+	// Record mutating calls in the audit log. This wraps the endpoints so
+	// requests rejected during authentication are recorded too.
+	identityEndpoints.Use(recorder.Endpoint)
+	domainEndpoints.Use(recorder.Endpoint)
+	issuerEndpoints.Use(recorder.Endpoint)
+	portEndpoints.Use(recorder.Endpoint)
+	releaseEndpoints.Use(recorder.Endpoint)
+
 	// Build the service HTTP request multiplexer and configure it to serve
 	// HTTP requests to the service endpoints.
 	var mux goahttp.Muxer
@@ -218,6 +432,10 @@ func handleHTTPServer(
 		openapiServer    *openapisvr.Server
 		identityServer   *identitysvr.Server
 		domainServer     *domainsvr.Server
+		issuerServer     *issuersvr.Server
+		certServer       *certificatesvr.Server
+		portServer       *portsvr.Server
+		releaseServer    *releasesvr.Server
 	)
 	{
 		eh := errorHandler(logger)
@@ -225,12 +443,20 @@ func handleHTTPServer(
 		openapiServer = openapisvr.New(openapiEndpoints, mux, dec, enc, eh, nil)
 		identityServer = identitysvr.New(identityEndpoints, mux, dec, enc, eh, nil)
 		domainServer = domainsvr.New(domainEndpoints, mux, dec, enc, eh, nil)
+		issuerServer = issuersvr.New(issuerEndpoints, mux, dec, enc, eh, nil)
+		certServer = certificatesvr.New(certEndpoints, mux, dec, enc, eh, nil)
+		portServer = portsvr.New(portEndpoints, mux, dec, enc, eh, nil)
+		releaseServer = releasesvr.New(releaseEndpoints, mux, dec, enc, eh, nil)
 		if debug {
 			servers := goahttp.Servers{
 				monitoringServer,
 				openapiServer,
 				identityServer,
 				domainServer,
+				issuerServer,
+				certServer,
+				portServer,
+				releaseServer,
 			}
 			servers.Use(httpmdlwr.Debug(mux, os.Stdout))
 		}
@@ -240,11 +466,22 @@ func handleHTTPServer(
 	openapisvr.Mount(mux, openapiServer)
 	identitysvr.Mount(mux, identityServer)
 	domainsvr.Mount(mux, domainServer)
+	issuersvr.Mount(mux, issuerServer)
+	certificatesvr.Mount(mux, certServer)
+	portsvr.Mount(mux, portServer)
+	releasesvr.Mount(mux, releaseServer)
 
 	// Wrap the multiplexer with additional middlewares. Middlewares mounted
 	// here apply to all the service endpoints.
 	var handler http.Handler = mux
 	{
+		handler = auth.TeamContext(handler)
+		handler = pagination.Middleware(handler)
+		if limiter != nil {
+			handler = limiter.Middleware(handler)
+		}
+		handler = audit.ClientIP(handler)
+		handler = proxies.Middleware(handler)
 		handler = httpmdlwr.Log(adapter)(handler) //nolint:all
 		handler = httpmdlwr.RequestID()(handler)  //nolint:all
 	}
@@ -264,6 +501,18 @@ func handleHTTPServer(
 	for _, m := range domainServer.Mounts {
 		logger.Debug().Msgf("HTTP %q mounted on %s %s", m.Method, m.Verb, m.Pattern)
 	}
+	for _, m := range issuerServer.Mounts {
+		logger.Debug().Msgf("HTTP %q mounted on %s %s", m.Method, m.Verb, m.Pattern)
+	}
+	for _, m := range certServer.Mounts {
+		logger.Debug().Msgf("HTTP %q mounted on %s %s", m.Method, m.Verb, m.Pattern)
+	}
+	for _, m := range portServer.Mounts {
+		logger.Debug().Msgf("HTTP %q mounted on %s %s", m.Method, m.Verb, m.Pattern)
+	}
+	for _, m := range releaseServer.Mounts {
+		logger.Debug().Msgf("HTTP %q mounted on %s %s", m.Method, m.Verb, m.Pattern)
+	}
 
 	(*wg).Add(1)
 	go func() {
@@ -289,6 +538,41 @@ func handleHTTPServer(
 	}()
 }
 
+// handleMetricsServer serves the Prometheus metrics in registry on addr. It is
+// kept off the API listener so metrics are not exposed publicly.
+func handleMetricsServer(
+	ctx context.Context,
+	addr string,
+	registry *prometheus.Registry,
+	wg *sync.WaitGroup,
+	errc chan error,
+	logger *svclogger.Logger,
+) {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: time.Second * 60}
+
+	(*wg).Add(1)
+	go func() {
+		defer (*wg).Done()
+
+		go func() {
+			logger.Info().Msgf("metrics server listening on %q", addr)
+			errc <- srv.ListenAndServe()
+		}()
+
+		<-ctx.Done()
+		logger.Info().Msgf("shutting down metrics server at %q", addr)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := srv.Shutdown(ctx); err != nil {
+			logger.Error().Err(err).Msg("failed to shutdown metrics server")
+		}
+	}()
+}
+
 // errorHandler returns a function that writes and logs the given error.
 // The function also writes and logs the error unique ID so that it's possible
 // to correlate.
@@ -8,8 +8,20 @@ import (
 )
 
 type Conf struct {
-	Db     dbConf
-	Server serverConf
+	Db        dbConf
+	Server    serverConf
+	Admin     adminConf
+	Session   sessionConf
+	OIDC      oidcConf
+	Mail      mailConf
+	Storage   storageConf
+	RateLimit rateLimitConf
+	ACME      acmeConf
+	CA        caConf
+	Certs     certsConf
+	Ingress   ingressConf
+	// Base64 encoded 32 byte key used to encrypt secrets at rest, e.g. openssl rand -base64 32
+	EncryptionKey string `env:"ENCRYPTION_KEY"`
 }
 
 type dbConf struct {
@@ -25,11 +37,144 @@ type dbConf struct {
 }
 
 type serverConf struct {
-	APIPort      int           `env:"API_SERVER_PORT,default=9090"`
-	WebPort      int           `env:"WEB_SERVER_PORT,default=9091"`
+	APIPort int `env:"API_SERVER_PORT,default=9090"`
+	WebPort int `env:"WEB_SERVER_PORT,default=9091"`
+	// Port Prometheus metrics are served on, apart from the public API
+	MetricsPort int `env:"METRICS_PORT,default=9092"`
+	// Public URL of the web UI, used to build links in emails
+	WebURL       string        `env:"WEB_BASE_URL,default=http://localhost:9091"`
 	TimeoutRead  time.Duration `env:"SERVER_TIMEOUT_READ,default=5s"`
 	TimeoutIdle  time.Duration `env:"SERVER_TIMEOUT_IDLE,default=5s"`
 	TimeoutWrite time.Duration `env:"SERVER_TIMEOUT_WRITE,default=5s"`
+	// Semicolon separated addresses or CIDRs of reverse proxies trusted to
+	// report the client address in X-Forwarded-For or X-Real-IP, e.g.
+	// 10.0.0.0/8. The headers are ignored when unset.
+	TrustedProxies []string `env:"TRUSTED_PROXIES"`
+}
+
+type sessionConf struct {
+	Lifetime time.Duration `env:"SESSION_LIFETIME,default=12h"`
+	// Only send cookies over HTTPS. Disable for local development over HTTP.
+	CookieSecure bool `env:"SESSION_COOKIE_SECURE,default=true"`
+}
+
+// oidcConf configures single sign-on. SSO is disabled when Issuer is unset.
+type oidcConf struct {
+	Issuer       string `env:"OIDC_ISSUER"`
+	ClientID     string `env:"OIDC_CLIENT_ID"`
+	ClientSecret string `env:"OIDC_CLIENT_SECRET"`
+	RedirectURL  string `env:"OIDC_REDIRECT_URL,default=http://localhost:9091/login/oidc/callback"`
+	// Name the provider is stored under when linking identities
+	ProviderName string `env:"OIDC_PROVIDER_NAME,default=sso"`
+	// Create users on first login when no account matches their email
+	AutoProvision bool `env:"OIDC_AUTO_PROVISION,default=false"`
+	// Semicolon separated email domains allowed to auto provision, e.g. example.com;example.org
+	AllowedDomains []string `env:"OIDC_ALLOWED_DOMAINS"`
+}
+
+type mailConf struct {
+	// log or smtp. The log driver prints messages and should only be used in development.
+	Driver   string `env:"MAIL_DRIVER,default=log"`
+	From     string `env:"MAIL_FROM,default=tawny@localhost"`
+	Host     string `env:"SMTP_HOST"`
+	Port     int    `env:"SMTP_PORT,default=587"`
+	Username string `env:"SMTP_USERNAME"`
+	Password string `env:"SMTP_PASSWORD"`
+	// How long email verification and password reset links remain valid
+	VerificationLifetime time.Duration `env:"MAIL_VERIFICATION_LIFETIME,default=48h"`
+	ResetLifetime        time.Duration `env:"MAIL_RESET_LIFETIME,default=1h"`
+}
+
+// storageConf configures where uploaded files such as profile photos are kept.
+type storageConf struct {
+	// disk or s3. Use s3 when running more than one replica.
+	Driver string `env:"STORAGE_DRIVER,default=disk"`
+	// Prefix of the URL uploaded files are served from. Defaults to /media,
+	// served by the web server, for disk and the bucket URL for s3.
+	PublicURL         string `env:"STORAGE_PUBLIC_URL"`
+	DiskPath          string `env:"STORAGE_DISK_PATH,default=./storage"`
+	S3Bucket          string `env:"S3_BUCKET"`
+	S3Region          string `env:"S3_REGION,default=us-east-1"`
+	S3Endpoint        string `env:"S3_ENDPOINT"`
+	S3AccessKeyID     string `env:"S3_ACCESS_KEY_ID"`
+	S3SecretAccessKey string `env:"S3_SECRET_ACCESS_KEY"`
+	S3ForcePathStyle  bool   `env:"S3_FORCE_PATH_STYLE,default=false"`
+}
+
+// rateLimitConf throttles the API and web UI per client address and per
+// credential, i.e. API key or login email.
+type rateLimitConf struct {
+	Enabled bool `env:"RATE_LIMIT_ENABLED,default=true"`
+	// memory or postgres. Use postgres when running more than one replica.
+	Store string `env:"RATE_LIMIT_STORE,default=memory"`
+	// Requests per second and burst allowed per client address
+	IPRate  float64 `env:"RATE_LIMIT_IP_RATE,default=10"`
+	IPBurst int     `env:"RATE_LIMIT_IP_BURST,default=20"`
+	// Requests per second and burst allowed per credential
+	KeyRate  float64 `env:"RATE_LIMIT_KEY_RATE,default=20"`
+	KeyBurst int     `env:"RATE_LIMIT_KEY_BURST,default=40"`
+	// Failed authentications within the window before locking out. Each
+	// further failure doubles the lockout up to the maximum.
+	AuthFailureLimit  int           `env:"AUTH_FAILURE_LIMIT,default=5"`
+	AuthFailureWindow time.Duration `env:"AUTH_FAILURE_WINDOW,default=15m"`
+	AuthLockout       time.Duration `env:"AUTH_LOCKOUT,default=1m"`
+	AuthLockoutMax    time.Duration `env:"AUTH_LOCKOUT_MAX,default=1h"`
+}
+
+// certsConf configures monitoring of the certificates served for domains.
+type certsConf struct {
+	// How often the certificate inventory is refreshed
+	CheckInterval time.Duration `env:"CERT_CHECK_INTERVAL,default=1h"`
+	// Notify when a certificate expires within this long
+	ExpiryThreshold time.Duration `env:"CERT_EXPIRY_THRESHOLD,default=336h"`
+	// URL expiry and renewal failure notifications are posted to as JSON
+	NotifyWebhookURL string `env:"CERT_NOTIFY_WEBHOOK_URL"`
+	// Semicolon separated addresses notifications are mailed to. Notifications
+	// are logged when neither a webhook nor an address is set.
+	NotifyEmail []string `env:"CERT_NOTIFY_EMAIL"`
+}
+
+// ingressConf locates the ingress controller domains must resolve to.
+type ingressConf struct {
+	// Namespace and name of the ingress controller's Service
+	ServiceNamespace string `env:"INGRESS_SERVICE_NAMESPACE,default=traefik"`
+	ServiceName      string `env:"INGRESS_SERVICE_NAME,default=traefik"`
+	// Semicolon separated public addresses of the ingress. Overrides the
+	// addresses of the Service, e.g. when traffic arrives through NAT.
+	Addresses []string `env:"INGRESS_ADDRESSES"`
+	// Semicolon separated traefik entrypoints app ports may be mapped on, as
+	// name=port/protocol, e.g. postgres=5432/tcp;game=27015/udp. They must
+	// also be defined in traefik's static configuration.
+	EntryPoints []string `env:"INGRESS_ENTRYPOINTS"`
+}
+
+// acmeConf configures the shared Let's Encrypt issuers. They are not created
+// when Email is unset.
+type acmeConf struct {
+	// Contact address registered with Let's Encrypt for expiry notices
+	Email string `env:"ACME_EMAIL"`
+	// Namespace cert-manager reads ClusterIssuer secrets from, i.e. its
+	// --cluster-resource-namespace
+	Namespace string `env:"CERT_MANAGER_NAMESPACE,default=cert-manager"`
+}
+
+// caConf configures the internal CA, which issues certificates for IP
+// addresses and internal hostnames on networks without internet access. Its
+// root is kept in the ACME namespace, where cert-manager reads it from.
+type caConf struct {
+	Enabled    bool          `env:"INTERNAL_CA_ENABLED,default=false"`
+	CommonName string        `env:"INTERNAL_CA_COMMON_NAME,default=Tawny Internal CA"`
+	Duration   time.Duration `env:"INTERNAL_CA_DURATION,default=87600h"`
+}
+
+type adminConf struct {
+	Email    string `env:"ADMIN_EMAIL,default=admin@tawny.internal"`
+	Password string `env:"ADMIN_PASSWORD"`
+	// Kubernetes secret holding the admin password when ADMIN_PASSWORD is unset
+	PasswordSecret string `env:"ADMIN_PASSWORD_SECRET,default=admin-password"`
+	// Kubernetes secret the API key of the admin bootstrapped by serve is
+	// written to
+	APIKeySecret string `env:"ADMIN_API_KEY_SECRET,default=admin-api-key"`
 }
 
 // AppConfig Setup and install the applications' configuration environment variables
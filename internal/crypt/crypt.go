@@ -0,0 +1,74 @@
+// Package crypt encrypts small secrets, such as two-factor seeds, before they
+// are written to the database.
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// KeySize is the length of the AES-256 key.
+const KeySize = 32
+
+var (
+	ErrNoKey      = errors.New("encryption key is not configured")
+	ErrCiphertext = errors.New("ciphertext is malformed")
+)
+
+// Cipher seals values with AES-GCM. The random nonce is prefixed to the
+// ciphertext and the result is base64 encoded.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// New returns a Cipher for a base64 encoded 32 byte key.
+func New(key string) (*Cipher, error) {
+	if key == "" {
+		return nil, ErrNoKey
+	}
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key must be base64 encoded: %w", err)
+	}
+	if len(raw) != KeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", KeySize, len(raw))
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (c *Cipher) Decrypt(ciphertext string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", ErrCiphertext
+	}
+	n := c.aead.NonceSize()
+	if len(raw) < n {
+		return "", ErrCiphertext
+	}
+	plain, err := c.aead.Open(nil, raw[:n], raw[n:], nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrCiphertext, err)
+	}
+	return string(plain), nil
+}
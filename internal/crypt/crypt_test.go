@@ -0,0 +1,62 @@
+package crypt
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var testKey = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", KeySize)))
+
+func TestRoundTrip(t *testing.T) {
+	c, err := New(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := c.Encrypt("JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := c.Encrypt("JBSWY3DPEHPK3PXP")
+	if a == b {
+		t.Error("expected a fresh nonce for every encryption")
+	}
+	got, err := c.Decrypt(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestDecryptRejectsTampering(t *testing.T) {
+	c, _ := New(testKey)
+	sealed, _ := c.Encrypt("secret")
+	raw, _ := base64.StdEncoding.DecodeString(sealed)
+	raw[len(raw)-1] ^= 0xff
+	if _, err := c.Decrypt(base64.StdEncoding.EncodeToString(raw)); !errors.Is(err, ErrCiphertext) {
+		t.Fatalf("expected ErrCiphertext, got %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		ok   bool
+	}{
+		{"valid", testKey, true},
+		{"empty", "", false},
+		{"not base64", "!!!", false},
+		{"short", base64.StdEncoding.EncodeToString([]byte("short")), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.key); (err == nil) != tt.ok {
+				t.Errorf("New() error = %v, want ok=%v", err, tt.ok)
+			}
+		})
+	}
+}
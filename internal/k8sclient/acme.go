@@ -0,0 +1,74 @@
+package k8sclient
+
+import (
+	"context"
+	"sort"
+
+	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	cm "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// annotationCertificateName names the Certificate a CertificateRequest was
+// created for.
+const annotationCertificateName = "cert-manager.io/certificate-name"
+
+// ListCertificateRequests returns the CertificateRequests created for the
+// named Certificate, newest first.
+func (k K8sClient) ListCertificateRequests(
+	ctx context.Context,
+	namespace, certificate string,
+) ([]cm.CertificateRequest, error) {
+	res, err := k.cmClient.CertmanagerV1().CertificateRequests(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var items []cm.CertificateRequest
+	for _, r := range res.Items {
+		if r.Annotations[annotationCertificateName] == certificate {
+			items = append(items, r)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[j].CreationTimestamp.Before(&items[i].CreationTimestamp)
+	})
+	return items, nil
+}
+
+// ListOrders returns the ACME Orders created for a CertificateRequest.
+func (k K8sClient) ListOrders(
+	ctx context.Context,
+	namespace string,
+	owner metav1.Object,
+) ([]cmacme.Order, error) {
+	res, err := k.cmClient.AcmeV1().Orders(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var items []cmacme.Order
+	for _, o := range res.Items {
+		if metav1.IsControlledBy(&o, owner) {
+			items = append(items, o)
+		}
+	}
+	return items, nil
+}
+
+// ListChallenges returns the ACME Challenges created for an Order.
+func (k K8sClient) ListChallenges(
+	ctx context.Context,
+	namespace string,
+	owner metav1.Object,
+) ([]cmacme.Challenge, error) {
+	res, err := k.cmClient.AcmeV1().Challenges(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var items []cmacme.Challenge
+	for _, c := range res.Items {
+		if metav1.IsControlledBy(&c, owner) {
+			items = append(items, c)
+		}
+	}
+	return items, nil
+}
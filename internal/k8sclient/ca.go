@@ -0,0 +1,56 @@
+package k8sclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	assets "github.com/danielmichaels/tawny"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// CertificateTypeInternal selects the internal CA, which issues certificates
+// for IP addresses and internal hostnames without reaching the internet.
+const CertificateTypeInternal = "internal"
+
+var (
+	// SelfSignedClusterIssuerName issues the root of the internal CA.
+	SelfSignedClusterIssuerName = fmt.Sprintf("%s-selfsigned", assets.AppName)
+	// InternalCAIssuerName is the ClusterIssuer signing with the internal CA.
+	InternalCAIssuerName = fmt.Sprintf("%s-internal-ca", assets.AppName)
+)
+
+// InternalCASecretName returns the name of the Secret holding the root
+// certificate and key of the internal CA.
+func InternalCASecretName() string {
+	return CreateCertSecretName(InternalCAIssuerName)
+}
+
+// EnsureInternalCA bootstraps the internal CA: a self-signed issuer, a root
+// certificate issued by it into namespace and a CA issuer signing with the
+// root. namespace must be cert-manager's cluster resource namespace, where CA
+// ClusterIssuers read their Secret from. An existing root is kept as is, since
+// reissuing it would invalidate every certificate clients trust through it.
+func (k K8sClient) EnsureInternalCA(
+	ctx context.Context,
+	namespace, commonName string,
+	duration time.Duration,
+) error {
+	if _, err := k.EnsureClusterIssuer(ctx, SelfSignedClusterIssuerName, WithClusterIssuerSelfSigned()); err != nil {
+		return fmt.Errorf("failed to ensure cluster issuer %q: %w", SelfSignedClusterIssuerName, err)
+	}
+	_, err := k.GetCertificate(ctx, InternalCAIssuerName, namespace)
+	if apierrors.IsNotFound(err) {
+		_, err = k.CreateCertificate(ctx, InternalCAIssuerName, namespace,
+			WithCertificateCA(commonName, duration),
+			WithCertificateName(SelfSignedClusterIssuerName),
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to ensure internal CA root certificate: %w", err)
+	}
+	if _, err := k.EnsureClusterIssuer(ctx, InternalCAIssuerName, WithClusterIssuerCA(InternalCASecretName())); err != nil {
+		return fmt.Errorf("failed to ensure cluster issuer %q: %w", InternalCAIssuerName, err)
+	}
+	return nil
+}
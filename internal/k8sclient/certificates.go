@@ -2,22 +2,63 @@ package k8sclient
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"strings"
+	"time"
 
 	"k8s.io/client-go/util/retry"
 
 	cm "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
 	assets "github.com/danielmichaels/tawny"
-	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 func CreateCertSecretName(name string) string {
 	return fmt.Sprintf(DefaultCertSecretName, name)
 }
 
+// domainHashLength is the number of hex digits of the domain hash in
+// resource names.
+const domainHashLength = 10
+
+// DomainResourceName returns the name of the resources created for a domain.
+// The readable part, in which the wildcard label of a wildcard domain becomes
+// "wildcard" and dots become dashes, is ambiguous so a hash of the domain is
+// appended. Names fit in a label value so resources can be selected by
+// domain.
+func DomainResourceName(domain string) string {
+	domain = strings.ToLower(domain)
+	sum := sha256.Sum256([]byte(domain))
+	readable := domain
+	if IsWildcardDomain(domain) {
+		readable = "wildcard" + strings.TrimPrefix(domain, "*")
+	}
+	readable = strings.ReplaceAll(readable, ".", "-")
+	readable = readable[:min(len(readable), validation.LabelValueMaxLength-domainHashLength-1)]
+	return strings.TrimRight(readable, "-") + "-" + hex.EncodeToString(sum[:])[:domainHashLength]
+}
+
+// IsWildcardDomain reports whether domain is a wildcard such as
+// *.example.com.
+func IsWildcardDomain(domain string) bool {
+	return strings.HasPrefix(domain, "*.")
+}
+
+// WildcardDomain returns the wildcard domain covering domain, e.g.
+// *.example.com for api.example.com. Wildcards cover a single label only.
+func WildcardDomain(domain string) string {
+	_, parent, ok := strings.Cut(domain, ".")
+	if !ok || IsWildcardDomain(domain) {
+		return ""
+	}
+	return "*." + parent
+}
+
 func (k K8sClient) ListCertificates(
 	ctx context.Context,
 	namespace string,
@@ -35,11 +76,23 @@ func (k K8sClient) ListCertificates(
 	return res, nil
 }
 
+// ListManagedCertificates returns the Certificates in namespace created by
+// tawny.
+func (k K8sClient) ListManagedCertificates(ctx context.Context, namespace string) ([]cm.Certificate, error) {
+	res, err := k.cmClient.CertmanagerV1().
+		Certificates(namespace).
+		List(ctx, metav1.ListOptions{LabelSelector: ManagedSelector()})
+	if err != nil {
+		return nil, err
+	}
+	return res.Items, nil
+}
+
 func (k K8sClient) GetCertificate(
 	ctx context.Context,
 	name, namespace string,
-) (*v1.Service, error) {
-	res, err := k.Client.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+) (*cm.Certificate, error) {
+	res, err := k.cmClient.CertmanagerV1().Certificates(namespace).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -82,6 +135,10 @@ func (k K8sClient) UpdateCertificate(
 	return result, nil
 }
 
+func (k K8sClient) DeleteCertificate(ctx context.Context, name, namespace string) error {
+	return k.cmClient.CertmanagerV1().Certificates(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
 type CertificateOption func(c *cm.Certificate)
 
 func WithCertificateDomain(domain string) CertificateOption {
@@ -89,6 +146,28 @@ func WithCertificateDomain(domain string) CertificateOption {
 		c.Spec.DNSNames = append(c.Spec.DNSNames, domain)
 	}
 }
+
+// WithCertificateIPAddress adds an IP SAN, which only the internal CA can
+// issue.
+func WithCertificateIPAddress(ip string) CertificateOption {
+	return func(c *cm.Certificate) {
+		c.Spec.IPAddresses = append(c.Spec.IPAddresses, ip)
+	}
+}
+
+// WithCertificateCA makes the certificate a CA root named commonName, valid
+// for duration.
+func WithCertificateCA(commonName string, duration time.Duration) CertificateOption {
+	return func(c *cm.Certificate) {
+		c.Spec.IsCA = true
+		c.Spec.CommonName = commonName
+		c.Spec.Duration = &metav1.Duration{Duration: duration}
+		c.Spec.PrivateKey = &cm.CertificatePrivateKey{
+			Algorithm: cm.ECDSAKeyAlgorithm,
+			Size:      256,
+		}
+	}
+}
 func WithCertificateKind(kind string) CertificateOption {
 	return func(c *cm.Certificate) {
 		c.Spec.IssuerRef.Kind = kind
@@ -99,6 +178,13 @@ func WithCertificateName(name string) CertificateOption {
 		c.Spec.IssuerRef.Name = name
 	}
 }
+
+// WithCertificateTeam labels a certificate as owned by teamID.
+func WithCertificateTeam(teamID string) CertificateOption {
+	return func(c *cm.Certificate) {
+		c.ObjectMeta.Labels[LabelTeam] = teamID
+	}
+}
 func NewCertificate(name, namespace string, opts ...CertificateOption) *cm.Certificate {
 	labels := CreateLabels(WithName(name))
 	if namespace == assets.AppName {
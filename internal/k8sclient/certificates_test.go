@@ -0,0 +1,53 @@
+package k8sclient
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestWildcardNames(t *testing.T) {
+	tests := []struct {
+		domain       string
+		resourceName string
+		wildcard     string
+		hostMatch    string
+	}{
+		{"app.example.com", "app-example-com-28059829b1", "*.example.com", "Host(`app.example.com`)"},
+		{"*.example.com", "wildcard-example-com-47287a8f16", "", "HostRegexp(`^[a-z0-9-]+\\.example\\.com$`)"},
+		{"localhost", "localhost-49960de588", "", "Host(`localhost`)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			if got := DomainResourceName(tt.domain); got != tt.resourceName {
+				t.Errorf("DomainResourceName() = %q, want %q", got, tt.resourceName)
+			}
+			if got := WildcardDomain(tt.domain); got != tt.wildcard {
+				t.Errorf("WildcardDomain() = %q, want %q", got, tt.wildcard)
+			}
+			if got := HostMatch(tt.domain); got != tt.hostMatch {
+				t.Errorf("HostMatch() = %q, want %q", got, tt.hostMatch)
+			}
+		})
+	}
+}
+
+func TestDomainResourceNameUnique(t *testing.T) {
+	pairs := [][2]string{
+		{"a-b.com", "a.b-com"},
+		{"wildcard.example.com", "*.example.com"},
+		{strings.Repeat("a", 60) + ".com", strings.Repeat("a", 60) + ".org"},
+	}
+	for _, p := range pairs {
+		a, b := DomainResourceName(p[0]), DomainResourceName(p[1])
+		if a == b {
+			t.Errorf("DomainResourceName(%q) = DomainResourceName(%q) = %q", p[0], p[1], a)
+		}
+		for _, name := range []string{a, b} {
+			if errs := validation.IsValidLabelValue(name); len(errs) > 0 {
+				t.Errorf("DomainResourceName() = %q is not a valid label value: %v", name, errs)
+			}
+		}
+	}
+}
@@ -2,11 +2,26 @@ package k8sclient
 
 import (
 	"context"
+	"strings"
 
+	assets "github.com/danielmichaels/tawny"
 	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
+// LabelRelease identifies the pods, by release resource name, of a version
+// of an app stood up by a release.
+const LabelRelease = "tawny.sh/release"
+
+// ReleaseResourceName returns the name of the Deployment and Service of the
+// release with id.
+func ReleaseResourceName(id string) string {
+	return strings.ReplaceAll(strings.ToLower(id), "_", "-")
+}
+
 func (k K8sClient) ListDeployments(
 	ctx context.Context,
 	namespace string,
@@ -28,3 +43,104 @@ func (k K8sClient) GetDeployment(
 	}
 	return res, nil
 }
+
+// ServiceDeployment returns the Deployment whose pods svc selects.
+func (k K8sClient) ServiceDeployment(ctx context.Context, svc *v1.Service) (*appsv1.Deployment, error) {
+	res, err := k.ListDeployments(ctx, svc.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if len(svc.Spec.Selector) > 0 {
+		selector := labels.SelectorFromSet(svc.Spec.Selector)
+		for i := range res.Items {
+			if selector.Matches(labels.Set(res.Items[i].Spec.Template.Labels)) {
+				return &res.Items[i], nil
+			}
+		}
+	}
+	return nil, apierrors.NewNotFound(appsv1.Resource("deployments"), svc.Name)
+}
+
+func (k K8sClient) CreateDeployment(
+	ctx context.Context,
+	name, namespace string,
+	opts ...DeploymentOption,
+) (*appsv1.Deployment, error) {
+	desired := NewDeployment(name, namespace, opts...)
+	res, err := k.Client.AppsV1().Deployments(namespace).Create(ctx, desired, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (k K8sClient) DeleteDeployment(ctx context.Context, name, namespace string) error {
+	return k.Client.AppsV1().Deployments(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+type DeploymentOption func(*appsv1.Deployment)
+
+// WithDeploymentSpec copies spec, such as that of the version of an app being
+// released.
+func WithDeploymentSpec(spec appsv1.DeploymentSpec) DeploymentOption {
+	return func(d *appsv1.Deployment) {
+		d.Spec = *spec.DeepCopy()
+	}
+}
+
+// WithDeploymentImage runs image in the named container.
+func WithDeploymentImage(container, image string) DeploymentOption {
+	return func(d *appsv1.Deployment) {
+		for i, c := range d.Spec.Template.Spec.Containers {
+			if c.Name == container {
+				d.Spec.Template.Spec.Containers[i].Image = image
+			}
+		}
+	}
+}
+
+// WithDeploymentRelease selects the pods of the Deployment by the release
+// label of name alone. The labels of exclude, the selector of the Service of
+// the running version, are removed from the pods so it does not select them.
+func WithDeploymentRelease(name string, exclude map[string]string) DeploymentOption {
+	return func(d *appsv1.Deployment) {
+		podLabels := make(map[string]string)
+		for k, v := range d.Spec.Template.Labels {
+			if _, ok := exclude[k]; !ok {
+				podLabels[k] = v
+			}
+		}
+		podLabels[LabelRelease] = name
+		d.Spec.Template.Labels = podLabels
+		d.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{LabelRelease: name}}
+	}
+}
+
+// WithDeploymentTeam labels a Deployment as owned by teamID.
+func WithDeploymentTeam(teamID string) DeploymentOption {
+	return func(d *appsv1.Deployment) {
+		d.ObjectMeta.Labels[LabelTeam] = teamID
+	}
+}
+
+func NewDeployment(name, namespace string, opts ...DeploymentOption) *appsv1.Deployment {
+	l := CreateLabels(WithName(name), WithComponent("deployment"))
+	if namespace == assets.AppName {
+		l = CreateLabels(WithName(name), WithComponent("deployment"), WithCoreLabel(true))
+	}
+	d := &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    l,
+		},
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
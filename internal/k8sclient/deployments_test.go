@@ -0,0 +1,52 @@
+package k8sclient
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestWithDeploymentRelease(t *testing.T) {
+	stable := appsv1.DeploymentSpec{
+		Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+		Template: v1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web", "tier": "frontend"}},
+			Spec: v1.PodSpec{Containers: []v1.Container{
+				{Name: "web", Image: "web:1"},
+				{Name: "proxy", Image: "proxy:1"},
+			}},
+		},
+	}
+	d := NewDeployment("rel-abc1234", DefaultNamespace,
+		WithDeploymentSpec(stable),
+		WithDeploymentImage("web", "web:2"),
+		WithDeploymentRelease("rel-abc1234", map[string]string{"app": "web"}),
+	)
+
+	pods := labels.Set(d.Spec.Template.Labels)
+	if labels.SelectorFromSet(map[string]string{"app": "web"}).Matches(pods) {
+		t.Errorf("pod labels %v are selected by the stable Service", pods)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(d.Spec.Selector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !selector.Matches(pods) {
+		t.Errorf("selector %v does not match pod labels %v", selector, pods)
+	}
+	if pods["tier"] != "frontend" {
+		t.Errorf("pod labels %v dropped labels outside the stable selector", pods)
+	}
+	if got := d.Spec.Template.Spec.Containers[0].Image; got != "web:2" {
+		t.Errorf("web image = %q, want %q", got, "web:2")
+	}
+	if got := d.Spec.Template.Spec.Containers[1].Image; got != "proxy:1" {
+		t.Errorf("proxy image = %q, want %q", got, "proxy:1")
+	}
+	if got := stable.Template.Spec.Containers[0].Image; got != "web:1" {
+		t.Errorf("stable spec was modified, image = %q", got)
+	}
+}
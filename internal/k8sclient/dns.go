@@ -0,0 +1,220 @@
+package k8sclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+
+	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// DNS providers understood by DNS-01 issuers.
+const (
+	DNSProviderCloudflare   = "cloudflare"
+	DNSProviderRoute53      = "route53"
+	DNSProviderDigitalOcean = "digitalocean"
+	DNSProviderRFC2136      = "rfc2136"
+	DNSProviderWebhook      = "webhook"
+)
+
+// DNSProvider builds the DNS-01 solver of an ACME issuer for one DNS service.
+// Settings are plain configuration while credentials are kept in a Secret,
+// which the solver refers to by key.
+type DNSProvider struct {
+	Name string
+	// Settings and Credentials which must be given.
+	Settings    []string
+	Credentials []string
+	// Optional settings and credentials.
+	OptionalSettings    []string
+	OptionalCredentials []string
+	// Open providers accept settings and credentials beyond those listed.
+	Open bool
+	// Solver returns the solver using settings and the credentials stored in
+	// secret. credentials lists the credential keys present in secret.
+	Solver func(settings map[string]string, secret string, credentials []string) *cmacme.ACMEChallengeSolverDNS01
+	// Match reports whether a solver was built by this provider.
+	Match func(*cmacme.ACMEChallengeSolverDNS01) bool
+}
+
+var dnsProviders = map[string]DNSProvider{}
+
+// RegisterDNSProvider makes a DNS provider available by name, replacing any
+// provider of the same name.
+func RegisterDNSProvider(p DNSProvider) {
+	dnsProviders[p.Name] = p
+}
+
+// GetDNSProvider returns the provider registered as name.
+func GetDNSProvider(name string) (DNSProvider, error) {
+	p, ok := dnsProviders[name]
+	if !ok {
+		return p, fmt.Errorf("unknown DNS provider %q", name)
+	}
+	return p, nil
+}
+
+// DNSProviderNames returns the names of the registered providers, sorted.
+func DNSProviderNames() []string {
+	names := make([]string, 0, len(dnsProviders))
+	for name := range dnsProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DNSProviderOf returns the name of the provider which built solver, or an
+// empty string when none matches.
+func DNSProviderOf(solver *cmacme.ACMEChallengeSolverDNS01) string {
+	for _, name := range DNSProviderNames() {
+		if dnsProviders[name].Match(solver) {
+			return name
+		}
+	}
+	return ""
+}
+
+// Validate checks that settings and credentials hold every required key and
+// nothing the provider does not understand. Credentials are not checked when
+// nil, i.e. when the stored credentials are kept.
+func (p DNSProvider) Validate(settings, credentials map[string]string) error {
+	if err := checkKeys("setting", settings, p.Settings, p.OptionalSettings, p.Open); err != nil {
+		return err
+	}
+	if credentials == nil {
+		return nil
+	}
+	return checkKeys("credential", credentials, p.Credentials, p.OptionalCredentials, p.Open)
+}
+
+func checkKeys(kind string, got map[string]string, required, optional []string, open bool) error {
+	for _, k := range required {
+		if got[k] == "" {
+			return fmt.Errorf("%s %q is required", kind, k)
+		}
+	}
+	if open {
+		return nil
+	}
+	for k := range got {
+		if !slices.Contains(required, k) && !slices.Contains(optional, k) {
+			return fmt.Errorf("unknown %s %q", kind, k)
+		}
+	}
+	return nil
+}
+
+// DNSCredentialsSecretName returns the name of the Secret holding the DNS
+// provider credentials of an issuer.
+func DNSCredentialsSecretName(issuer string) string {
+	return fmt.Sprintf("%s-dns-credentials", issuer)
+}
+
+func secretRef(secret, key string) cmmeta.SecretKeySelector {
+	return cmmeta.SecretKeySelector{
+		Key:                  key,
+		LocalObjectReference: cmmeta.LocalObjectReference{Name: secret},
+	}
+}
+
+func init() {
+	RegisterDNSProvider(DNSProvider{
+		Name:             DNSProviderCloudflare,
+		Credentials:      []string{"api_token"},
+		OptionalSettings: []string{"email"},
+		Solver: func(settings map[string]string, secret string, _ []string) *cmacme.ACMEChallengeSolverDNS01 {
+			ref := secretRef(secret, "api_token")
+			return &cmacme.ACMEChallengeSolverDNS01{
+				Cloudflare: &cmacme.ACMEIssuerDNS01ProviderCloudflare{
+					Email:    settings["email"],
+					APIToken: &ref,
+				},
+			}
+		},
+		Match: func(s *cmacme.ACMEChallengeSolverDNS01) bool { return s.Cloudflare != nil },
+	})
+	RegisterDNSProvider(DNSProvider{
+		Name:             DNSProviderRoute53,
+		Settings:         []string{"region"},
+		OptionalSettings: []string{"access_key_id", "hosted_zone_id", "role"},
+		// Without credentials the ambient AWS credentials of cert-manager
+		// are used.
+		OptionalCredentials: []string{"secret_access_key"},
+		Solver: func(settings map[string]string, secret string, credentials []string) *cmacme.ACMEChallengeSolverDNS01 {
+			r := &cmacme.ACMEIssuerDNS01ProviderRoute53{
+				Region:       settings["region"],
+				AccessKeyID:  settings["access_key_id"],
+				HostedZoneID: settings["hosted_zone_id"],
+				Role:         settings["role"],
+			}
+			if slices.Contains(credentials, "secret_access_key") {
+				r.SecretAccessKey = secretRef(secret, "secret_access_key")
+			}
+			return &cmacme.ACMEChallengeSolverDNS01{Route53: r}
+		},
+		Match: func(s *cmacme.ACMEChallengeSolverDNS01) bool { return s.Route53 != nil },
+	})
+	RegisterDNSProvider(DNSProvider{
+		Name:        DNSProviderDigitalOcean,
+		Credentials: []string{"token"},
+		Solver: func(_ map[string]string, secret string, _ []string) *cmacme.ACMEChallengeSolverDNS01 {
+			return &cmacme.ACMEChallengeSolverDNS01{
+				DigitalOcean: &cmacme.ACMEIssuerDNS01ProviderDigitalOcean{
+					Token: secretRef(secret, "token"),
+				},
+			}
+		},
+		Match: func(s *cmacme.ACMEChallengeSolverDNS01) bool { return s.DigitalOcean != nil },
+	})
+	RegisterDNSProvider(DNSProvider{
+		Name:                DNSProviderRFC2136,
+		Settings:            []string{"nameserver"},
+		OptionalSettings:    []string{"tsig_key_name", "tsig_algorithm"},
+		OptionalCredentials: []string{"tsig_secret"},
+		Solver: func(settings map[string]string, secret string, credentials []string) *cmacme.ACMEChallengeSolverDNS01 {
+			r := &cmacme.ACMEIssuerDNS01ProviderRFC2136{
+				Nameserver:    settings["nameserver"],
+				TSIGKeyName:   settings["tsig_key_name"],
+				TSIGAlgorithm: settings["tsig_algorithm"],
+			}
+			if slices.Contains(credentials, "tsig_secret") {
+				r.TSIGSecret = secretRef(secret, "tsig_secret")
+			}
+			return &cmacme.ACMEChallengeSolverDNS01{RFC2136: r}
+		},
+		Match: func(s *cmacme.ACMEChallengeSolverDNS01) bool { return s.RFC2136 != nil },
+	})
+	// Webhook solvers are configured freely. Settings other than the group
+	// and solver names are passed on as the webhook config, along with the
+	// name of the credentials Secret as secretName when there is one.
+	RegisterDNSProvider(DNSProvider{
+		Name:     DNSProviderWebhook,
+		Settings: []string{"group_name", "solver_name"},
+		Open:     true,
+		Solver: func(settings map[string]string, secret string, credentials []string) *cmacme.ACMEChallengeSolverDNS01 {
+			config := make(map[string]string)
+			for k, v := range settings {
+				if k != "group_name" && k != "solver_name" {
+					config[k] = v
+				}
+			}
+			if len(credentials) > 0 {
+				config["secretName"] = secret
+			}
+			w := &cmacme.ACMEIssuerDNS01ProviderWebhook{
+				GroupName:  settings["group_name"],
+				SolverName: settings["solver_name"],
+			}
+			if len(config) > 0 {
+				raw, _ := json.Marshal(config)
+				w.Config = &apiextensionsv1.JSON{Raw: raw}
+			}
+			return &cmacme.ACMEChallengeSolverDNS01{Webhook: w}
+		},
+		Match: func(s *cmacme.ACMEChallengeSolverDNS01) bool { return s.Webhook != nil },
+	})
+}
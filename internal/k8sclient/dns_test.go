@@ -0,0 +1,56 @@
+package k8sclient
+
+import (
+	"testing"
+)
+
+func TestDNSProviderValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		provider    string
+		settings    map[string]string
+		credentials map[string]string
+		wantErr     bool
+	}{
+		{"cloudflare", DNSProviderCloudflare, nil, map[string]string{"api_token": "t"}, false},
+		{"cloudflare without token", DNSProviderCloudflare, nil, map[string]string{}, true},
+		{"cloudflare keeps stored token", DNSProviderCloudflare, nil, nil, false},
+		{"route53 ambient credentials", DNSProviderRoute53, map[string]string{"region": "us-east-1"}, map[string]string{}, false},
+		{"route53 without region", DNSProviderRoute53, map[string]string{}, map[string]string{}, true},
+		{"route53 unknown setting", DNSProviderRoute53, map[string]string{"region": "us-east-1", "zone": "x"}, nil, true},
+		{"digitalocean unknown credential", DNSProviderDigitalOcean, nil, map[string]string{"token": "t", "key": "k"}, true},
+		{"rfc2136", DNSProviderRFC2136, map[string]string{"nameserver": "10.0.0.53:53", "tsig_key_name": "tawny"}, map[string]string{"tsig_secret": "s"}, false},
+		{"webhook accepts any config", DNSProviderWebhook, map[string]string{"group_name": "acme.example.com", "solver_name": "hetzner", "zone": "x"}, map[string]string{"api_key": "k"}, false},
+		{"webhook without solver", DNSProviderWebhook, map[string]string{"group_name": "acme.example.com"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := GetDNSProvider(tt.provider)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Validate(tt.settings, tt.credentials); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDNSProviderSolver(t *testing.T) {
+	for _, name := range DNSProviderNames() {
+		t.Run(name, func(t *testing.T) {
+			p, _ := GetDNSProvider(name)
+			settings := map[string]string{}
+			for _, k := range p.Settings {
+				settings[k] = "x"
+			}
+			solver := p.Solver(settings, DNSCredentialsSecretName("acme"), p.Credentials)
+			if got := DNSProviderOf(solver); got != name {
+				t.Errorf("DNSProviderOf() = %q, want %q", got, name)
+			}
+		})
+	}
+	if _, err := GetDNSProvider("bind"); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+}
@@ -3,6 +3,9 @@ package k8sclient
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"sort"
+	"strings"
 
 	assets "github.com/danielmichaels/tawny"
 	"github.com/rs/zerolog/log"
@@ -20,8 +23,6 @@ const (
 )
 
 func ingressRouteNameGenerator(name, namespace, entryPoint string) string {
-	a := fmt.Sprintf("%s-%s-%s-%s", namespace, name, entryPoint, DefaultIngressRouteName)
-	fmt.Printf("Name: %q\n", a)
 	return fmt.Sprintf("%s-%s-%s-%s", namespace, name, entryPoint, DefaultIngressRouteName)
 }
 
@@ -78,6 +79,30 @@ func (k K8sClient) UpdateIngress(
 	return result, nil
 }
 
+// PatchIngress applies opts to the existing websecure IngressRoute, keeping
+// the rest of its spec.
+func (k K8sClient) PatchIngress(
+	ctx context.Context,
+	name, namespace string,
+	opts ...IngressRouteOption,
+) (*traefikv1alpha1.IngressRoute, error) {
+	var result *traefikv1alpha1.IngressRoute
+	if retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		res, err := k.tClient.TraefikV1alpha1().IngressRoutes(namespace).Get(ctx, ingressRouteNameGenerator(name, namespace, EntryPointWebSecure), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		for _, opt := range opts {
+			opt(res)
+		}
+		result, err = k.tClient.TraefikV1alpha1().IngressRoutes(namespace).Update(ctx, res, metav1.UpdateOptions{})
+		return err
+	}); retryErr != nil {
+		return nil, retryErr
+	}
+	return result, nil
+}
+
 type IngressRouteOption func(*traefikv1alpha1.IngressRoute)
 
 func WithIngressRouteEntryPoint(entryType string) IngressRouteOption {
@@ -97,7 +122,6 @@ func WithIngressRouteTLS(secretName string) IngressRouteOption {
 	tls := &traefikv1alpha1.TLS{
 		SecretName: secretName,
 	}
-	fmt.Println(tls)
 	return func(i *traefikv1alpha1.IngressRoute) {
 		i.Spec.TLS = tls
 	}
@@ -117,7 +141,7 @@ func WithIngressRouteRule(
 		}
 	}
 	route := traefikv1alpha1.Route{
-		Match: fmt.Sprintf("Host(`%s`)", match),
+		Match: HostMatch(match),
 		Kind:  "Rule",
 		Services: []traefikv1alpha1.Service{
 			{LoadBalancerSpec: traefikv1alpha1.LoadBalancerSpec{
@@ -134,6 +158,86 @@ func WithIngressRouteRule(
 	}
 }
 
+// WithIngressRouteMatch adds a route sending requests matching the traefik
+// rule match to backend. A priority of zero leaves traefik to rank the route
+// by the length of its rule.
+func WithIngressRouteMatch(match string, priority int, backend traefikv1alpha1.LoadBalancerSpec) IngressRouteOption {
+	route := traefikv1alpha1.Route{
+		Match:    match,
+		Kind:     "Rule",
+		Priority: priority,
+		Services: []traefikv1alpha1.Service{{LoadBalancerSpec: backend}},
+	}
+	return func(i *traefikv1alpha1.IngressRoute) {
+		i.Spec.Routes = append(i.Spec.Routes, route)
+	}
+}
+
+// ServiceBackend refers a route to a port of a Kubernetes Service.
+func ServiceBackend(name, namespace string, port int32) traefikv1alpha1.LoadBalancerSpec {
+	return traefikv1alpha1.LoadBalancerSpec{
+		Name:      name,
+		Kind:      "Service",
+		Namespace: namespace,
+		Port:      intstr.FromInt32(port),
+	}
+}
+
+// TraefikServiceBackend refers a route to a TraefikService, such as a
+// weighted round robin.
+func TraefikServiceBackend(name, namespace string) traefikv1alpha1.LoadBalancerSpec {
+	return traefikv1alpha1.LoadBalancerSpec{
+		Name:      name,
+		Kind:      "TraefikService",
+		Namespace: namespace,
+	}
+}
+
+// WithIngressRoutePriority sets the priority of the routes added so far.
+// Traefik otherwise prefers the route with the longest rule.
+func WithIngressRoutePriority(priority int) IngressRouteOption {
+	return func(i *traefikv1alpha1.IngressRoute) {
+		for r := range i.Spec.Routes {
+			i.Spec.Routes[r].Priority = priority
+		}
+	}
+}
+
+// HostMatch returns the traefik rule matching requests for domain. Wildcard
+// domains match any single label in place of the wildcard.
+func HostMatch(domain string) string {
+	if IsWildcardDomain(domain) {
+		parent := strings.TrimPrefix(domain, "*.")
+		return fmt.Sprintf("HostRegexp(`^[a-z0-9-]+\\.%s$`)", regexp.QuoteMeta(parent))
+	}
+	return fmt.Sprintf("Host(`%s`)", domain)
+}
+
+// RouteMatch returns the traefik rule matching requests for domain whose path
+// starts with pathPrefix, when set, and which carry every header in headers.
+func RouteMatch(domain, pathPrefix string, headers map[string]string) string {
+	rule := []string{HostMatch(domain)}
+	if pathPrefix != "" && pathPrefix != "/" {
+		rule = append(rule, fmt.Sprintf("PathPrefix(`%s`)", pathPrefix))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rule = append(rule, fmt.Sprintf("Header(`%s`, `%s`)", name, headers[name]))
+	}
+	return strings.Join(rule, " && ")
+}
+
+// WithIngressRouteTeam labels an ingress route as owned by teamID.
+func WithIngressRouteTeam(teamID string) IngressRouteOption {
+	return func(i *traefikv1alpha1.IngressRoute) {
+		i.ObjectMeta.Labels[LabelTeam] = teamID
+	}
+}
+
 func NewIngressRoute(
 	name, namespace string,
 	opts ...IngressRouteOption,
@@ -157,7 +261,7 @@ func NewIngressRoute(
 		opt(i)
 	}
 
-	if i.Spec.TLS.SecretName != "" {
+	if i.Spec.TLS != nil && i.Spec.TLS.SecretName != "" {
 		i.ObjectMeta.Name = ingressRouteNameGenerator(name, namespace, EntryPointWebSecure)
 	}
 	return i
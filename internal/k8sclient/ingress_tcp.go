@@ -0,0 +1,157 @@
+package k8sclient
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	assets "github.com/danielmichaels/tawny"
+	traefikv1alpha1 "github.com/traefik/traefik/v3/pkg/provider/kubernetes/crd/traefikio/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Protocols of an entrypoint.
+const (
+	ProtocolTCP = "tcp"
+	ProtocolUDP = "udp"
+)
+
+// EntryPoint is a traefik entrypoint apps may expose a port on. Entrypoints
+// are part of traefik's static configuration and cannot be created at
+// runtime.
+type EntryPoint struct {
+	Name     string
+	Port     int32
+	Protocol string
+}
+
+// ParseEntryPoints parses entrypoints given as name=port/protocol, e.g.
+// postgres=5432/tcp. The protocol defaults to tcp.
+func ParseEntryPoints(specs []string) ([]EntryPoint, error) {
+	var res []EntryPoint
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		name, addr, ok := strings.Cut(spec, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid entrypoint %q: want name=port/protocol", spec)
+		}
+		port, protocol, _ := strings.Cut(addr, "/")
+		if protocol == "" {
+			protocol = ProtocolTCP
+		}
+		if protocol != ProtocolTCP && protocol != ProtocolUDP {
+			return nil, fmt.Errorf("invalid entrypoint %q: unknown protocol %q", spec, protocol)
+		}
+		p, err := strconv.ParseInt(port, 10, 32)
+		if err != nil || p < 1 || p > 65535 {
+			return nil, fmt.Errorf("invalid entrypoint %q: invalid port %q", spec, port)
+		}
+		res = append(res, EntryPoint{Name: name, Port: int32(p), Protocol: protocol})
+	}
+	return res, nil
+}
+
+// PortResourceName returns the name of the resources created for a port
+// mapping, whose identifier may not be a valid object name.
+func PortResourceName(id string) string {
+	return strings.ReplaceAll(strings.ToLower(id), "_", "-")
+}
+
+func (k K8sClient) CreateIngressTCP(
+	ctx context.Context,
+	name, namespace string,
+	opts ...IngressRouteTCPOption,
+) (*traefikv1alpha1.IngressRouteTCP, error) {
+	route := NewIngressRouteTCP(name, namespace, opts...)
+	res, err := k.tClient.TraefikV1alpha1().
+		IngressRouteTCPs(namespace).
+		Create(ctx, route, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (k K8sClient) DeleteIngressTCP(ctx context.Context, name, namespace string) error {
+	return k.tClient.TraefikV1alpha1().IngressRouteTCPs(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+type IngressRouteTCPOption func(*traefikv1alpha1.IngressRouteTCP)
+
+func WithIngressRouteTCPEntryPoint(entryPoint string) IngressRouteTCPOption {
+	return func(i *traefikv1alpha1.IngressRouteTCP) {
+		i.Spec.EntryPoints = append(i.Spec.EntryPoints, entryPoint)
+	}
+}
+
+// WithIngressRouteTCPRule routes connections for hostSNI to a Service. An
+// empty hostSNI matches every connection, which is the only match possible
+// for plain TCP; hostnames can only be told apart over TLS.
+func WithIngressRouteTCPRule(hostSNI, svcName, svcNamespace string, svcPort int32) IngressRouteTCPOption {
+	route := traefikv1alpha1.RouteTCP{
+		Match: HostSNIMatch(hostSNI),
+		Services: []traefikv1alpha1.ServiceTCP{{
+			Name:      svcName,
+			Namespace: svcNamespace,
+			Port:      intstr.FromInt32(svcPort),
+		}},
+	}
+	return func(i *traefikv1alpha1.IngressRouteTCP) {
+		i.Spec.Routes = append(i.Spec.Routes, route)
+	}
+}
+
+// WithIngressRouteTCPPassthrough forwards TLS connections to the Service
+// without terminating them, e.g. for databases which serve their own
+// certificate.
+func WithIngressRouteTCPPassthrough() IngressRouteTCPOption {
+	return func(i *traefikv1alpha1.IngressRouteTCP) {
+		i.Spec.TLS = &traefikv1alpha1.TLSTCP{Passthrough: true}
+	}
+}
+
+// WithIngressRouteTCPTeam labels a TCP route as owned by teamID.
+func WithIngressRouteTCPTeam(teamID string) IngressRouteTCPOption {
+	return func(i *traefikv1alpha1.IngressRouteTCP) {
+		i.ObjectMeta.Labels[LabelTeam] = teamID
+	}
+}
+
+// HostSNIMatch returns the traefik rule matching TCP connections for host,
+// or every connection when host is empty.
+func HostSNIMatch(host string) string {
+	if host == "" {
+		host = "*"
+	}
+	return fmt.Sprintf("HostSNI(`%s`)", host)
+}
+
+func NewIngressRouteTCP(
+	name, namespace string,
+	opts ...IngressRouteTCPOption,
+) *traefikv1alpha1.IngressRouteTCP {
+	labels := CreateLabels(WithName(name), WithComponent("ingressroutetcp"))
+	if namespace == assets.AppName {
+		labels = CreateLabels(WithName(name), WithComponent("ingressroutetcp"), WithCoreLabel(true))
+	}
+	i := &traefikv1alpha1.IngressRouteTCP{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "traefik.io/v1alpha1",
+			Kind:       "IngressRouteTCP",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
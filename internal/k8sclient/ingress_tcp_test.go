@@ -0,0 +1,44 @@
+package k8sclient
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEntryPoints(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []string
+		want    []EntryPoint
+		wantErr bool
+	}{
+		{
+			name:  "tcp and udp",
+			specs: []string{"postgres=5432/tcp", "game=27015/udp"},
+			want: []EntryPoint{
+				{Name: "postgres", Port: 5432, Protocol: ProtocolTCP},
+				{Name: "game", Port: 27015, Protocol: ProtocolUDP},
+			},
+		},
+		{
+			name:  "protocol defaults to tcp",
+			specs: []string{" redis=6379 ", ""},
+			want:  []EntryPoint{{Name: "redis", Port: 6379, Protocol: ProtocolTCP}},
+		},
+		{name: "missing name", specs: []string{"=5432/tcp"}, wantErr: true},
+		{name: "missing port", specs: []string{"postgres"}, wantErr: true},
+		{name: "port out of range", specs: []string{"postgres=70000/tcp"}, wantErr: true},
+		{name: "unknown protocol", specs: []string{"postgres=5432/sctp"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseEntryPoints(tt.specs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseEntryPoints() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseEntryPoints() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package k8sclient
+
+import "testing"
+
+func TestRouteMatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		domain     string
+		pathPrefix string
+		headers    map[string]string
+		want       string
+	}{
+		{"host only", "example.com", "", nil, "Host(`example.com`)"},
+		{"root prefix", "example.com", "/", nil, "Host(`example.com`)"},
+		{"path prefix", "example.com", "/api", nil, "Host(`example.com`) && PathPrefix(`/api`)"},
+		{
+			"headers are sorted",
+			"example.com", "/api",
+			map[string]string{"X-Version": "2", "X-Canary": "true"},
+			"Host(`example.com`) && PathPrefix(`/api`) && Header(`X-Canary`, `true`) && Header(`X-Version`, `2`)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RouteMatch(tt.domain, tt.pathPrefix, tt.headers); got != tt.want {
+				t.Errorf("RouteMatch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
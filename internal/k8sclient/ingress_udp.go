@@ -0,0 +1,85 @@
+package k8sclient
+
+import (
+	"context"
+
+	assets "github.com/danielmichaels/tawny"
+	traefikv1alpha1 "github.com/traefik/traefik/v3/pkg/provider/kubernetes/crd/traefikio/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func (k K8sClient) CreateIngressUDP(
+	ctx context.Context,
+	name, namespace string,
+	opts ...IngressRouteUDPOption,
+) (*traefikv1alpha1.IngressRouteUDP, error) {
+	route := NewIngressRouteUDP(name, namespace, opts...)
+	res, err := k.tClient.TraefikV1alpha1().
+		IngressRouteUDPs(namespace).
+		Create(ctx, route, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (k K8sClient) DeleteIngressUDP(ctx context.Context, name, namespace string) error {
+	return k.tClient.TraefikV1alpha1().IngressRouteUDPs(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+type IngressRouteUDPOption func(*traefikv1alpha1.IngressRouteUDP)
+
+func WithIngressRouteUDPEntryPoint(entryPoint string) IngressRouteUDPOption {
+	return func(i *traefikv1alpha1.IngressRouteUDP) {
+		i.Spec.EntryPoints = append(i.Spec.EntryPoints, entryPoint)
+	}
+}
+
+// WithIngressRouteUDPService sends the datagrams arriving on the entrypoint
+// to a Service. UDP has no rule to match on, so an entrypoint serves a single
+// route.
+func WithIngressRouteUDPService(svcName, svcNamespace string, svcPort int32) IngressRouteUDPOption {
+	route := traefikv1alpha1.RouteUDP{
+		Services: []traefikv1alpha1.ServiceUDP{{
+			Name:      svcName,
+			Namespace: svcNamespace,
+			Port:      intstr.FromInt32(svcPort),
+		}},
+	}
+	return func(i *traefikv1alpha1.IngressRouteUDP) {
+		i.Spec.Routes = append(i.Spec.Routes, route)
+	}
+}
+
+// WithIngressRouteUDPTeam labels a UDP route as owned by teamID.
+func WithIngressRouteUDPTeam(teamID string) IngressRouteUDPOption {
+	return func(i *traefikv1alpha1.IngressRouteUDP) {
+		i.ObjectMeta.Labels[LabelTeam] = teamID
+	}
+}
+
+func NewIngressRouteUDP(
+	name, namespace string,
+	opts ...IngressRouteUDPOption,
+) *traefikv1alpha1.IngressRouteUDP {
+	labels := CreateLabels(WithName(name), WithComponent("ingressrouteudp"))
+	if namespace == assets.AppName {
+		labels = CreateLabels(WithName(name), WithComponent("ingressrouteudp"), WithCoreLabel(true))
+	}
+	i := &traefikv1alpha1.IngressRouteUDP{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "traefik.io/v1alpha1",
+			Kind:       "IngressRouteUDP",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
@@ -3,16 +3,75 @@ package k8sclient
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
 	cm "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
 	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	assets "github.com/danielmichaels/tawny"
 	"github.com/danielmichaels/tawny/internal/ptr"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/util/retry"
 )
 
+// Certificate types select the Let's Encrypt server which issues a
+// certificate. Staging certificates are untrusted but not rate limited.
+const (
+	CertificateTypeStaging    = "staging"
+	CertificateTypeProduction = "production"
+)
+
+// ACMEServer returns the ACME directory URL of a certificate type.
+func ACMEServer(certificateType string) (string, error) {
+	switch certificateType {
+	case CertificateTypeStaging:
+		return LetsEncryptStaging, nil
+	case CertificateTypeProduction:
+		return LetsEncryptProduction, nil
+	default:
+		return "", fmt.Errorf("unknown certificate type %q", certificateType)
+	}
+}
+
+// CertificateType returns the certificate type of an ACME directory URL, or
+// an empty string when the server is not Let's Encrypt.
+func CertificateType(server string) string {
+	switch server {
+	case LetsEncryptStaging:
+		return CertificateTypeStaging
+	case LetsEncryptProduction:
+		return CertificateTypeProduction
+	default:
+		return ""
+	}
+}
+
+// Solvers prove ownership of a domain to an ACME server.
+const (
+	SolverHTTP01 = "http01"
+	SolverDNS01  = "dns01"
+)
+
+// DefaultClusterIssuerName is the name of the shared issuer for a certificate
+// type. Domains which do not choose an issuer are issued by it.
+func DefaultClusterIssuerName(certificateType string) string {
+	return fmt.Sprintf("%s-letsencrypt-%s", assets.AppName, certificateType)
+}
+
+// ClusterIssuerReady reports whether cert-manager has registered the issuer
+// and it can issue certificates.
+func ClusterIssuerReady(i *cm.ClusterIssuer) bool {
+	for _, c := range i.Status.Conditions {
+		if c.Type == cm.IssuerConditionReady {
+			return c.Status == cmmeta.ConditionTrue
+		}
+	}
+	return false
+}
+
 func generatePrivKeyRef(name string) cmmeta.SecretKeySelector {
 	label := fmt.Sprintf("domain-cert-%s", name)
 	return cmmeta.SecretKeySelector{
@@ -21,6 +80,29 @@ func generatePrivKeyRef(name string) cmmeta.SecretKeySelector {
 	}
 }
 
+// ClusterIssuerSolvesDNS01 reports whether the issuer has a DNS-01 solver for
+// domain, which wildcard certificates require.
+func ClusterIssuerSolvesDNS01(i *cm.ClusterIssuer, domain string) bool {
+	if i.Spec.ACME == nil {
+		return false
+	}
+	name := strings.TrimPrefix(domain, "*.")
+	for _, s := range i.Spec.ACME.Solvers {
+		if s.DNS01 == nil {
+			continue
+		}
+		if s.Selector == nil || len(s.Selector.DNSZones) == 0 {
+			return true
+		}
+		for _, zone := range s.Selector.DNSZones {
+			if name == zone || strings.HasSuffix(name, "."+zone) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (k K8sClient) CreateClusterIssuer(
 	ctx context.Context,
 	opts ...ClusterIssuerOption,
@@ -35,6 +117,70 @@ func (k K8sClient) CreateClusterIssuer(
 	return res, nil
 }
 
+func (k K8sClient) GetClusterIssuer(ctx context.Context, name string) (*cm.ClusterIssuer, error) {
+	res, err := k.cmClient.CertmanagerV1().ClusterIssuers().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// clusterIssuerLabels are carried by every issuer managed by tawny, shared or
+// owned by a team.
+func clusterIssuerLabels() labels.Set {
+	component := CreateLabels(WithComponent("clusterissuer"), WithCoreLabel(true))["tawny.sh/component"]
+	return labels.Set{"tawny.sh/component": component}
+}
+
+// ManagedClusterIssuer reports whether an issuer with l is managed by tawny.
+// Other issuers in the cluster are never offered to teams.
+func ManagedClusterIssuer(l map[string]string) bool {
+	return labels.SelectorFromSet(clusterIssuerLabels()).Matches(labels.Set(l))
+}
+
+func (k K8sClient) DeleteClusterIssuer(ctx context.Context, name string) error {
+	return k.cmClient.CertmanagerV1().ClusterIssuers().Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+// ListClusterIssuers returns the issuers managed by tawny.
+func (k K8sClient) ListClusterIssuers(ctx context.Context) ([]cm.ClusterIssuer, error) {
+	selector := labels.SelectorFromSet(clusterIssuerLabels())
+	res, err := k.cmClient.CertmanagerV1().
+		ClusterIssuers().
+		List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	return res.Items, nil
+}
+
+// EnsureClusterIssuer updates the named issuer, creating it when it does not
+// exist yet.
+func (k K8sClient) EnsureClusterIssuer(
+	ctx context.Context,
+	name string,
+	opts ...ClusterIssuerOption,
+) (*cm.ClusterIssuer, error) {
+	res, err := k.UpdateClusterIssuer(ctx, name, opts...)
+	if apierrors.IsNotFound(err) {
+		return k.CreateClusterIssuer(ctx, append(opts, WithClusterIssuerCustomName(name))...)
+	}
+	return res, err
+}
+
+// EnsureDefaultClusterIssuers creates or updates the shared Let's Encrypt
+// issuer of each certificate type, registering email as the ACME contact.
+func (k K8sClient) EnsureDefaultClusterIssuers(ctx context.Context, email string) error {
+	for _, certificateType := range []string{CertificateTypeStaging, CertificateTypeProduction} {
+		name := DefaultClusterIssuerName(certificateType)
+		server, _ := ACMEServer(certificateType)
+		if _, err := k.EnsureClusterIssuer(ctx, name, WithClusterIssuerACMEHTTP01(email, server, name)); err != nil {
+			return fmt.Errorf("failed to ensure cluster issuer %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
 func (k K8sClient) UpdateClusterIssuer(
 	ctx context.Context,
 	name string,
@@ -62,49 +208,44 @@ func (k K8sClient) UpdateClusterIssuer(
 
 type ClusterIssuerOption func(*cm.ClusterIssuer)
 
+// WithClusterIssuerACMEDNS01 solves challenges for dnsZones, or every domain
+// when none are given, with a DNS-01 solver built by a DNSProvider. The ACME
+// account key is kept in a secret derived from name.
 func WithClusterIssuerACMEDNS01(
-	email, server, name, apikeyName, apikeyRef string,
+	email, server, name string,
+	solver *cmacme.ACMEChallengeSolverDNS01,
 	dnsZones []string,
 ) ClusterIssuerOption {
 	return func(i *cm.ClusterIssuer) {
 		if i.Spec.IssuerConfig.ACME == nil {
 			i.Spec.IssuerConfig.ACME = &cmacme.ACMEIssuer{
-				Email:  email,
-				Server: server,
-				PrivateKey: cmmeta.SecretKeySelector{
-					//Key:                  name,
-					LocalObjectReference: cmmeta.LocalObjectReference{Name: name},
-				},
+				Email:      email,
+				Server:     server,
+				PrivateKey: generatePrivKeyRef(name),
 			}
 		}
-		dns01Solver := cmacme.ACMEChallengeSolver{
-			Selector: &cmacme.CertificateDNSNameSelector{DNSZones: dnsZones},
-			DNS01: &cmacme.ACMEChallengeSolverDNS01{
-				Cloudflare: &cmacme.ACMEIssuerDNS01ProviderCloudflare{
-					Email: email,
-					APIToken: &cmmeta.SecretKeySelector{
-						Key:                  apikeyRef,
-						LocalObjectReference: cmmeta.LocalObjectReference{Name: apikeyName},
-					},
-				},
-			},
+		dns01Solver := cmacme.ACMEChallengeSolver{DNS01: solver}
+		if len(dnsZones) > 0 {
+			dns01Solver.Selector = &cmacme.CertificateDNSNameSelector{DNSZones: dnsZones}
 		}
 		i.Spec.IssuerConfig.ACME.Solvers =
 			append(i.Spec.IssuerConfig.ACME.Solvers, dns01Solver)
 	}
 }
 
-func WithClusterIssuerACMEHTTP01(email, name string) ClusterIssuerOption {
+// WithClusterIssuerACMEHTTP01 solves challenges for any domain by serving
+// them through traefik. The ACME account key is kept in a secret derived from
+// name.
+func WithClusterIssuerACMEHTTP01(email, server, name string) ClusterIssuerOption {
 	return func(i *cm.ClusterIssuer) {
 		if i.Spec.IssuerConfig.ACME == nil {
 			i.Spec.IssuerConfig.ACME = &cmacme.ACMEIssuer{
 				Email:      email,
-				Server:     LetsEncryptStaging, // todo replace with prod
+				Server:     server,
 				PrivateKey: generatePrivKeyRef(name),
 			}
 		}
 		http01Solver := cmacme.ACMEChallengeSolver{
-			Selector: &cmacme.CertificateDNSNameSelector{DNSNames: []string{name}},
 			HTTP01: &cmacme.ACMEChallengeSolverHTTP01{
 				Ingress: &cmacme.ACMEChallengeSolverHTTP01Ingress{
 					ServiceType:      v1.ServiceTypeClusterIP,
@@ -121,12 +262,27 @@ func WithClusterIssuerSelfSigned() ClusterIssuerOption {
 		i.Spec.IssuerConfig.SelfSigned = &cm.SelfSignedIssuer{}
 	}
 }
+
+// WithClusterIssuerCA signs certificates with the CA held in secretName.
+func WithClusterIssuerCA(secretName string) ClusterIssuerOption {
+	return func(i *cm.ClusterIssuer) {
+		i.Spec.IssuerConfig.CA = &cm.CAIssuer{SecretName: secretName}
+	}
+}
 func WithClusterIssuerCustomName(name string) ClusterIssuerOption {
 	return func(i *cm.ClusterIssuer) {
 		i.ObjectMeta.Name = name
 	}
 }
 
+// WithClusterIssuerTeam labels an issuer as owned by teamID. Issuers without
+// a team are shared by every team.
+func WithClusterIssuerTeam(teamID string) ClusterIssuerOption {
+	return func(i *cm.ClusterIssuer) {
+		i.ObjectMeta.Labels[LabelTeam] = teamID
+	}
+}
+
 func NewClusterIssuer(opts ...ClusterIssuerOption) *cm.ClusterIssuer {
 	c := &cm.ClusterIssuer{
 		TypeMeta: metav1.TypeMeta{
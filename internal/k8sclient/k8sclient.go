@@ -16,6 +16,7 @@ import (
 
 const (
 	LetsEncryptStaging      = "https://acme-staging-v02.api.letsencrypt.org/directory"
+	LetsEncryptProduction   = "https://acme-v02.api.letsencrypt.org/directory"
 	DefaultIngressRouteName = "ingressroute"
 )
 
@@ -6,6 +6,23 @@ import (
 	assets "github.com/danielmichaels/tawny"
 )
 
+// LabelTeam identifies the team which owns a resource.
+const LabelTeam = "tawny.sh/team"
+
+// OwnedByTeam reports whether a resource with labels is owned by teamID.
+func OwnedByTeam(labels map[string]string, teamID string) bool {
+	return teamID != "" && labels[LabelTeam] == teamID
+}
+
+// LabelDomain identifies the domain, by resource name, a resource routes
+// requests for.
+const LabelDomain = "tawny.sh/domain"
+
+// ManagedSelector selects the resources created by tawny, core or not.
+func ManagedSelector() string {
+	return fmt.Sprintf("tawny.sh/managed-by in (%s,%s-core)", assets.AppName, assets.AppName)
+}
+
 type LabelOpts struct {
 	Extra     map[string]string
 	Name      string
@@ -35,6 +52,7 @@ func WithCustomLabel(key, value string) LabelOpt {
 		args.Extra[key] = value
 	}
 }
+
 func WithCoreLabel(core bool) LabelOpt {
 	return func(l *LabelOpts) {
 		l.Core = core
@@ -0,0 +1,24 @@
+package k8sclient
+
+import "testing"
+
+func TestOwnedByTeam(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels map[string]string
+		team   string
+		want   bool
+	}{
+		{"owned by the team", map[string]string{LabelTeam: "team_1"}, "team_1", true},
+		{"owned by another team", map[string]string{LabelTeam: "team_2"}, "team_1", false},
+		{"unlabelled", map[string]string{}, "team_1", false},
+		{"no team", map[string]string{LabelTeam: ""}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := OwnedByTeam(tt.labels, tt.team); got != tt.want {
+				t.Errorf("OwnedByTeam() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package k8sclient
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+func (k K8sClient) GetSecret(ctx context.Context, name, namespace string) (*v1.Secret, error) {
+	res, err := k.Client.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ListTLSSecrets returns the kubernetes.io/tls Secrets in namespace. Those
+// written by cert-manager carry none of tawny's labels.
+func (k K8sClient) ListTLSSecrets(ctx context.Context, namespace string) ([]v1.Secret, error) {
+	res, err := k.Client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: "type=" + string(v1.SecretTypeTLS),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.Items, nil
+}
+
+func (k K8sClient) DeleteSecret(ctx context.Context, name, namespace string) error {
+	return k.Client.CoreV1().Secrets(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+// ApplySecret replaces the type, labels and data of the named Secret,
+// creating it when it does not exist yet.
+func (k K8sClient) ApplySecret(
+	ctx context.Context,
+	name, namespace string,
+	opts ...SecretOption,
+) (*v1.Secret, error) {
+	desired := NewSecret(name, namespace, opts...)
+	var result *v1.Secret
+	if retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		res, err := k.Client.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			result, err = k.Client.CoreV1().Secrets(namespace).Create(ctx, desired, metav1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		res.Type = desired.Type
+		res.Labels = desired.Labels
+		res.Data = desired.Data
+		result, err = k.Client.CoreV1().Secrets(namespace).Update(ctx, res, metav1.UpdateOptions{})
+		return err
+	}); retryErr != nil {
+		return nil, retryErr
+	}
+	return result, nil
+}
+
+type SecretOption func(*v1.Secret)
+
+// WithSecretData sets the data of a Secret from string values.
+func WithSecretData(data map[string]string) SecretOption {
+	return func(s *v1.Secret) {
+		for k, v := range data {
+			s.Data[k] = []byte(v)
+		}
+	}
+}
+
+// WithSecretTLS makes a Secret a kubernetes.io/tls Secret holding a PEM
+// certificate chain and its private key.
+func WithSecretTLS(chain, key []byte) SecretOption {
+	return func(s *v1.Secret) {
+		s.Type = v1.SecretTypeTLS
+		s.Data[v1.TLSCertKey] = chain
+		s.Data[v1.TLSPrivateKeyKey] = key
+	}
+}
+
+// WithSecretTeam labels a Secret as owned by teamID.
+func WithSecretTeam(teamID string) SecretOption {
+	return func(s *v1.Secret) {
+		s.ObjectMeta.Labels[LabelTeam] = teamID
+	}
+}
+
+func NewSecret(name, namespace string, opts ...SecretOption) *v1.Secret {
+	s := &v1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    CreateLabels(WithName(name), WithComponent("secret")),
+		},
+		Type: v1.SecretTypeOpaque,
+		Data: map[string][]byte{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
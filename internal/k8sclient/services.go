@@ -6,10 +6,13 @@ import (
 
 	assets "github.com/danielmichaels/tawny"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
 )
 
-func serviceNameGenerator(name string) string {
+// ServiceName returns the name of the Service exposing an app.
+func ServiceName(name string) string {
 	return fmt.Sprintf(DefaultServiceName, assets.AppName, name)
 }
 
@@ -27,3 +30,126 @@ func (k K8sClient) GetService(ctx context.Context, name, namespace string) (*v1.
 	}
 	return res, nil
 }
+
+// GetTeamService returns the named Service when it is labelled as owned by
+// teamID. Apps share a namespace, so the name alone does not tie a Service to
+// a team; those of other teams are reported as not found.
+func (k K8sClient) GetTeamService(ctx context.Context, name, namespace, teamID string) (*v1.Service, error) {
+	res, err := k.GetService(ctx, name, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if !OwnedByTeam(res.Labels, teamID) {
+		return nil, apierrors.NewNotFound(v1.Resource("services"), name)
+	}
+	return res, nil
+}
+
+func (k K8sClient) CreateService(
+	ctx context.Context,
+	name, namespace string,
+	opts ...ServiceOption,
+) (*v1.Service, error) {
+	desired := NewService(name, namespace, opts...)
+	res, err := k.Client.CoreV1().Services(namespace).Create(ctx, desired, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (k K8sClient) DeleteService(ctx context.Context, name, namespace string) error {
+	return k.Client.CoreV1().Services(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+// SetServiceSelector points the named Service at the pods matching selector,
+// such as those of a promoted release.
+func (k K8sClient) SetServiceSelector(
+	ctx context.Context,
+	name, namespace string,
+	selector map[string]string,
+) (*v1.Service, error) {
+	var result *v1.Service
+	if retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		res, err := k.Client.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		res.Spec.Selector = selector
+		result, err = k.Client.CoreV1().Services(namespace).Update(ctx, res, metav1.UpdateOptions{})
+		return err
+	}); retryErr != nil {
+		return nil, retryErr
+	}
+	return result, nil
+}
+
+type ServiceOption func(*v1.Service)
+
+// WithServicePorts exposes the ports of another Service, so a release serves
+// on the same ports as the running version.
+func WithServicePorts(ports []v1.ServicePort) ServiceOption {
+	return func(s *v1.Service) {
+		for _, p := range ports {
+			s.Spec.Ports = append(s.Spec.Ports, v1.ServicePort{
+				Name:       p.Name,
+				Protocol:   p.Protocol,
+				Port:       p.Port,
+				TargetPort: p.TargetPort,
+			})
+		}
+	}
+}
+
+// WithServiceSelector sends the requests of a Service to the pods matching
+// selector.
+func WithServiceSelector(selector map[string]string) ServiceOption {
+	return func(s *v1.Service) {
+		s.Spec.Selector = selector
+	}
+}
+
+// WithServiceTeam labels a Service as owned by teamID.
+func WithServiceTeam(teamID string) ServiceOption {
+	return func(s *v1.Service) {
+		s.ObjectMeta.Labels[LabelTeam] = teamID
+	}
+}
+
+func NewService(name, namespace string, opts ...ServiceOption) *v1.Service {
+	l := CreateLabels(WithName(name), WithComponent("service"))
+	if namespace == assets.AppName {
+		l = CreateLabels(WithName(name), WithComponent("service"), WithCoreLabel(true))
+	}
+	s := &v1.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Service",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    l,
+		},
+		Spec: v1.ServiceSpec{Type: v1.ServiceTypeClusterIP},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// ServiceAddresses returns the external addresses of a Service: the IPs or
+// hostnames of its load balancer followed by its external IPs.
+func ServiceAddresses(svc *v1.Service) []string {
+	var addrs []string
+	for _, ing := range svc.Status.LoadBalancer.Ingress {
+		if ing.IP != "" {
+			addrs = append(addrs, ing.IP)
+		}
+		if ing.Hostname != "" {
+			addrs = append(addrs, ing.Hostname)
+		}
+	}
+	return append(addrs, svc.Spec.ExternalIPs...)
+}
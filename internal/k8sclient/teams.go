@@ -0,0 +1,75 @@
+package k8sclient
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// teamResources are the custom resources created on behalf of a team, deleted
+// before the resources they depend on.
+var teamResources = []string{
+	"traefik.io/v1alpha1/ingressroutes",
+	"traefik.io/v1alpha1/ingressroutetcps",
+	"traefik.io/v1alpha1/ingressrouteudps",
+	"traefik.io/v1alpha1/traefikservices",
+	"traefik.io/v1alpha1/middlewares",
+	"cert-manager.io/v1/certificates",
+	"cert-manager.io/v1/clusterissuers",
+}
+
+// DeleteTeamResources removes every resource labelled as owned by teamID in
+// all namespaces. Resources which have already gone are ignored so the call
+// can be retried after a partial failure.
+func (k K8sClient) DeleteTeamResources(ctx context.Context, teamID string) error {
+	selector := metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{LabelTeam: teamID}).String(),
+	}
+	for _, r := range teamResources {
+		ri := k.DynamicClient.Resource(NewGVR(r).GVR())
+		list, err := ri.Namespace(metav1.NamespaceAll).List(ctx, selector)
+		if err != nil {
+			return fmt.Errorf("failed to list %s for team %q: %w", r, teamID, err)
+		}
+		for _, item := range list.Items {
+			err := ri.Namespace(item.GetNamespace()).Delete(ctx, item.GetName(), metav1.DeleteOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return fmt.Errorf("failed to delete %s %s/%s: %w", r, item.GetNamespace(), item.GetName(), err)
+			}
+		}
+	}
+	services, err := k.Client.CoreV1().Services(metav1.NamespaceAll).List(ctx, selector)
+	if err != nil {
+		return fmt.Errorf("failed to list services for team %q: %w", teamID, err)
+	}
+	for _, svc := range services.Items {
+		err := k.Client.CoreV1().Services(svc.Namespace).Delete(ctx, svc.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete service %s/%s: %w", svc.Namespace, svc.Name, err)
+		}
+	}
+	deployments, err := k.Client.AppsV1().Deployments(metav1.NamespaceAll).List(ctx, selector)
+	if err != nil {
+		return fmt.Errorf("failed to list deployments for team %q: %w", teamID, err)
+	}
+	for _, d := range deployments.Items {
+		err := k.Client.AppsV1().Deployments(d.Namespace).Delete(ctx, d.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete deployment %s/%s: %w", d.Namespace, d.Name, err)
+		}
+	}
+	secrets, err := k.Client.CoreV1().Secrets(metav1.NamespaceAll).List(ctx, selector)
+	if err != nil {
+		return fmt.Errorf("failed to list secrets for team %q: %w", teamID, err)
+	}
+	for _, s := range secrets.Items {
+		err := k.Client.CoreV1().Secrets(s.Namespace).Delete(ctx, s.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete secret %s/%s: %w", s.Namespace, s.Name, err)
+		}
+	}
+	return nil
+}
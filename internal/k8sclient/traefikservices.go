@@ -0,0 +1,124 @@
+package k8sclient
+
+import (
+	"context"
+
+	assets "github.com/danielmichaels/tawny"
+	traefikv1alpha1 "github.com/traefik/traefik/v3/pkg/provider/kubernetes/crd/traefikio/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/util/retry"
+)
+
+// ApplyTraefikService replaces the labels and spec of the named
+// TraefikService, creating it when it does not exist yet.
+func (k K8sClient) ApplyTraefikService(
+	ctx context.Context,
+	name, namespace string,
+	opts ...TraefikServiceOption,
+) (*traefikv1alpha1.TraefikService, error) {
+	desired := NewTraefikService(name, namespace, opts...)
+	var result *traefikv1alpha1.TraefikService
+	if retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		res, err := k.tClient.TraefikV1alpha1().TraefikServices(namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			result, err = k.tClient.TraefikV1alpha1().
+				TraefikServices(namespace).
+				Create(ctx, desired, metav1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		res.Labels = desired.Labels
+		res.Spec = desired.Spec
+		result, err = k.tClient.TraefikV1alpha1().TraefikServices(namespace).Update(ctx, res, metav1.UpdateOptions{})
+		return err
+	}); retryErr != nil {
+		return nil, retryErr
+	}
+	return result, nil
+}
+
+// ListTraefikServices returns the TraefikServices in namespace carrying
+// every label in match.
+func (k K8sClient) ListTraefikServices(
+	ctx context.Context,
+	namespace string,
+	match map[string]string,
+) ([]traefikv1alpha1.TraefikService, error) {
+	res, err := k.tClient.TraefikV1alpha1().
+		TraefikServices(namespace).
+		List(ctx, metav1.ListOptions{LabelSelector: labels.SelectorFromSet(match).String()})
+	if err != nil {
+		return nil, err
+	}
+	return res.Items, nil
+}
+
+func (k K8sClient) DeleteTraefikService(ctx context.Context, name, namespace string) error {
+	return k.tClient.TraefikV1alpha1().TraefikServices(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+type TraefikServiceOption func(*traefikv1alpha1.TraefikService)
+
+// WithTraefikServiceWeighted adds a Service to the weighted round robin of the
+// TraefikService. Requests are shared in proportion to the weights.
+func WithTraefikServiceWeighted(svcName, svcNamespace string, svcPort int32, weight int) TraefikServiceOption {
+	return func(t *traefikv1alpha1.TraefikService) {
+		if t.Spec.Weighted == nil {
+			t.Spec.Weighted = &traefikv1alpha1.WeightedRoundRobin{}
+		}
+		t.Spec.Weighted.Services = append(t.Spec.Weighted.Services, traefikv1alpha1.Service{
+			LoadBalancerSpec: traefikv1alpha1.LoadBalancerSpec{
+				Name:      svcName,
+				Kind:      "Service",
+				Namespace: svcNamespace,
+				Port:      intstr.FromInt32(svcPort),
+				Weight:    &weight,
+			},
+		})
+	}
+}
+
+// WithTraefikServiceTeam labels a TraefikService as owned by teamID.
+func WithTraefikServiceTeam(teamID string) TraefikServiceOption {
+	return func(t *traefikv1alpha1.TraefikService) {
+		t.ObjectMeta.Labels[LabelTeam] = teamID
+	}
+}
+
+// WithTraefikServiceDomain labels a TraefikService as routing requests for
+// the domain resources named name.
+func WithTraefikServiceDomain(name string) TraefikServiceOption {
+	return func(t *traefikv1alpha1.TraefikService) {
+		t.ObjectMeta.Labels[LabelDomain] = name
+	}
+}
+
+func NewTraefikService(
+	name, namespace string,
+	opts ...TraefikServiceOption,
+) *traefikv1alpha1.TraefikService {
+	l := CreateLabels(WithName(name), WithComponent("traefikservice"))
+	if namespace == assets.AppName {
+		l = CreateLabels(WithName(name), WithComponent("traefikservice"), WithCoreLabel(true))
+	}
+	t := &traefikv1alpha1.TraefikService{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "traefik.io/v1alpha1",
+			Kind:       "TraefikService",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    l,
+		},
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
@@ -0,0 +1,114 @@
+// Package mailer delivers transactional email. The driver is chosen by
+// configuration so that development installs can log messages instead of
+// requiring an SMTP relay.
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielmichaels/tawny/internal/logger"
+)
+
+const (
+	DriverLog  = "log"
+	DriverSMTP = "smtp"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends a Message.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type Config struct {
+	Driver   string
+	From     string
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
+// New returns the Mailer for cfg.Driver.
+func New(cfg Config, logger *logger.Logger) (Mailer, error) {
+	switch cfg.Driver {
+	case "", DriverLog:
+		return &LogMailer{logger: logger}, nil
+	case DriverSMTP:
+		if cfg.Host == "" || cfg.From == "" {
+			return nil, fmt.Errorf("smtp mailer requires a host and from address")
+		}
+		return &SMTPMailer{cfg: cfg}, nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
+
+// LogMailer writes messages to the log. It is intended for development only
+// as message bodies contain secrets such as password reset links.
+type LogMailer struct {
+	logger *logger.Logger
+}
+
+func (m *LogMailer) Send(_ context.Context, msg Message) error {
+	m.logger.Info().
+		Str("to", msg.To).
+		Str("subject", msg.Subject).
+		Str("body", msg.Body).
+		Msg("mail not delivered, log driver in use")
+	return nil
+}
+
+// SMTPMailer delivers messages through an SMTP relay using STARTTLS when the
+// server supports it.
+type SMTPMailer struct {
+	cfg Config
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if strings.ContainsAny(msg.To+msg.Subject, "\r\n") {
+		return fmt.Errorf("mail headers must not contain line breaks")
+	}
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	var a smtp.Auth
+	if m.cfg.Username != "" {
+		a = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- smtp.SendMail(addr, a, m.cfg.From, []string{msg.To}, m.build(msg))
+	}()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errc:
+		if err != nil {
+			return fmt.Errorf("failed to send mail to %q: %w", msg.To, err)
+		}
+		return nil
+	}
+}
+
+func (m *SMTPMailer) build(msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", m.cfg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,151 @@
+// Package notify delivers operational notifications, such as expiring
+// certificates, to the channels configured for the installation.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/danielmichaels/tawny/internal/logger"
+	"github.com/danielmichaels/tawny/internal/mailer"
+)
+
+// Kinds of notification.
+const (
+	KindCertificateExpiring      = "certificate.expiring"
+	KindCertificateRenewalFailed = "certificate.renewal_failed"
+)
+
+// Notification is a single event worth telling an operator about.
+type Notification struct {
+	Kind    string            `json:"kind"`
+	Summary string            `json:"summary"`
+	Team    string            `json:"team,omitempty"`
+	Details map[string]string `json:"details,omitempty"`
+	Time    time.Time         `json:"time"`
+}
+
+// Notifier delivers a Notification.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+type Config struct {
+	// WebhookURL receives notifications as JSON when set.
+	WebhookURL string
+	// Email addresses notifications are mailed to.
+	Email []string
+}
+
+// New returns a Notifier delivering to every channel set in cfg. Without
+// any, notifications are logged.
+func New(cfg Config, mail mailer.Mailer, logger *logger.Logger) Notifier {
+	var all Multi
+	if cfg.WebhookURL != "" {
+		all = append(all, &Webhook{URL: cfg.WebhookURL})
+	}
+	if len(cfg.Email) > 0 {
+		all = append(all, &Email{Mailer: mail, To: cfg.Email})
+	}
+	if len(all) == 0 {
+		return &Log{logger: logger}
+	}
+	return all
+}
+
+// Multi delivers to several notifiers, attempting all of them.
+type Multi []Notifier
+
+func (m Multi) Notify(ctx context.Context, n Notification) error {
+	var errs []error
+	for _, notifier := range m {
+		if err := notifier.Notify(ctx, n); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Log writes notifications to the log.
+type Log struct {
+	logger *logger.Logger
+}
+
+func (l *Log) Notify(_ context.Context, n Notification) error {
+	ev := l.logger.Warn().Str("kind", n.Kind).Str("team", n.Team)
+	for k, v := range n.Details {
+		ev = ev.Str(k, v)
+	}
+	ev.Msg(n.Summary)
+	return nil
+}
+
+// Webhook posts notifications as JSON to URL.
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+func (w *Webhook) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := w.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post notification: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("notification webhook responded %s", res.Status)
+	}
+	return nil
+}
+
+// Email mails notifications to each address in To.
+type Email struct {
+	Mailer mailer.Mailer
+	To     []string
+}
+
+func (e *Email) Notify(ctx context.Context, n Notification) error {
+	var b strings.Builder
+	b.WriteString(n.Summary)
+	b.WriteString("\n\n")
+	keys := make([]string, 0, len(n.Details))
+	for k := range n.Details {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s: %s\n", k, n.Details[k])
+	}
+	var errs []error
+	for _, to := range e.To {
+		err := e.Mailer.Send(ctx, mailer.Message{
+			To:      to,
+			Subject: "[tawny] " + n.Summary,
+			Body:    b.String(),
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,183 @@
+// Package pagination pages through list results either by page number or by
+// an opaque cursor, and describes the page returned with totals and links to
+// its neighbours. It is shared by every list endpoint so that they behave the
+// same way.
+package pagination
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	DefaultSize = 20
+	MaxSize     = 100
+
+	// Query parameters understood by list endpoints.
+	ParamSize   = "page_size"
+	ParamNumber = "page_number"
+	ParamCursor = "cursor"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page is a validated position within a list.
+type Page struct {
+	// Size is the maximum number of results on the page.
+	Size int
+	// Number is the page, starting at 1.
+	Number int
+	// Anchor is an optional upper bound on row IDs fixed when paging
+	// started, so that rows added since do not shift later pages. Zero when
+	// the list is not anchored.
+	Anchor int64
+	// cursor is set when the page was requested by cursor, in which case
+	// links to other pages are cursors too.
+	cursor bool
+}
+
+// cursor is the decoded form of an opaque cursor. Clients must not rely on
+// its contents.
+type cursor struct {
+	Number int   `json:"n"`
+	Size   int   `json:"s"`
+	Anchor int64 `json:"a,omitempty"`
+}
+
+// New validates the paging parameters of a request. A cursor takes precedence
+// over the page size and number. Out of range sizes and numbers are clamped.
+func New(size, number int, c *string) (Page, error) {
+	if c != nil && *c != "" {
+		return decode(*c)
+	}
+	return Page{Size: clampSize(size), Number: max(number, 1)}, nil
+}
+
+// Limit is the SQL LIMIT of the page.
+func (p Page) Limit() int32 {
+	return int32(p.Size)
+}
+
+// Offset is the SQL OFFSET of the page.
+func (p Page) Offset() int32 {
+	return int32((p.Number - 1) * p.Size)
+}
+
+// Anchored returns p bounded by anchor unless it already is. Call it with the
+// highest row ID when listing the first page of an anchored list.
+func (p Page) Anchored(anchor int64) Page {
+	if p.Anchor == 0 {
+		p.Anchor = anchor
+	}
+	return p
+}
+
+// Metadata describes a page of results.
+type Metadata struct {
+	Total   int
+	Size    int
+	Current int
+	First   int
+	Last    int
+	// Next and Prev are relative links to the neighbouring pages, empty at
+	// either end of the list.
+	Next string
+	Prev string
+	// NextCursor and PrevCursor are the cursors of the neighbouring pages.
+	NextCursor string
+	PrevCursor string
+}
+
+// Metadata describes p within a list of total results. Links are built from
+// the request URL stored by Middleware and omitted when it is absent.
+func (p Page) Metadata(ctx context.Context, total int) Metadata {
+	last := max((total+p.Size-1)/p.Size, 1)
+	m := Metadata{
+		Total:   total,
+		Size:    p.Size,
+		Current: p.Number,
+		First:   1,
+		Last:    last,
+	}
+	u, _ := ctx.Value(ctxKeyURL).(*url.URL)
+	if p.Number < last {
+		next := p
+		next.Number++
+		m.NextCursor = next.encode()
+		m.Next = next.link(u)
+	}
+	if p.Number > 1 {
+		prev := p
+		prev.Number = min(p.Number-1, last)
+		m.PrevCursor = prev.encode()
+		m.Prev = prev.link(u)
+	}
+	return m
+}
+
+func (p Page) encode() string {
+	b, _ := json.Marshal(cursor{Number: p.Number, Size: p.Size, Anchor: p.Anchor})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decode(s string) (Page, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Page{}, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return Page{}, ErrInvalidCursor
+	}
+	if c.Number < 1 || c.Size < 1 || c.Size > MaxSize || c.Anchor < 0 {
+		return Page{}, ErrInvalidCursor
+	}
+	return Page{Size: c.Size, Number: c.Number, Anchor: c.Anchor, cursor: true}, nil
+}
+
+// link returns the path and query of the request in u moved to page p.
+func (p Page) link(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	q := u.Query()
+	if p.cursor || p.Anchor != 0 {
+		q.Del(ParamNumber)
+		q.Del(ParamSize)
+		q.Set(ParamCursor, p.encode())
+	} else {
+		q.Del(ParamCursor)
+		q.Set(ParamNumber, strconv.Itoa(p.Number))
+		q.Set(ParamSize, strconv.Itoa(p.Size))
+	}
+	return (&url.URL{Path: u.Path, RawQuery: q.Encode()}).String()
+}
+
+func clampSize(size int) int {
+	switch {
+	case size < 1:
+		return DefaultSize
+	case size > MaxSize:
+		return MaxSize
+	default:
+		return size
+	}
+}
+
+type ctxKey int
+
+const ctxKeyURL ctxKey = iota
+
+// Middleware stores the request URL on the context so that list endpoints
+// can link to other pages.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u := *r.URL
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKeyURL, &u)))
+	})
+}
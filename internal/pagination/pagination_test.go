@@ -0,0 +1,169 @@
+package pagination
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func ptr(s string) *string { return &s }
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int
+		number     int
+		cursor     *string
+		wantSize   int
+		wantNumber int
+		wantLimit  int32
+		wantOffset int32
+		wantErr    error
+	}{
+		{"defaults", 0, 0, nil, DefaultSize, 1, DefaultSize, 0, nil},
+		{"second page", 10, 2, nil, 10, 2, 10, 10, nil},
+		{"size clamped", 500, 3, nil, MaxSize, 3, MaxSize, 2 * MaxSize, nil},
+		{"negative number", 5, -4, nil, 5, 1, 5, 0, nil},
+		{"empty cursor ignored", 5, 2, ptr(""), 5, 2, 5, 5, nil},
+		{"cursor wins", 5, 2, ptr(Page{Size: 7, Number: 3}.encode()), 7, 3, 7, 14, nil},
+		{"not base64", 5, 1, ptr("%%%"), 0, 0, 0, 0, ErrInvalidCursor},
+		{"not json", 5, 1, ptr("bm9wZQ"), 0, 0, 0, 0, ErrInvalidCursor},
+		{"oversized cursor", 5, 1, ptr(Page{Size: MaxSize + 1, Number: 1}.encode()), 0, 0, 0, 0, ErrInvalidCursor},
+		{"zero page cursor", 5, 1, ptr(Page{Size: 5}.encode()), 0, 0, 0, 0, ErrInvalidCursor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(tt.size, tt.number, tt.cursor)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if p.Size != tt.wantSize || p.Number != tt.wantNumber {
+				t.Errorf("page = %d/%d, want %d/%d", p.Number, p.Size, tt.wantNumber, tt.wantSize)
+			}
+			if p.Limit() != tt.wantLimit || p.Offset() != tt.wantOffset {
+				t.Errorf("limit/offset = %d/%d, want %d/%d", p.Limit(), p.Offset(), tt.wantLimit, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	want := Page{Size: 25, Number: 4, Anchor: 991, cursor: true}
+	got, err := New(0, 0, ptr(want.encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestAnchored(t *testing.T) {
+	p := Page{Size: 10, Number: 1}.Anchored(42)
+	if p.Anchor != 42 {
+		t.Errorf("anchor = %d, want 42", p.Anchor)
+	}
+	if p = p.Anchored(99); p.Anchor != 42 {
+		t.Errorf("anchor moved to %d", p.Anchor)
+	}
+}
+
+// withURL returns a context holding target as stored by Middleware.
+func withURL(t *testing.T, target string) context.Context {
+	t.Helper()
+	var ctx context.Context
+	h := Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		ctx = r.Context()
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	return ctx
+}
+
+func TestMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		page     Page
+		total    int
+		wantLast int
+		wantNext string
+		wantPrev string
+	}{
+		{
+			name:     "empty list",
+			target:   "/users",
+			page:     Page{Size: 10, Number: 1},
+			total:    0,
+			wantLast: 1,
+		},
+		{
+			name:     "exact fit",
+			target:   "/users",
+			page:     Page{Size: 10, Number: 1},
+			total:    10,
+			wantLast: 1,
+		},
+		{
+			name:     "first of three",
+			target:   "/users?page_size=10",
+			page:     Page{Size: 10, Number: 1},
+			total:    21,
+			wantLast: 3,
+			wantNext: "/users?page_number=2&page_size=10",
+		},
+		{
+			name:     "middle keeps filters",
+			target:   "/teams/team_abcdefg/audit?resource_type=domain&page_number=2&page_size=10",
+			page:     Page{Size: 10, Number: 2},
+			total:    30,
+			wantLast: 3,
+			wantNext: "/teams/team_abcdefg/audit?page_number=3&page_size=10&resource_type=domain",
+			wantPrev: "/teams/team_abcdefg/audit?page_number=1&page_size=10&resource_type=domain",
+		},
+		{
+			name:     "past the end",
+			target:   "/apps/a/domains?page_number=9",
+			page:     Page{Size: 5, Number: 9},
+			total:    12,
+			wantLast: 3,
+			wantPrev: "/apps/a/domains?page_number=3&page_size=5",
+		},
+		{
+			name:     "anchored links use cursors",
+			target:   "/audit?page_number=1",
+			page:     Page{Size: 5, Number: 1, Anchor: 77},
+			total:    12,
+			wantLast: 3,
+			wantNext: "/audit?cursor=" + Page{Size: 5, Number: 2, Anchor: 77}.encode(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := tt.page.Metadata(withURL(t, tt.target), tt.total)
+			if m.Total != tt.total || m.First != 1 || m.Last != tt.wantLast || m.Current != tt.page.Number {
+				t.Errorf("metadata = %+v", m)
+			}
+			if m.Next != tt.wantNext {
+				t.Errorf("next = %q, want %q", m.Next, tt.wantNext)
+			}
+			if m.Prev != tt.wantPrev {
+				t.Errorf("prev = %q, want %q", m.Prev, tt.wantPrev)
+			}
+			if (m.Next == "") != (m.NextCursor == "") || (m.Prev == "") != (m.PrevCursor == "") {
+				t.Errorf("cursors %q/%q do not match links", m.NextCursor, m.PrevCursor)
+			}
+		})
+	}
+}
+
+func TestMetadataWithoutURL(t *testing.T) {
+	m := Page{Size: 10, Number: 1}.Metadata(context.Background(), 50)
+	if m.Next != "" || m.NextCursor == "" {
+		t.Errorf("next = %q, cursor = %q", m.Next, m.NextCursor)
+	}
+}
@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Memory is a Store for a single replica.
+type Memory struct {
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	failures map[string]*failures
+	now      func() time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket will have refilled and can be forgotten.
+	full time.Time
+}
+
+type failures struct {
+	count       int
+	lockedUntil time.Time
+	// expires is when the failures are forgotten. Each failure extends it by
+	// the window so that backoff carries over between lockouts.
+	expires time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		buckets:  make(map[string]*bucket),
+		failures: make(map[string]*failures),
+		now:      time.Now,
+	}
+}
+
+func (m *Memory) Take(_ context.Context, key string, limit Limit) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		m.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.updated).Seconds()*limit.Rate)
+	b.updated = now
+	if b.tokens < 1 {
+		return waitFor(b.tokens, limit.Rate), nil
+	}
+	b.tokens--
+	b.full = now.Add(refillIn(b.tokens, limit))
+	return 0, nil
+}
+
+func (m *Memory) Fail(_ context.Context, key string, window time.Duration) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	f, ok := m.failures[key]
+	if !ok || !now.Before(f.expires) {
+		f = &failures{}
+		m.failures[key] = f
+	}
+	f.count++
+	if exp := now.Add(window); exp.After(f.expires) {
+		f.expires = exp
+	}
+	return f.count, nil
+}
+
+func (m *Memory) Lock(_ context.Context, key string, d time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.failures[key]
+	if !ok {
+		f = &failures{}
+		m.failures[key] = f
+	}
+	until := m.now().Add(d)
+	if until.After(f.lockedUntil) {
+		f.lockedUntil = until
+	}
+	if until.After(f.expires) {
+		f.expires = until
+	}
+	return nil
+}
+
+func (m *Memory) Locked(_ context.Context, key string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.failures[key]
+	if !ok {
+		return 0, nil
+	}
+	return max(f.lockedUntil.Sub(m.now()), 0), nil
+}
+
+func (m *Memory) Sweep(_ context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	for k, b := range m.buckets {
+		if !now.Before(b.full) {
+			delete(m.buckets, k)
+		}
+	}
+	for k, f := range m.failures {
+		if !now.Before(f.expires) {
+			delete(m.failures, k)
+		}
+	}
+	return nil
+}
+
+// waitFor returns how long until a bucket holding tokens has one whole token.
+func waitFor(tokens, rate float64) time.Duration {
+	if rate <= 0 {
+		return time.Hour
+	}
+	return time.Duration((1 - tokens) / rate * float64(time.Second))
+}
+
+// refillIn returns how long until a bucket holding tokens is full.
+func refillIn(tokens float64, limit Limit) time.Duration {
+	if limit.Rate <= 0 {
+		return time.Hour
+	}
+	return time.Duration((float64(limit.Burst) - tokens) / limit.Rate * float64(time.Second))
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/danielmichaels/tawny/internal/store"
+	"github.com/jackc/pgx/v5"
+)
+
+// Postgres is a Store shared by every replica using the database.
+type Postgres struct {
+	DB *store.Queries
+}
+
+func (p *Postgres) Take(ctx context.Context, key string, limit Limit) (time.Duration, error) {
+	_, err := p.DB.TakeRateLimitToken(ctx, store.TakeRateLimitTokenParams{
+		Key:   key,
+		Burst: float64(limit.Burst),
+		Rate:  limit.Rate,
+	})
+	if err == nil {
+		return 0, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, err
+	}
+	secs, err := p.DB.RateLimitWait(ctx, store.RateLimitWaitParams{Rate: limit.Rate, Key: key})
+	if err != nil {
+		return 0, err
+	}
+	// The bucket may have refilled between the queries, but the client was
+	// still refused so must wait for something.
+	return max(seconds(secs), time.Second), nil
+}
+
+func (p *Postgres) Fail(ctx context.Context, key string, window time.Duration) (int, error) {
+	n, err := p.DB.CreateAuthFailure(ctx, store.CreateAuthFailureParams{
+		Key:           key,
+		WindowSeconds: window.Seconds(),
+	})
+	return int(n), err
+}
+
+func (p *Postgres) Lock(ctx context.Context, key string, d time.Duration) error {
+	return p.DB.LockAuthFailures(ctx, store.LockAuthFailuresParams{
+		Key:            key,
+		LockoutSeconds: d.Seconds(),
+	})
+}
+
+func (p *Postgres) Locked(ctx context.Context, key string) (time.Duration, error) {
+	secs, err := p.DB.GetAuthLockout(ctx, key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return seconds(secs), nil
+}
+
+func (p *Postgres) Sweep(ctx context.Context) error {
+	return p.DB.DeleteExpiredRateLimits(ctx)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
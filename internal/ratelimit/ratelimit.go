@@ -0,0 +1,226 @@
+// Package ratelimit throttles requests with token buckets per client IP and
+// per credential, and locks out clients which repeatedly fail to
+// authenticate. State is kept in a Store so that replicas can share it.
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/danielmichaels/tawny/internal/logger"
+	"github.com/danielmichaels/tawny/internal/store"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	StoreMemory   = "memory"
+	StorePostgres = "postgres"
+)
+
+// sweepInterval is how often expired state is removed from the store.
+const sweepInterval = 10 * time.Minute
+
+// Limit is a token bucket refilling at Rate tokens per second up to Burst.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l Limit) enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// Lockout locks a client out once it fails to authenticate Threshold times
+// within Window. Each further failure doubles the lockout, starting at Base
+// and capped at Max.
+type Lockout struct {
+	Threshold int
+	Window    time.Duration
+	Base      time.Duration
+	Max       time.Duration
+}
+
+// Duration returns how long to lock a client out after failures failed
+// attempts, zero if it is below the threshold.
+func (l Lockout) Duration(failures int) time.Duration {
+	if l.Threshold <= 0 || failures < l.Threshold {
+		return 0
+	}
+	shift := failures - l.Threshold
+	if shift > 30 {
+		return l.Max
+	}
+	d := l.Base << shift
+	if d > l.Max || d <= 0 {
+		return l.Max
+	}
+	return d
+}
+
+// New returns a Limiter keeping state in the named store.
+func New(storeName string, db *store.Queries, log *logger.Logger) (*Limiter, error) {
+	var s Store
+	switch storeName {
+	case "", StoreMemory:
+		s = NewMemory()
+	case StorePostgres:
+		s = &Postgres{DB: db}
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", storeName)
+	}
+	return &Limiter{Store: s, Logger: log}, nil
+}
+
+// Store holds bucket and failure state. Implementations must make each
+// method atomic for a key.
+type Store interface {
+	// Take removes a token from the bucket for key. It returns zero when a
+	// token was available, otherwise how long until one will be.
+	Take(ctx context.Context, key string, limit Limit) (time.Duration, error)
+	// Fail records a failed authentication for key and returns the number
+	// of failures within window.
+	Fail(ctx context.Context, key string, window time.Duration) (int, error)
+	// Lock locks key out for d, extending any existing lockout.
+	Lock(ctx context.Context, key string, d time.Duration) error
+	// Locked returns the remaining lockout for key, zero if not locked.
+	Locked(ctx context.Context, key string) (time.Duration, error)
+	// Sweep removes expired state.
+	Sweep(ctx context.Context) error
+}
+
+// Limiter applies limits to HTTP requests.
+type Limiter struct {
+	Store  Store
+	Logger *logger.Logger
+	// IP limits every request by client address.
+	IP Limit
+	// Credential limits requests presenting the same credential.
+	Credential Limit
+	Lockout    Lockout
+	// CredentialFunc extracts the credential from a request, e.g. an API key
+	// header or the email of a login form. Empty values are not limited.
+	CredentialFunc func(r *http.Request) string
+}
+
+// Middleware rejects requests over the limits with 429 Too Many Requests and
+// a Retry-After header. Responses with status 401 count as failed
+// authentications for both the client address and the credential. It must be
+// placed after middleware which sets RemoteAddr from trusted proxy headers.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		keys := []string{"ip:" + clientIP(r)}
+		if l.CredentialFunc != nil {
+			if c := l.CredentialFunc(r); c != "" {
+				keys = append(keys, "cred:"+hash(c))
+			}
+		}
+		for _, k := range keys {
+			wait, err := l.Store.Locked(ctx, k)
+			if err != nil {
+				// Failing open keeps the API available if the store is down.
+				l.Logger.Error().Err(err).Msg("rate limit store unavailable")
+				next.ServeHTTP(w, r)
+				return
+			}
+			if wait > 0 {
+				tooManyRequests(w, wait, "too many failed authentication attempts")
+				return
+			}
+		}
+		limits := []Limit{l.IP, l.Credential}
+		for i, k := range keys {
+			if !limits[i].enabled() {
+				continue
+			}
+			wait, err := l.Store.Take(ctx, k, limits[i])
+			if err != nil {
+				l.Logger.Error().Err(err).Msg("rate limit store unavailable")
+				next.ServeHTTP(w, r)
+				return
+			}
+			if wait > 0 {
+				tooManyRequests(w, wait, "rate limit exceeded")
+				return
+			}
+		}
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+		if ww.Status() == http.StatusUnauthorized {
+			l.fail(ctx, keys)
+		}
+	})
+}
+
+// Header returns a CredentialFunc reading the named request header.
+func Header(name string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// Run sweeps expired state until ctx is cancelled.
+func (l *Limiter) Run(ctx context.Context) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.Store.Sweep(ctx); err != nil {
+				l.Logger.Error().Err(err).Msg("failed to sweep rate limits")
+			}
+		}
+	}
+}
+
+func (l *Limiter) fail(ctx context.Context, keys []string) {
+	ctx = context.WithoutCancel(ctx)
+	for _, k := range keys {
+		n, err := l.Store.Fail(ctx, k, l.Lockout.Window)
+		if err != nil {
+			l.Logger.Error().Err(err).Msg("failed to record authentication failure")
+			continue
+		}
+		if d := l.Lockout.Duration(n); d > 0 {
+			if err := l.Store.Lock(ctx, k, d); err != nil {
+				l.Logger.Error().Err(err).Msg("failed to lock out client")
+				continue
+			}
+			l.Logger.Warn().Str("key", k).Int("failures", n).Dur("lockout", d).Msg("client locked out")
+		}
+	}
+}
+
+func tooManyRequests(w http.ResponseWriter, wait time.Duration, msg string) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"name":    "too_many_requests",
+		"message": msg,
+	})
+}
+
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// hash avoids keeping credentials in the store.
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/danielmichaels/tawny/internal/logger"
+)
+
+func TestLockoutDuration(t *testing.T) {
+	l := Lockout{Threshold: 5, Base: time.Minute, Max: time.Hour}
+	tests := map[int]time.Duration{
+		0:   0,
+		4:   0,
+		5:   time.Minute,
+		6:   2 * time.Minute,
+		8:   8 * time.Minute,
+		12:  time.Hour,
+		100: time.Hour,
+	}
+	for failures, want := range tests {
+		if got := l.Duration(failures); got != want {
+			t.Errorf("Duration(%d) = %s, want %s", failures, got, want)
+		}
+	}
+}
+
+func TestMemoryTake(t *testing.T) {
+	now := time.Unix(0, 0)
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+	ctx := context.Background()
+	limit := Limit{Rate: 2, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		if wait, _ := m.Take(ctx, "k", limit); wait != 0 {
+			t.Fatalf("take %d: wait %s, want 0", i, wait)
+		}
+	}
+	if wait, _ := m.Take(ctx, "k", limit); wait != 500*time.Millisecond {
+		t.Fatalf("empty bucket: wait %s, want 500ms", wait)
+	}
+	if wait, _ := m.Take(ctx, "other", limit); wait != 0 {
+		t.Fatalf("other key: wait %s, want 0", wait)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if wait, _ := m.Take(ctx, "k", limit); wait != 0 {
+		t.Fatalf("after refill: wait %s, want 0", wait)
+	}
+
+	now = now.Add(time.Hour)
+	_ = m.Sweep(ctx)
+	if len(m.buckets) != 0 {
+		t.Fatalf("sweep left %d full buckets", len(m.buckets))
+	}
+}
+
+func TestMemoryFailures(t *testing.T) {
+	now := time.Unix(0, 0)
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		if n, _ := m.Fail(ctx, "k", time.Minute); n != i {
+			t.Fatalf("failure %d counted as %d", i, n)
+		}
+		now = now.Add(30 * time.Second)
+	}
+	// Each failure extends the window.
+	now = now.Add(time.Minute)
+	if n, _ := m.Fail(ctx, "k", time.Minute); n != 1 {
+		t.Fatalf("failures after window = %d, want 1", n)
+	}
+
+	_ = m.Lock(ctx, "k", 10*time.Minute)
+	_ = m.Lock(ctx, "k", time.Minute)
+	if d, _ := m.Locked(ctx, "k"); d != 10*time.Minute {
+		t.Fatalf("locked for %s, want 10m", d)
+	}
+	now = now.Add(10 * time.Minute)
+	if d, _ := m.Locked(ctx, "k"); d != 0 {
+		t.Fatalf("locked for %s after expiry", d)
+	}
+	_ = m.Sweep(ctx)
+	if len(m.failures) != 0 {
+		t.Fatalf("sweep left %d expired failures", len(m.failures))
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	l := &Limiter{
+		Store:          NewMemory(),
+		Logger:         logger.New("test", false, false),
+		IP:             Limit{Rate: 0.001, Burst: 100},
+		Credential:     Limit{Rate: 0.001, Burst: 3},
+		Lockout:        Lockout{Threshold: 2, Window: time.Minute, Base: time.Minute, Max: time.Hour},
+		CredentialFunc: Header("X-API-KEY"),
+	}
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-KEY") == "bad" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	do := func(key, addr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = addr + ":1234"
+		r.Header.Set("X-API-KEY", key)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	for i := 0; i < 3; i++ {
+		if w := do("good", "10.0.0.1"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d", i, w.Code)
+		}
+	}
+	w := do("good", "10.0.0.2")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("over key limit: status %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	do("bad", "10.0.0.3")
+	do("bad", "10.0.0.3")
+	w = do("other", "10.0.0.3")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Fatalf("locked out address: status %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+}
@@ -0,0 +1,108 @@
+// Package realip resolves the address of clients connecting through reverse
+// proxies. Proxy headers are only honoured from trusted proxies so clients
+// cannot choose the address they are rate limited and audited under.
+package realip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Resolver rewrites RemoteAddr from X-Forwarded-For or X-Real-IP when the
+// connecting peer is a trusted proxy. The zero value and nil trust nobody.
+type Resolver struct {
+	trusted []netip.Prefix
+}
+
+// New returns a Resolver trusting proxies in cidrs, e.g. 10.0.0.0/8. Single
+// addresses are accepted as host prefixes.
+func New(cidrs []string) (*Resolver, error) {
+	rs := &Resolver{}
+	for _, c := range cidrs {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		if !strings.Contains(c, "/") {
+			addr, err := netip.ParseAddr(c)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", c, err)
+			}
+			rs.trusted = append(rs.trusted, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", c, err)
+		}
+		rs.trusted = append(rs.trusted, p.Masked())
+	}
+	return rs, nil
+}
+
+// Middleware sets RemoteAddr to the client address reported by trusted
+// proxies. Requests from other peers keep the address of the connection.
+func (rs *Resolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ip, ok := rs.clientIP(r); ok {
+			r.RemoteAddr = ip
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP walks X-Forwarded-For from the right, skipping trusted proxies, so
+// addresses prepended by the client are never used. X-Real-IP is only used
+// when X-Forwarded-For is absent.
+func (rs *Resolver) clientIP(r *http.Request) (string, bool) {
+	if rs == nil || !rs.isTrusted(peer(r.RemoteAddr)) {
+		return "", false
+	}
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				return "", false
+			}
+			if !rs.isTrusted(addr) {
+				return addr.Unmap().String(), true
+			}
+		}
+		return "", false
+	}
+	if xrip := r.Header.Get("X-Real-IP"); xrip != "" {
+		addr, err := netip.ParseAddr(strings.TrimSpace(xrip))
+		if err != nil {
+			return "", false
+		}
+		return addr.Unmap().String(), true
+	}
+	return "", false
+}
+
+func (rs *Resolver) isTrusted(addr netip.Addr) bool {
+	if !addr.IsValid() {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range rs.trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// peer parses the address of the connection, which may lack a port.
+func peer(remoteAddr string) netip.Addr {
+	host := remoteAddr
+	if h, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		host = h
+	}
+	addr, _ := netip.ParseAddr(host)
+	return addr
+}
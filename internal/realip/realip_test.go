@@ -0,0 +1,80 @@
+package realip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	rs, err := New([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name:    "untrusted peer ignores headers",
+			remote:  "203.0.113.7:4000",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.2"},
+			want:    "203.0.113.7:4000",
+		},
+		{
+			name:    "trusted peer uses forwarded for",
+			remote:  "10.1.2.3:4000",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			want:    "198.51.100.1",
+		},
+		{
+			name:    "spoofed entries left of the client are skipped",
+			remote:  "10.1.2.3:4000",
+			headers: map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.1, 10.9.9.9"},
+			want:    "198.51.100.1",
+		},
+		{
+			name:    "single trusted address",
+			remote:  "192.168.1.1:4000",
+			headers: map[string]string{"X-Real-IP": "198.51.100.2"},
+			want:    "198.51.100.2",
+		},
+		{
+			name:    "malformed header keeps peer",
+			remote:  "10.1.2.3:4000",
+			headers: map[string]string{"X-Forwarded-For": "not-an-ip"},
+			want:    "10.1.2.3:4000",
+		},
+		{
+			name:    "true client ip is never trusted",
+			remote:  "10.1.2.3:4000",
+			headers: map[string]string{"True-Client-IP": "198.51.100.3"},
+			want:    "10.1.2.3:4000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := rs.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+			if got != tt.want {
+				t.Errorf("RemoteAddr = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	if _, err := New([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("expected an error for an invalid prefix")
+	}
+}
@@ -16,7 +16,10 @@ func Render(ctx context.Context, w http.ResponseWriter, status int, t templ.Comp
 
 type contextKey string
 
-const authUserContextKey = contextKey("authUser")
+const (
+	authUserContextKey  = contextKey("authUser")
+	csrfTokenContextKey = contextKey("csrfToken")
+)
 
 type CtxUser struct {
 	UserID         string
@@ -70,3 +73,15 @@ func GetUserAvatar(ctx context.Context) string {
 	}
 	return defaultAvatar
 }
+
+func SetCSRFToken(r *http.Request, token string) *http.Request {
+	ctx := context.WithValue(r.Context(), csrfTokenContextKey, token)
+	return r.WithContext(ctx)
+}
+
+// GetCSRFToken returns the token which must be submitted with any form on the
+// page. It is read by templates when rendering hidden form fields.
+func GetCSRFToken(ctx context.Context) string {
+	token, _ := ctx.Value(csrfTokenContextKey).(string)
+	return token
+}
@@ -0,0 +1,250 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+const (
+	testClientID = "tawny-test"
+	testKeyID    = "test-key"
+)
+
+// mockIssuer is a minimal OpenID Connect provider. It supports discovery,
+// JWKS and the token endpoint with PKCE so tests run without network access.
+type mockIssuer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu       sync.Mutex
+	codes    map[string]authRequest
+	audience string
+	nonce    string
+}
+
+type authRequest struct {
+	challenge string
+	nonce     string
+	subject   string
+	email     string
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockIssuer{key: key, codes: map[string]authRequest{}, audience: testClientID}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                m.URL,
+			"authorization_endpoint":                m.URL + "/authorize",
+			"token_endpoint":                        m.URL + "/token",
+			"jwks_uri":                              m.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{
+			Key:       &m.key.PublicKey,
+			KeyID:     testKeyID,
+			Algorithm: string(jose.RS256),
+			Use:       "sig",
+		}}})
+	})
+	mux.HandleFunc("/token", m.token)
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+// authorize simulates the user approving the request at authURL and returns
+// the authorization code the provider would redirect back with.
+func (m *mockIssuer) authorize(t *testing.T, authURL, subject, email string) string {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("expected S256 PKCE challenge, got %q", q.Get("code_challenge_method"))
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	code := "code-" + subject
+	m.codes[code] = authRequest{
+		challenge: q.Get("code_challenge"),
+		nonce:     q.Get("nonce"),
+		subject:   subject,
+		email:     email,
+	}
+	return code
+}
+
+func (m *mockIssuer) token(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	m.mu.Lock()
+	req, ok := m.codes[r.PostForm.Get("code")]
+	delete(m.codes, r.PostForm.Get("code"))
+	m.mu.Unlock()
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != req.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+	nonce := req.nonce
+	if m.nonce != "" {
+		nonce = m.nonce
+	}
+	claims, _ := json.Marshal(map[string]any{
+		"iss":            m.URL,
+		"sub":            req.subject,
+		"aud":            m.audience,
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"nonce":          nonce,
+		"email":          req.email,
+		"email_verified": true,
+		"name":           "Test User",
+	})
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: m.key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", testKeyID),
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	jws, err := signer.Sign(claims)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	idToken, _ := jws.CompactSerialize()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func newTestProvider(t *testing.T, m *mockIssuer) *Provider {
+	t.Helper()
+	p, err := New(context.Background(), Config{
+		Name:        "mock",
+		Issuer:      m.URL,
+		ClientID:    testClientID,
+		RedirectURL: "http://localhost/login/oidc/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestExchange(t *testing.T) {
+	m := newMockIssuer(t)
+	p := newTestProvider(t, m)
+
+	verifier := GenerateVerifier()
+	code := m.authorize(t, p.AuthCodeURL("state", "nonce-1", verifier), "sub-1", "Jane@Example.com")
+	id, err := p.Exchange(context.Background(), code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id.Subject != "sub-1" {
+		t.Errorf("expected subject %q, got %q", "sub-1", id.Subject)
+	}
+	if id.Email != "jane@example.com" {
+		t.Errorf("expected lower cased email, got %q", id.Email)
+	}
+	if !id.EmailVerified {
+		t.Error("expected email to be verified")
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	m := newMockIssuer(t)
+	p := newTestProvider(t, m)
+
+	code := m.authorize(t, p.AuthCodeURL("state", "nonce", GenerateVerifier()), "sub", "a@example.com")
+	if _, err := p.Exchange(context.Background(), code, GenerateVerifier(), "nonce"); err == nil {
+		t.Fatal("expected exchange with the wrong PKCE verifier to fail")
+	}
+}
+
+func TestExchangeRejectsNonceMismatch(t *testing.T) {
+	m := newMockIssuer(t)
+	m.nonce = "replayed"
+	p := newTestProvider(t, m)
+
+	verifier := GenerateVerifier()
+	code := m.authorize(t, p.AuthCodeURL("state", "nonce", verifier), "sub", "a@example.com")
+	_, err := p.Exchange(context.Background(), code, verifier, "nonce")
+	if !errors.Is(err, ErrNonceMismatch) {
+		t.Fatalf("expected ErrNonceMismatch, got %v", err)
+	}
+}
+
+func TestExchangeRejectsWrongAudience(t *testing.T) {
+	m := newMockIssuer(t)
+	m.audience = "another-client"
+	p := newTestProvider(t, m)
+
+	verifier := GenerateVerifier()
+	code := m.authorize(t, p.AuthCodeURL("state", "nonce", verifier), "sub", "a@example.com")
+	if _, err := p.Exchange(context.Background(), code, verifier, "nonce"); err == nil {
+		t.Fatal("expected id_token for another audience to be rejected")
+	}
+}
+
+func TestCanProvision(t *testing.T) {
+	tests := []struct {
+		name    string
+		auto    bool
+		domains []string
+		email   string
+		want    bool
+	}{
+		{"disabled", false, nil, "a@example.com", false},
+		{"any domain", true, nil, "a@example.com", true},
+		{"allowed domain", true, []string{"example.com"}, "a@example.com", true},
+		{"allowed domain case insensitive", true, []string{"Example.com"}, "a@EXAMPLE.COM", true},
+		{"other domain", true, []string{"example.com"}, "a@evil.com", false},
+		{"subdomain is not parent", true, []string{"example.com"}, "a@sub.example.com", false},
+		{"lookalike suffix", true, []string{"example.com"}, "a@notexample.com", false},
+		{"no domain", true, []string{"example.com"}, "example.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Provider{AutoProvision: tt.auto}
+			for _, d := range tt.domains {
+				p.allowedDomains = append(p.allowedDomains, strings.ToLower(d))
+			}
+			if got := p.CanProvision(tt.email); got != tt.want {
+				t.Errorf("CanProvision(%q) = %v, want %v", tt.email, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+// Package storage persists user uploaded files, such as profile photos, on
+// local disk or in S3 compatible object storage.
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	DriverDisk = "disk"
+	DriverS3   = "s3"
+
+	// DiskPublicPath is where the web server serves files from the disk driver.
+	DiskPublicPath = "/media"
+)
+
+// Store saves objects by key. Put returns the public path or URL of the
+// object which is what callers persist. Delete accepts that same value.
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) (string, error)
+	Delete(ctx context.Context, location string) error
+}
+
+type Config struct {
+	Driver string
+	// PublicURL is prefixed to keys to build the location returned by Put.
+	PublicURL string
+	// DiskPath is the directory files are written to by the disk driver.
+	DiskPath string
+
+	S3Bucket          string
+	S3Region          string
+	S3Endpoint        string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3ForcePathStyle  bool
+}
+
+// New returns the Store for cfg.Driver.
+func New(cfg Config) (Store, error) {
+	switch cfg.Driver {
+	case "", DriverDisk:
+		if err := os.MkdirAll(cfg.DiskPath, 0o750); err != nil {
+			return nil, fmt.Errorf("failed to create storage directory: %w", err)
+		}
+		publicURL := strings.TrimRight(cfg.PublicURL, "/")
+		if publicURL == "" {
+			publicURL = DiskPublicPath
+		}
+		return &Disk{dir: cfg.DiskPath, publicURL: publicURL}, nil
+	case DriverS3:
+		if cfg.S3Bucket == "" {
+			return nil, fmt.Errorf("s3 storage requires a bucket")
+		}
+		awsCfg := aws.NewConfig().
+			WithRegion(cfg.S3Region).
+			WithS3ForcePathStyle(cfg.S3ForcePathStyle)
+		if cfg.S3Endpoint != "" {
+			awsCfg = awsCfg.WithEndpoint(cfg.S3Endpoint)
+		}
+		if cfg.S3AccessKeyID != "" {
+			awsCfg = awsCfg.WithCredentials(
+				credentials.NewStaticCredentials(cfg.S3AccessKeyID, cfg.S3SecretAccessKey, ""),
+			)
+		}
+		sess, err := session.NewSession(awsCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create s3 session: %w", err)
+		}
+		publicURL := strings.TrimRight(cfg.PublicURL, "/")
+		if publicURL == "" {
+			publicURL = fmt.Sprintf("https://%s.s3.%s.amazonaws.com", cfg.S3Bucket, cfg.S3Region)
+		}
+		return &S3{client: s3.New(sess), bucket: cfg.S3Bucket, publicURL: publicURL}, nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}
+
+// Disk stores files beneath a local directory which the web server exposes
+// at PublicURL.
+type Disk struct {
+	dir       string
+	publicURL string
+}
+
+func (d *Disk) Put(_ context.Context, key string, r io.Reader, _ string) (string, error) {
+	p, err := d.path(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o750); err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(f.Name(), p); err != nil {
+		return "", err
+	}
+	return d.publicURL + "/" + key, nil
+}
+
+func (d *Disk) Delete(_ context.Context, location string) error {
+	key, ok := strings.CutPrefix(location, d.publicURL+"/")
+	if !ok {
+		return nil
+	}
+	p, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Dir is the directory files are stored in.
+func (d *Disk) Dir() string {
+	return d.dir
+}
+
+// path resolves key beneath the storage directory, rejecting traversal.
+func (d *Disk) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" || clean != "/"+key {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return filepath.Join(d.dir, filepath.FromSlash(clean)), nil
+}
+
+// S3 stores objects in an S3 compatible bucket. Objects are expected to be
+// publicly readable at PublicURL, e.g. through a bucket policy or CDN.
+type S3 struct {
+	client    *s3.S3
+	bucket    string
+	publicURL string
+}
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, contentType string) (string, error) {
+	body, ok := r.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return "", err
+		}
+		body = bytes.NewReader(b)
+	}
+	_, err := s.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        body,
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload %q: %w", key, err)
+	}
+	return s.publicURL + "/" + key, nil
+}
+
+func (s *S3) Delete(ctx context.Context, location string) error {
+	key, ok := strings.CutPrefix(location, s.publicURL+"/")
+	if !ok {
+		return nil
+	}
+	_, err := s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
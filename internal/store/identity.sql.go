@@ -11,36 +11,164 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const countUsers = `-- name: CountUsers :one
+const addTeamMember = `-- name: AddTeamMember :exec
+INSERT INTO team_user (team_id, user_id, role)
+VALUES ($1, $2, $3)
+`
 
+type AddTeamMemberParams struct {
+	TeamID pgtype.Text `json:"team_id"`
+	UserID pgtype.Text `json:"user_id"`
+	Role   UserRole    `json:"role"`
+}
 
-SELECT count(*) OVER ()
-FROM users u
-         JOIN team_user tu ON u.uuid = tu.user_id
-WHERE tu.team_id IN (SELECT tu.team_id
-                     FROM team_user tu
-                     WHERE tu.team_id = $1)
+func (q *Queries) AddTeamMember(ctx context.Context, arg AddTeamMemberParams) error {
+	_, err := q.db.Exec(ctx, addTeamMember, arg.TeamID, arg.UserID, arg.Role)
+	return err
+}
+
+const bootstrapAdmin = `-- name: BootstrapAdmin :one
+WITH new_team AS (
+    INSERT INTO teams (name, personal_team)
+        VALUES ('admin_team', true)
+        RETURNING uuid, id),
+     new_user AS (
+         INSERT INTO users (name, email, password, email_verified_at, current_team_id, platform_admin)
+             VALUES ('admin', $1, $2, NOW(), (SELECT id FROM new_team), TRUE)
+             RETURNING uuid),
+     new_user_team AS (
+         INSERT INTO team_user (team_id, user_id, role)
+             SELECT new_team.uuid, new_user.uuid, 'owner'
+             FROM new_team,
+                  new_user
+             RETURNING user_id, team_id),
+     new_token AS (
+         INSERT INTO personal_access_tokens (tokenable_type, tokenable_id, name, token, abilities)
+             SELECT 'user', new_user.uuid, 'bootstrap', ('key_' || generate_uid(20)), '["*"]'
+             FROM new_user
+             RETURNING token)
+SELECT new_user.uuid AS user_id, new_team.uuid AS team_id, new_token.token AS personal_access_token
+FROM new_user,
+     new_team,
+     new_token
 `
 
-// $2 is the UUID of the authenticated user
-// Count all users the authorized user can see; used in pagination
-func (q *Queries) CountUsers(ctx context.Context, teamID pgtype.Text) (int64, error) {
-	row := q.db.QueryRow(ctx, countUsers, teamID)
+type BootstrapAdminParams struct {
+	Email    pgtype.Text `json:"email"`
+	Password pgtype.Text `json:"password"`
+}
+
+type BootstrapAdminRow struct {
+	UserID              string `json:"user_id"`
+	TeamID              string `json:"team_id"`
+	PersonalAccessToken string `json:"personal_access_token"`
+}
+
+// Create the admin user, their team and an API key holding every scope. Used
+// by `tawny admin bootstrap` on first boot.
+func (q *Queries) BootstrapAdmin(ctx context.Context, arg BootstrapAdminParams) (BootstrapAdminRow, error) {
+	row := q.db.QueryRow(ctx, bootstrapAdmin, arg.Email, arg.Password)
+	var i BootstrapAdminRow
+	err := row.Scan(&i.UserID, &i.TeamID, &i.PersonalAccessToken)
+	return i, err
+}
+
+const countTeamOwners = `-- name: CountTeamOwners :one
+SELECT count(*)
+FROM team_user
+WHERE team_id = $1
+  AND role = 'owner'
+`
+
+// Count the owners of a team; a team must always keep at least one.
+func (q *Queries) CountTeamOwners(ctx context.Context, teamID pgtype.Text) (int64, error) {
+	row := q.db.QueryRow(ctx, countTeamOwners, teamID)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
 }
 
+const countUsers = `-- name: CountUsers :one
+SELECT count(*), COALESCE(max(id), 0)::BIGINT AS max_id
+FROM team_user
+WHERE team_id = $1
+  AND ($2::BIGINT IS NULL OR id <= $2)
+`
+
+type CountUsersParams struct {
+	TeamID pgtype.Text `json:"team_id"`
+	MaxID  pgtype.Int8 `json:"max_id"`
+}
+
+type CountUsersRow struct {
+	Count int64 `json:"count"`
+	MaxID int64 `json:"max_id"`
+}
+
+// Count the members of a team; used in pagination. max_id is the newest
+// membership, used to anchor later pages.
+func (q *Queries) CountUsers(ctx context.Context, arg CountUsersParams) (CountUsersRow, error) {
+	row := q.db.QueryRow(ctx, countUsers, arg.TeamID, arg.MaxID)
+	var i CountUsersRow
+	err := row.Scan(&i.Count, &i.MaxID)
+	return i, err
+}
+
+const createPersonalAccessToken = `-- name: CreatePersonalAccessToken :one
+INSERT INTO personal_access_tokens (tokenable_type, tokenable_id, name, token, abilities, team_id)
+VALUES ('user', $1, $2, ('key_' || generate_uid(20)), $3, $4)
+RETURNING id, name, token, abilities, team_id, created_at
+`
+
+type CreatePersonalAccessTokenParams struct {
+	TokenableID string      `json:"tokenable_id"`
+	Name        string      `json:"name"`
+	Abilities   pgtype.Text `json:"abilities"`
+	TeamID      pgtype.Text `json:"team_id"`
+}
+
+type CreatePersonalAccessTokenRow struct {
+	ID        int64              `json:"id"`
+	Name      string             `json:"name"`
+	Token     string             `json:"token"`
+	Abilities pgtype.Text        `json:"abilities"`
+	TeamID    pgtype.Text        `json:"team_id"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+// Mint a new API key for a user. abilities is a JSON array of scopes and
+// team_id optionally binds the key to a single team.
+func (q *Queries) CreatePersonalAccessToken(ctx context.Context, arg CreatePersonalAccessTokenParams) (CreatePersonalAccessTokenRow, error) {
+	row := q.db.QueryRow(ctx, createPersonalAccessToken,
+		arg.TokenableID,
+		arg.Name,
+		arg.Abilities,
+		arg.TeamID,
+	)
+	var i CreatePersonalAccessTokenRow
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Token,
+		&i.Abilities,
+		&i.TeamID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const createTeam = `-- name: CreateTeam :one
-WITH admin_check AS (SELECT 1
-                     FROM team_user
-                     WHERE user_id = $1 -- uuid of the user
-                       AND role = 'admin')
-INSERT
-INTO teams (name, personal_team)
-SELECT $2, false -- name of the new team
-WHERE EXISTS (SELECT 1 FROM admin_check)
-RETURNING name, uuid, personal_team
+WITH new_team AS (
+    INSERT INTO teams (name, personal_team)
+        VALUES ($2, false)
+        RETURNING name, uuid, personal_team),
+     new_team_user AS (
+         INSERT INTO team_user (team_id, user_id, role)
+             SELECT new_team.uuid, $1, 'owner'
+             FROM new_team
+             RETURNING team_id)
+SELECT name, uuid, personal_team
+FROM new_team
 `
 
 type CreateTeamParams struct {
@@ -54,7 +182,8 @@ type CreateTeamRow struct {
 	PersonalTeam pgtype.Bool `json:"personal_team"`
 }
 
-// Create a new team. Can only be created by a user with admin privileges
+// Create a new team owned by the creating user. Permission to create teams
+// is checked by the caller.
 func (q *Queries) CreateTeam(ctx context.Context, arg CreateTeamParams) (CreateTeamRow, error) {
 	row := q.db.QueryRow(ctx, createTeam, arg.UserID, arg.Name)
 	var i CreateTeamRow
@@ -73,7 +202,7 @@ WITH new_team AS (
              RETURNING uuid),
      new_user_team AS (
          INSERT INTO team_user (team_id, user_id, role)
-             SELECT new_team.uuid, new_user.uuid, 'maintainer'
+             SELECT new_team.uuid, new_user.uuid, 'owner'
              FROM new_team,
                   new_user
              RETURNING user_id, team_id),
@@ -118,13 +247,11 @@ func (q *Queries) CreateUserWithNewTeam(ctx context.Context, arg CreateUserWithN
 
 const doesAdminExist = `-- name: DoesAdminExist :one
 SELECT EXISTS (SELECT 1
-               FROM users u
-                        JOIN team_user tu ON u.uuid = tu.user_id
-               WHERE u.name = 'admin'
-                 AND tu.role = 'admin') AS admin_exists
+               FROM users
+               WHERE platform_admin) AS admin_exists
 `
 
-// Create admin user (for initial setup only)
+// Report whether a platform admin exists (for initial setup only)
 func (q *Queries) DoesAdminExist(ctx context.Context) (bool, error) {
 	row := q.db.QueryRow(ctx, doesAdminExist)
 	var admin_exists bool
@@ -132,8 +259,67 @@ func (q *Queries) DoesAdminExist(ctx context.Context) (bool, error) {
 	return admin_exists, err
 }
 
+const getTeamByUUID = `-- name: GetTeamByUUID :one
+SELECT name, uuid, personal_team, created_at, updated_at
+FROM teams
+WHERE uuid = $1
+`
+
+type GetTeamByUUIDRow struct {
+	Name         string             `json:"name"`
+	Uuid         string             `json:"uuid"`
+	PersonalTeam pgtype.Bool        `json:"personal_team"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+}
+
+func (q *Queries) GetTeamByUUID(ctx context.Context, uuid string) (GetTeamByUUIDRow, error) {
+	row := q.db.QueryRow(ctx, getTeamByUUID, uuid)
+	var i GetTeamByUUIDRow
+	err := row.Scan(
+		&i.Name,
+		&i.Uuid,
+		&i.PersonalTeam,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getTeamMembership = `-- name: GetTeamMembership :one
+SELECT tu.team_id, tu.user_id, tu.role, t.require_two_factor
+FROM team_user tu
+         JOIN teams t ON tu.team_id = t.uuid
+WHERE tu.team_id = $1
+  AND tu.user_id = $2
+`
+
+type GetTeamMembershipParams struct {
+	TeamID pgtype.Text `json:"team_id"`
+	UserID pgtype.Text `json:"user_id"`
+}
+
+type GetTeamMembershipRow struct {
+	TeamID           pgtype.Text `json:"team_id"`
+	UserID           pgtype.Text `json:"user_id"`
+	Role             UserRole    `json:"role"`
+	RequireTwoFactor bool        `json:"require_two_factor"`
+}
+
+func (q *Queries) GetTeamMembership(ctx context.Context, arg GetTeamMembershipParams) (GetTeamMembershipRow, error) {
+	row := q.db.QueryRow(ctx, getTeamMembership, arg.TeamID, arg.UserID)
+	var i GetTeamMembershipRow
+	err := row.Scan(
+		&i.TeamID,
+		&i.UserID,
+		&i.Role,
+		&i.RequireTwoFactor,
+	)
+	return i, err
+}
+
 const getUserByID = `-- name: GetUserByID :one
-SELECT u.uuid, u.name, u.email, u.created_at, u.updated_at, tu.role
+SELECT u.uuid, u.name, u.email, u.created_at, u.updated_at, tu.role, u.profile_photo_path
 FROM users u
          JOIN team_user tu ON u.uuid = tu.user_id
 WHERE u.uuid = $1 -- $1 is the UUID of the user you want to retrieve
@@ -146,12 +332,13 @@ type GetUserByIDParams struct {
 }
 
 type GetUserByIDRow struct {
-	Uuid      string             `json:"uuid"`
-	Name      pgtype.Text        `json:"name"`
-	Email     pgtype.Text        `json:"email"`
-	CreatedAt pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
-	Role      UserRole           `json:"role"`
+	Uuid             string             `json:"uuid"`
+	Name             pgtype.Text        `json:"name"`
+	Email            pgtype.Text        `json:"email"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+	Role             UserRole           `json:"role"`
+	ProfilePhotoPath pgtype.Text        `json:"profile_photo_path"`
 }
 
 // Get users in the same team mapping as the logged-in user when provided another user's ID
@@ -165,29 +352,31 @@ func (q *Queries) GetUserByID(ctx context.Context, arg GetUserByIDParams) (GetUs
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Role,
+		&i.ProfilePhotoPath,
 	)
 	return i, err
 }
 
 const listUsers = `-- name: ListUsers :many
-SELECT u.id, u.name, u.email, u.created_at, u.updated_at, tu.role
+SELECT u.id, u.uuid, u.name, u.email, u.created_at, u.updated_at, tu.role
 FROM users u
          JOIN team_user tu ON u.uuid = tu.user_id
-WHERE tu.team_id IN (SELECT team_id
-                     FROM team_user
-                     WHERE tu.team_id = $1)
-ORDER BY u.created_at DESC
-LIMIT $2 OFFSET $3
+WHERE tu.team_id = $1
+  AND ($2::BIGINT IS NULL OR tu.id <= $2)
+ORDER BY u.created_at DESC, u.id DESC
+LIMIT $3 OFFSET $4
 `
 
 type ListUsersParams struct {
 	TeamID pgtype.Text `json:"team_id"`
-	Limit  int32       `json:"limit"`
-	Offset int32       `json:"offset"`
+	MaxID  pgtype.Int8 `json:"max_id"`
+	Lim    int32       `json:"lim"`
+	Off    int32       `json:"off"`
 }
 
 type ListUsersRow struct {
 	ID        int32              `json:"id"`
+	Uuid      string             `json:"uuid"`
 	Name      pgtype.Text        `json:"name"`
 	Email     pgtype.Text        `json:"email"`
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
@@ -195,9 +384,15 @@ type ListUsersRow struct {
 	Role      UserRole           `json:"role"`
 }
 
-// List all users associated with the authorized user and get the total count
+// List the members of a team, newest first. The ID breaks ties so that pages
+// are stable.
 func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUsersRow, error) {
-	rows, err := q.db.Query(ctx, listUsers, arg.TeamID, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, listUsers,
+		arg.TeamID,
+		arg.MaxID,
+		arg.Lim,
+		arg.Off,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -207,6 +402,7 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUse
 		var i ListUsersRow
 		if err := rows.Scan(
 			&i.ID,
+			&i.Uuid,
 			&i.Name,
 			&i.Email,
 			&i.CreatedAt,
@@ -223,32 +419,82 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUse
 	return items, nil
 }
 
-const retrieveUserWithTeamInfoByAPIKEY = `-- name: RetrieveUserWithTeamInfoByAPIKEY :one
-SELECT u.uuid, u.name, u.email, t.name, t.uuid AS team_uuid
+const removeTeamMember = `-- name: RemoveTeamMember :execrows
+DELETE
+FROM team_user
+WHERE team_id = $1
+  AND user_id = $2
+`
+
+type RemoveTeamMemberParams struct {
+	TeamID pgtype.Text `json:"team_id"`
+	UserID pgtype.Text `json:"user_id"`
+}
+
+func (q *Queries) RemoveTeamMember(ctx context.Context, arg RemoveTeamMemberParams) (int64, error) {
+	result, err := q.db.Exec(ctx, removeTeamMember, arg.TeamID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const retrieveUserByAPIKEY = `-- name: RetrieveUserByAPIKEY :one
+SELECT u.uuid,
+       u.name,
+       u.email,
+       u.email_verified_at IS NOT NULL       AS email_verified,
+       u.two_factor_confirmed_at IS NOT NULL AS two_factor_enabled,
+       u.platform_admin,
+       pat.abilities,
+       pat.team_id                           AS token_team_id,
+       ct.team_id                            AS current_team_id,
+       pat.id                                AS token_id
 FROM users u
-         JOIN personal_access_tokens pat ON u.uuid = pat.tokenable_id
-         JOIN team_user tu ON u.uuid = tu.user_id
-         JOIN teams t ON tu.team_id = t.uuid
+         JOIN personal_access_tokens pat ON u.uuid = pat.user_id
+         LEFT JOIN LATERAL (SELECT tu.team_id, tu.role
+                            FROM team_user tu
+                                     JOIN teams t ON t.uuid = tu.team_id
+                            WHERE tu.user_id = u.uuid
+                            ORDER BY (t.id = u.current_team_id) IS TRUE DESC,
+                                     t.personal_team IS TRUE DESC,
+                                     tu.created_at
+                            LIMIT 1) ct ON TRUE
 WHERE pat.token = $1
 `
 
-type RetrieveUserWithTeamInfoByAPIKEYRow struct {
-	Uuid     string      `json:"uuid"`
-	Name     pgtype.Text `json:"name"`
-	Email    pgtype.Text `json:"email"`
-	Name_2   string      `json:"name_2"`
-	TeamUuid string      `json:"team_uuid"`
+type RetrieveUserByAPIKEYRow struct {
+	Uuid             string      `json:"uuid"`
+	Name             pgtype.Text `json:"name"`
+	Email            pgtype.Text `json:"email"`
+	EmailVerified    bool        `json:"email_verified"`
+	TwoFactorEnabled bool        `json:"two_factor_enabled"`
+	PlatformAdmin    bool        `json:"platform_admin"`
+	Abilities        pgtype.Text `json:"abilities"`
+	TokenTeamID      pgtype.Text `json:"token_team_id"`
+	CurrentTeamID    pgtype.Text `json:"current_team_id"`
+	TokenID          int64       `json:"token_id"`
 }
 
-func (q *Queries) RetrieveUserWithTeamInfoByAPIKEY(ctx context.Context, token string) (RetrieveUserWithTeamInfoByAPIKEYRow, error) {
-	row := q.db.QueryRow(ctx, retrieveUserWithTeamInfoByAPIKEY, token)
-	var i RetrieveUserWithTeamInfoByAPIKEYRow
+// Retrieve the user owning an API key along with the team the key is bound to
+// (if any) and the user's current team. The current team falls back to the
+// personal team when the user is no longer a member of the selected team.
+// Team membership is resolved separately so that users in several teams act
+// within an explicit team.
+func (q *Queries) RetrieveUserByAPIKEY(ctx context.Context, token string) (RetrieveUserByAPIKEYRow, error) {
+	row := q.db.QueryRow(ctx, retrieveUserByAPIKEY, token)
+	var i RetrieveUserByAPIKEYRow
 	err := row.Scan(
 		&i.Uuid,
 		&i.Name,
 		&i.Email,
-		&i.Name_2,
-		&i.TeamUuid,
+		&i.EmailVerified,
+		&i.TwoFactorEnabled,
+		&i.PlatformAdmin,
+		&i.Abilities,
+		&i.TokenTeamID,
+		&i.CurrentTeamID,
+		&i.TokenID,
 	)
 	return i, err
 }
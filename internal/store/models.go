@@ -14,8 +14,10 @@ import (
 type UserRole string
 
 const (
+	UserRoleOwner      UserRole = "owner"
 	UserRoleAdmin      UserRole = "admin"
 	UserRoleMaintainer UserRole = "maintainer"
+	UserRoleViewer     UserRole = "viewer"
 )
 
 func (e *UserRole) Scan(src interface{}) error {
@@ -53,16 +55,122 @@ func (ns NullUserRole) Value() (driver.Value, error) {
 	return string(ns.UserRole), nil
 }
 
+type AuditEvents struct {
+	ID                    int64              `json:"id"`
+	ActorUserID           pgtype.Text        `json:"actor_user_id"`
+	ActorTokenID          pgtype.Int8        `json:"actor_token_id"`
+	TeamID                pgtype.Text        `json:"team_id"`
+	Action                string             `json:"action"`
+	ResourceType          string             `json:"resource_type"`
+	ResourceID            string             `json:"resource_id"`
+	RequestID             string             `json:"request_id"`
+	IpAddress             pgtype.Text        `json:"ip_address"`
+	Outcome               string             `json:"outcome"`
+	CreatedAt             pgtype.Timestamptz `json:"created_at"`
+	ActorServiceAccountID pgtype.Text        `json:"actor_service_account_id"`
+}
+
+type AuthFailures struct {
+	Key         string             `json:"key"`
+	Failures    int32              `json:"failures"`
+	LockedUntil pgtype.Timestamptz `json:"locked_until"`
+	ExpiresAt   pgtype.Timestamptz `json:"expires_at"`
+}
+
+type CertificateNotifications struct {
+	Key       string             `json:"key"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type Domains struct {
+	ID                   int64              `json:"id"`
+	Uuid                 string             `json:"uuid"`
+	TeamID               string             `json:"team_id"`
+	AppID                string             `json:"app_id"`
+	Name                 string             `json:"name"`
+	CertificateType      string             `json:"certificate_type"`
+	CreatedAt            pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt            pgtype.Timestamptz `json:"updated_at"`
+	Issuer               string             `json:"issuer"`
+	TlsSecret            string             `json:"tls_secret"`
+	CertificateExpiresAt pgtype.Timestamptz `json:"certificate_expires_at"`
+	Routes               []byte             `json:"routes"`
+}
+
 type PersonalAccessTokens struct {
-	ID            int64              `json:"id"`
-	TokenableType string             `json:"tokenable_type"`
-	TokenableID   string             `json:"tokenable_id"`
-	Name          string             `json:"name"`
-	Token         string             `json:"token"`
-	Abilities     pgtype.Text        `json:"abilities"`
-	LastUsedAt    pgtype.Timestamptz `json:"last_used_at"`
-	CreatedAt     pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+	ID               int64              `json:"id"`
+	TokenableType    string             `json:"tokenable_type"`
+	TokenableID      string             `json:"tokenable_id"`
+	Name             string             `json:"name"`
+	Token            string             `json:"token"`
+	Abilities        pgtype.Text        `json:"abilities"`
+	LastUsedAt       pgtype.Timestamptz `json:"last_used_at"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+	TeamID           pgtype.Text        `json:"team_id"`
+	UserID           pgtype.Text        `json:"user_id"`
+	ServiceAccountID pgtype.Text        `json:"service_account_id"`
+}
+
+type PortMappings struct {
+	ID          int64              `json:"id"`
+	Uuid        string             `json:"uuid"`
+	TeamID      string             `json:"team_id"`
+	AppID       string             `json:"app_id"`
+	Entrypoint  string             `json:"entrypoint"`
+	Protocol    string             `json:"protocol"`
+	Hostname    string             `json:"hostname"`
+	Passthrough bool               `json:"passthrough"`
+	ServicePort int32              `json:"service_port"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+}
+
+type RateLimitBuckets struct {
+	Key       string             `json:"key"`
+	Tokens    float64            `json:"tokens"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+	FullAt    pgtype.Timestamptz `json:"full_at"`
+}
+
+type Releases struct {
+	ID               int64              `json:"id"`
+	Uuid             string             `json:"uuid"`
+	TeamID           string             `json:"team_id"`
+	AppID            string             `json:"app_id"`
+	Domain           string             `json:"domain"`
+	Strategy         string             `json:"strategy"`
+	Image            string             `json:"image"`
+	Container        string             `json:"container"`
+	StableDeployment string             `json:"stable_deployment"`
+	Weight           int32              `json:"weight"`
+	Status           string             `json:"status"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+}
+
+type ServiceAccounts struct {
+	ID          int64              `json:"id"`
+	Uuid        string             `json:"uuid"`
+	TeamID      string             `json:"team_id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Role        UserRole           `json:"role"`
+	CreatedBy   pgtype.Text        `json:"created_by"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+}
+
+type Sessions struct {
+	ID         int64              `json:"id"`
+	TokenHash  string             `json:"token_hash"`
+	UserID     string             `json:"user_id"`
+	IpAddress  pgtype.Text        `json:"ip_address"`
+	UserAgent  pgtype.Text        `json:"user_agent"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+	IdentityID pgtype.Int8        `json:"identity_id"`
 }
 
 type TeamUser struct {
@@ -75,24 +183,58 @@ type TeamUser struct {
 }
 
 type Teams struct {
-	ID           int32              `json:"id"`
-	Uuid         string             `json:"uuid"`
-	PersonalTeam pgtype.Bool        `json:"personal_team"`
-	Name         string             `json:"name"`
-	CreatedAt    pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
-}
-
-type Users struct {
 	ID               int32              `json:"id"`
 	Uuid             string             `json:"uuid"`
-	Name             pgtype.Text        `json:"name"`
-	Email            pgtype.Text        `json:"email"`
-	EmailVerifiedAt  pgtype.Timestamptz `json:"email_verified_at"`
-	Password         pgtype.Text        `json:"password"`
-	RememberToken    pgtype.Text        `json:"remember_token"`
-	CurrentTeamID    pgtype.Int4        `json:"current_team_id"`
-	ProfilePhotoPath pgtype.Text        `json:"profile_photo_path"`
+	PersonalTeam     pgtype.Bool        `json:"personal_team"`
+	Name             string             `json:"name"`
 	CreatedAt        pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+	RequireTwoFactor bool               `json:"require_two_factor"`
+}
+
+type TwoFactorRecoveryCodes struct {
+	ID        int64              `json:"id"`
+	UserID    string             `json:"user_id"`
+	CodeHash  string             `json:"code_hash"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type UserIdentities struct {
+	ID        int64              `json:"id"`
+	UserID    string             `json:"user_id"`
+	Provider  string             `json:"provider"`
+	Subject   string             `json:"subject"`
+	Email     pgtype.Text        `json:"email"`
+	AvatarUrl pgtype.Text        `json:"avatar_url"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type UserTokens struct {
+	ID        int64              `json:"id"`
+	UserID    string             `json:"user_id"`
+	Purpose   string             `json:"purpose"`
+	TokenHash string             `json:"token_hash"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type Users struct {
+	ID                   int32              `json:"id"`
+	Uuid                 string             `json:"uuid"`
+	Name                 pgtype.Text        `json:"name"`
+	Email                pgtype.Text        `json:"email"`
+	EmailVerifiedAt      pgtype.Timestamptz `json:"email_verified_at"`
+	Password             pgtype.Text        `json:"password"`
+	RememberToken        pgtype.Text        `json:"remember_token"`
+	CurrentTeamID        pgtype.Int4        `json:"current_team_id"`
+	ProfilePhotoPath     pgtype.Text        `json:"profile_photo_path"`
+	CreatedAt            pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt            pgtype.Timestamptz `json:"updated_at"`
+	TwoFactorSecret      pgtype.Text        `json:"two_factor_secret"`
+	TwoFactorConfirmedAt pgtype.Timestamptz `json:"two_factor_confirmed_at"`
+	TwoFactorLastCounter pgtype.Int8        `json:"two_factor_last_counter"`
+	PlatformAdmin        bool               `json:"platform_admin"`
 }
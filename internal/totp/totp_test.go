@@ -0,0 +1,98 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed from RFC 6238 Appendix B.
+var rfc6238Secret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestHOTPVectors(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	key := []byte("12345678901234567890")
+	for _, tt := range tests {
+		got := hotp(key, Counter(time.Unix(tt.unix, 0)), 8)
+		if got != tt.want {
+			t.Errorf("hotp at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code, err := Code(rfc6238Secret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != "050471" {
+		t.Fatalf("expected 6 digit truncation of the RFC vector, got %s", code)
+	}
+
+	tests := []struct {
+		name string
+		code string
+		at   time.Time
+		want bool
+	}{
+		{"current period", code, now, true},
+		{"previous period", code, now.Add(Period), true},
+		{"next period", code, now.Add(-Period), true},
+		{"outside skew", code, now.Add(2 * Period), false},
+		{"wrong code", "000000", now, false},
+		{"wrong length", "05047", now, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := Validate(rfc6238Secret, tt.code, tt.at); ok != tt.want {
+				t.Errorf("Validate() = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateReturnsCounter(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code, _ := Code(rfc6238Secret, now)
+	counter, ok := Validate(rfc6238Secret, code, now.Add(Period))
+	if !ok || counter != Counter(now) {
+		t.Fatalf("expected counter %d, got %d (ok=%v)", Counter(now), counter, ok)
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	s, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decode(s); err != nil {
+		t.Fatalf("secret is not valid base32: %v", err)
+	}
+	if strings.Contains(s, "=") {
+		t.Error("secret should not be padded")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Tawny", "jane@example.com", "JBSWY3DPEHPK3PXP")
+	if !strings.HasPrefix(uri, "otpauth://totp/Tawny:jane@example.com?") {
+		t.Errorf("unexpected uri %s", uri)
+	}
+	for _, want := range []string{"secret=JBSWY3DPEHPK3PXP", "issuer=Tawny", "digits=6", "period=30"} {
+		if !strings.Contains(uri, want) {
+			t.Errorf("uri %s missing %s", uri, want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package webserver
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/danielmichaels/tawny/assets/static/view/pages"
+	"github.com/danielmichaels/tawny/internal/account"
+	"github.com/danielmichaels/tawny/internal/render"
+)
+
+func (app *Application) verifyEmailPage(w http.ResponseWriter, r *http.Request) {
+	_ = render.Render(r.Context(), w, http.StatusOK, pages.VerifyEmailPage(r.URL.Query().Get("token"), ""))
+}
+
+func (app *Application) verifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.PostFormValue("token")
+	if _, err := app.Accounts.VerifyEmail(r.Context(), token); err != nil {
+		if errors.Is(err, account.ErrInvalidToken) {
+			_ = render.Render(r.Context(), w, http.StatusBadRequest, pages.VerifyEmailPage(token, err.Error()))
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+	_ = render.Render(r.Context(), w, http.StatusOK, pages.MessagePage(
+		"Email verified",
+		"Thanks for confirming your email address.",
+	))
+}
+
+func (app *Application) forgotPasswordPage(w http.ResponseWriter, r *http.Request) {
+	_ = render.Render(r.Context(), w, http.StatusOK, pages.ForgotPasswordPage("", ""))
+}
+
+func (app *Application) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	email := strings.ToLower(strings.TrimSpace(r.PostFormValue("email")))
+	if email == "" {
+		_ = render.Render(
+			r.Context(),
+			w,
+			http.StatusUnprocessableEntity,
+			pages.ForgotPasswordPage(email, "Email is required"),
+		)
+		return
+	}
+	if err := app.Accounts.RequestPasswordReset(r.Context(), email); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	_ = render.Render(r.Context(), w, http.StatusOK, pages.MessagePage(
+		"Check your email",
+		"If an account exists for that address we have sent a link to reset your password.",
+	))
+}
+
+func (app *Application) resetPasswordPage(w http.ResponseWriter, r *http.Request) {
+	_ = render.Render(r.Context(), w, http.StatusOK, pages.ResetPasswordPage(r.URL.Query().Get("token"), ""))
+}
+
+func (app *Application) resetPassword(w http.ResponseWriter, r *http.Request) {
+	token := r.PostFormValue("token")
+	password := r.PostFormValue("password")
+	if password != r.PostFormValue("password_confirm") {
+		_ = render.Render(
+			r.Context(),
+			w,
+			http.StatusUnprocessableEntity,
+			pages.ResetPasswordPage(token, "Passwords do not match"),
+		)
+		return
+	}
+	err := app.Accounts.ResetPassword(r.Context(), token, password)
+	switch {
+	case errors.Is(err, account.ErrInvalidToken), errors.Is(err, account.ErrPasswordTooWeak):
+		_ = render.Render(r.Context(), w, http.StatusBadRequest, pages.ResetPasswordPage(token, err.Error()))
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+	// All sessions were revoked, including any for this browser.
+	http.SetCookie(w, app.sessionCookie("", time.Unix(0, 0)))
+	_ = render.Render(r.Context(), w, http.StatusOK, pages.MessagePage(
+		"Password updated",
+		"Your password has been changed and you have been signed out everywhere.",
+	))
+}
@@ -0,0 +1,56 @@
+package webserver
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/danielmichaels/tawny/assets/static/view/pages"
+	"github.com/danielmichaels/tawny/internal/auth"
+	"github.com/danielmichaels/tawny/internal/authz"
+	"github.com/danielmichaels/tawny/internal/certs"
+	"github.com/danielmichaels/tawny/internal/k8sclient"
+	"github.com/danielmichaels/tawny/internal/render"
+	"github.com/danielmichaels/tawny/internal/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// domainDiagnostics shows how far issuance of a domain's certificate got.
+func (app *Application) domainDiagnostics(w http.ResponseWriter, r *http.Request) {
+	ut := auth.CtxAuthInfo(r.Context())
+	if err := authz.Authorize(authz.Role(ut.Role), authz.ResourceDomain, authz.ActionRead); err != nil {
+		app.forbidden(w, r)
+		return
+	}
+	domain := strings.ToLower(chi.URLParam(r, "domain"))
+	d, err := app.DB.GetDomain(r.Context(), store.GetDomainParams{TeamID: ut.TeamUUID, Name: domain})
+	if errors.Is(err, pgx.ErrNoRows) {
+		app.notFound(w, r)
+		return
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	ingress, err := app.Ingress.Lookup(r.Context(), app.Kube)
+	if err != nil {
+		app.Logger.Warn().Err(err).Msg("error looking up ingress addresses")
+	}
+	certificate := ""
+	if d.Issuer != "" {
+		certificate = certs.CertificateFor(d.Name, d.TlsSecret)
+	}
+	diag, err := certs.Diagnose(r.Context(), app.Kube, net.DefaultResolver, k8sclient.DefaultNamespace, certificate, d.Name, ingress)
+	if apierrors.IsNotFound(err) {
+		app.notFound(w, r)
+		return
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	_ = render.Render(r.Context(), w, http.StatusOK, pages.DomainDiagnosticsPage(diag))
+}
@@ -28,6 +28,10 @@ func (app *Application) methodNotAllowed(w http.ResponseWriter, r *http.Request)
 		)
 	}
 }
+func (app *Application) forbidden(w http.ResponseWriter, r *http.Request) {
+	_ = render.Render(r.Context(), w, http.StatusForbidden, pages.ForbiddenErrorPage())
+}
+
 func (app *Application) serverError(w http.ResponseWriter, r *http.Request, err error) {
 	app.Logger.Error().Err(err).Send()
 	chirender.HTML(w, r, "<h2>ERROR</h2>")
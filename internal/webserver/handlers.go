@@ -0,0 +1,125 @@
+package webserver
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/danielmichaels/tawny/assets/static/view/pages"
+	"github.com/danielmichaels/tawny/internal/account"
+	"github.com/danielmichaels/tawny/internal/auth"
+	"github.com/danielmichaels/tawny/internal/render"
+	"github.com/danielmichaels/tawny/internal/store"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// dummyPasswordHash is compared against when a login email does not exist so
+// that response times do not reveal which accounts exist.
+const dummyPasswordHash = "$2a$10$ijQuEX4C6010eHUd/JNSv.PC1wYuxIu.UTeoevKo6soP1gRcSCn6W"
+
+const loginFailedMessage = "Email or password is incorrect"
+
+// maxSwitcherTeams bounds the teams offered by the team switcher.
+const maxSwitcherTeams = 100
+
+func (app *Application) dashboard(w http.ResponseWriter, r *http.Request) {
+	ut := auth.CtxAuthInfo(r.Context())
+	teams, err := app.DB.ListTeams(r.Context(), store.ListTeamsParams{
+		UserID: pgtype.Text{String: ut.UserUUID, Valid: true},
+		Lim:    maxSwitcherTeams,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	_ = render.Render(r.Context(), w, http.StatusOK, pages.DashboardPage(teams, ut.TeamUUID))
+}
+
+func (app *Application) loginPage(w http.ResponseWriter, r *http.Request) {
+	if render.GetUserContext(r) != nil {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	app.renderLogin(w, r, http.StatusOK, "", "")
+}
+
+// renderLogin renders the login page, offering SSO when it is configured.
+func (app *Application) renderLogin(w http.ResponseWriter, r *http.Request, status int, email, errMsg string) {
+	_ = render.Render(r.Context(), w, status, pages.LoginPage(email, errMsg, app.OIDC != nil))
+}
+
+func (app *Application) login(w http.ResponseWriter, r *http.Request) {
+	email := strings.ToLower(strings.TrimSpace(r.PostFormValue("email")))
+	password := r.PostFormValue("password")
+	if email == "" || password == "" {
+		app.renderLogin(w, r, http.StatusUnprocessableEntity, email, "Email and password are required")
+		return
+	}
+
+	hash := dummyPasswordHash
+	u, err := app.DB.GetUserCredentialsByEmail(r.Context(), pgtype.Text{String: email, Valid: true})
+	if err == nil && u.Password.Valid {
+		hash = u.Password.String
+	}
+	ok, matchErr := store.Matches(password, hash)
+	if err != nil || matchErr != nil || !ok {
+		app.Logger.Warn().Str("email", email).Msg("failed login attempt")
+		app.renderLogin(w, r, http.StatusUnauthorized, email, loginFailedMessage)
+		return
+	}
+
+	app.completeLogin(w, r, u.Uuid, pgtype.Int8{})
+}
+
+// completeLogin starts a session for a user who has passed the first factor.
+// Users enrolled in two-factor authentication are sent to enter a code first,
+// in which case the SSO identity is not recorded against the session.
+func (app *Application) completeLogin(
+	w http.ResponseWriter,
+	r *http.Request,
+	userID string,
+	identityID pgtype.Int8,
+) {
+	st, err := app.Accounts.TwoFactorStatus(r.Context(), userID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if st.Enabled {
+		token, err := app.Accounts.BeginTwoFactorLogin(r.Context(), userID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		http.SetCookie(w, app.twoFactorCookie(token, time.Now().Add(account.TwoFactorLoginLifetime)))
+		http.Redirect(w, r, "/login/2fa", http.StatusSeeOther)
+		return
+	}
+	app.startSession(w, r, userID, identityID)
+}
+
+// startSession replaces any existing session, which prevents session
+// fixation, and redirects to the dashboard.
+func (app *Application) startSession(
+	w http.ResponseWriter,
+	r *http.Request,
+	userID string,
+	identityID pgtype.Int8,
+) {
+	if err := app.destroySession(w, r); err != nil {
+		app.Logger.Warn().Err(err).Msg("failed to destroy previous session")
+	}
+	if err := app.createSession(w, r, userID, identityID); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (app *Application) logout(w http.ResponseWriter, r *http.Request) {
+	if err := app.destroySession(w, r); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
@@ -1 +1,110 @@
 package webserver
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"github.com/danielmichaels/tawny/internal/auth"
+	"github.com/danielmichaels/tawny/internal/render"
+)
+
+const (
+	csrfCookieName = "tawny_csrf"
+	csrfFieldName  = "csrf_token"
+	csrfHeaderName = "X-CSRF-Token"
+)
+
+// authenticate populates the user context from the session cookie. Requests
+// without a valid session continue anonymously.
+func (app *Application) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := r.Cookie(sessionCookieName)
+		if err != nil || c.Value == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		s, err := app.DB.GetSessionUser(r.Context(), hashToken(c.Value))
+		if err != nil {
+			http.SetCookie(w, app.sessionCookie("", time.Unix(0, 0)))
+			next.ServeHTTP(w, r)
+			return
+		}
+		avatar := s.ProfilePhotoPath.String
+		if avatar == "" {
+			avatar = s.ProviderAvatarUrl.String
+		}
+		// The same auth info as API keys carry so that handlers can share
+		// authorization checks. The team was validated as a membership by
+		// the query.
+		r = r.WithContext(auth.CtxSetAuthInfo(r.Context(), auth.CtxInfo{
+			UserUUID:      s.UserID,
+			TeamUUID:      s.CurrentTeamID.String,
+			Role:          s.CurrentTeamRole.UserRole,
+			Verified:      s.EmailVerified,
+			PlatformAdmin: s.PlatformAdmin,
+		}))
+		r = render.SetUserContext(r, render.CtxUser{
+			UserID:         s.UserID,
+			TeamID:         s.CurrentTeamID.String,
+			ProviderUserID: s.ProviderUserID.String,
+			UserMetadata: render.UserMetadata{
+				Email:  s.Email.String,
+				Avatar: avatar,
+			},
+		})
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireAuthentication redirects anonymous users to the login page.
+func (app *Application) requireAuthentication(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if render.GetUserContext(r) == nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		w.Header().Add("Cache-Control", "no-store")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// csrf implements double submit cookie protection. Every unsafe request must
+// echo the token from the CSRF cookie in a form field or header.
+func (app *Application) csrf(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var token string
+		if c, err := r.Cookie(csrfCookieName); err == nil {
+			token = c.Value
+		}
+		if token == "" {
+			t, err := newToken()
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+			token = t
+			http.SetCookie(w, &http.Cookie{
+				Name:     csrfCookieName,
+				Value:    token,
+				Path:     "/",
+				HttpOnly: true,
+				Secure:   app.Config.Session.CookieSecure,
+				SameSite: http.SameSiteStrictMode,
+			})
+		}
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		default:
+			sent := r.Header.Get(csrfHeaderName)
+			if sent == "" {
+				sent = r.PostFormValue(csrfFieldName)
+			}
+			if subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+				app.forbidden(w, r)
+				return
+			}
+		}
+		next.ServeHTTP(w, render.SetCSRFToken(r, token))
+	})
+}
@@ -0,0 +1,173 @@
+package webserver
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/danielmichaels/tawny/internal/sso"
+	"github.com/danielmichaels/tawny/internal/store"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	oidcCookieName = "tawny_oidc"
+	// oidcFlowLifetime bounds how long a user may take at the identity provider.
+	oidcFlowLifetime = 10 * time.Minute
+)
+
+var errSSONotPermitted = errors.New("no account matches this identity and auto provisioning is not permitted")
+
+// oidcLogin starts the authorization code flow. The state, nonce and PKCE
+// verifier are kept in a short-lived cookie until the provider redirects back.
+func (app *Application) oidcLogin(w http.ResponseWriter, r *http.Request) {
+	if app.OIDC == nil {
+		app.notFound(w, r)
+		return
+	}
+	state, err := newToken()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	nonce, err := newToken()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	verifier := sso.GenerateVerifier()
+	http.SetCookie(w, app.oidcCookie(
+		strings.Join([]string{state, nonce, verifier}, "."),
+		time.Now().Add(oidcFlowLifetime),
+	))
+	http.Redirect(w, r, app.OIDC.AuthCodeURL(state, nonce, verifier), http.StatusFound)
+}
+
+// oidcCallback completes the flow, links the identity to a user and starts a
+// session.
+func (app *Application) oidcCallback(w http.ResponseWriter, r *http.Request) {
+	if app.OIDC == nil {
+		app.notFound(w, r)
+		return
+	}
+	c, err := r.Cookie(oidcCookieName)
+	http.SetCookie(w, app.oidcCookie("", time.Unix(0, 0)))
+	if err != nil {
+		app.renderLogin(w, r, http.StatusBadRequest, "", "Your sign in attempt expired, please try again")
+		return
+	}
+	flow := strings.Split(c.Value, ".")
+	state := r.URL.Query().Get("state")
+	if len(flow) != 3 || subtle.ConstantTimeCompare([]byte(flow[0]), []byte(state)) != 1 {
+		app.renderLogin(w, r, http.StatusBadRequest, "", "Invalid sign in state, please try again")
+		return
+	}
+	if e := r.URL.Query().Get("error"); e != "" {
+		app.Logger.Warn().Str("error", e).Str("description", r.URL.Query().Get("error_description")).
+			Msg("identity provider returned an error")
+		app.renderLogin(w, r, http.StatusUnauthorized, "", "Single sign-on failed")
+		return
+	}
+
+	id, err := app.OIDC.Exchange(r.Context(), r.URL.Query().Get("code"), flow[2], flow[1])
+	if err != nil {
+		app.Logger.Warn().Err(err).Msg("oidc exchange failed")
+		app.renderLogin(w, r, http.StatusUnauthorized, "", "Single sign-on failed")
+		return
+	}
+	userID, identityID, err := app.linkIdentity(r, id)
+	if errors.Is(err, errSSONotPermitted) {
+		app.Logger.Warn().Str("email", id.Email).Str("subject", id.Subject).Msg(err.Error())
+		app.renderLogin(w, r, http.StatusForbidden, "", "Your account is not permitted to sign in")
+		return
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.completeLogin(w, r, userID, pgtype.Int8{Int64: identityID, Valid: true})
+}
+
+// linkIdentity resolves the user for a verified identity. Identities already
+// linked are used directly, otherwise a user with the same verified email is
+// linked, and finally a new user is provisioned if the provider allows it.
+func (app *Application) linkIdentity(r *http.Request, id *sso.Identity) (string, int64, error) {
+	ctx := r.Context()
+	linked, err := app.DB.GetUserByIdentity(ctx, store.GetUserByIdentityParams{
+		Provider: app.OIDC.Name,
+		Subject:  id.Subject,
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return "", 0, err
+	}
+	userID := linked.UserID
+
+	if userID == "" {
+		// Unverified emails could be used to take over an existing account.
+		if id.Email == "" || !id.EmailVerified {
+			return "", 0, errSSONotPermitted
+		}
+		// Emails are stored lowercased by every other sign-up path.
+		email := pgtype.Text{String: strings.ToLower(id.Email), Valid: true}
+		u, err := app.DB.GetUserCredentialsByEmail(ctx, email)
+		switch {
+		case err == nil:
+			userID = u.Uuid
+		case !errors.Is(err, pgx.ErrNoRows):
+			return "", 0, err
+		case !app.OIDC.CanProvision(id.Email):
+			return "", 0, errSSONotPermitted
+		default:
+			name := id.Name
+			if name == "" {
+				name = strings.Split(id.Email, "@")[0]
+			}
+			created, err := app.DB.CreateUserWithNewTeam(ctx, store.CreateUserWithNewTeamParams{
+				Column1: pgtype.Text{String: name, Valid: true},
+				Name:    pgtype.Text{String: name, Valid: true},
+				Email:   email,
+			})
+			if err != nil {
+				return "", 0, fmt.Errorf("failed to provision sso user: %w", err)
+			}
+			userID = created.UserID
+			app.Logger.Info().Str("user", userID).Str("provider", app.OIDC.Name).Msg("provisioned sso user")
+		}
+		if err := app.DB.MarkEmailVerified(ctx, userID); err != nil {
+			return "", 0, err
+		}
+	}
+
+	identityID, err := app.DB.UpsertUserIdentity(ctx, store.UpsertUserIdentityParams{
+		UserID:    userID,
+		Provider:  app.OIDC.Name,
+		Subject:   id.Subject,
+		Email:     pgtype.Text{String: id.Email, Valid: id.Email != ""},
+		AvatarUrl: pgtype.Text{String: id.Picture, Valid: id.Picture != ""},
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	return userID, identityID, nil
+}
+
+func (app *Application) oidcCookie(value string, expires time.Time) *http.Cookie {
+	c := &http.Cookie{
+		Name:     oidcCookieName,
+		Value:    value,
+		Path:     "/login/oidc",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   app.Config.Session.CookieSecure,
+		// Lax is required for the cookie to be sent on the provider's redirect.
+		SameSite: http.SameSiteLaxMode,
+	}
+	if value == "" {
+		c.MaxAge = -1
+	}
+	return c
+}
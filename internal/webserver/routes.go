@@ -2,8 +2,12 @@ package webserver
 
 import (
 	"net/http"
+	"os"
+	"strings"
 
 	assets "github.com/danielmichaels/tawny"
+	"github.com/danielmichaels/tawny/internal/audit"
+	"github.com/danielmichaels/tawny/internal/storage"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/httplog"
@@ -13,7 +17,9 @@ func (app *Application) routes() http.Handler {
 	router := chi.NewRouter()
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.URLFormat)
-	router.Use(middleware.RealIP)
+	router.Use(middleware.RequestID)
+	router.Use(app.RealIP.Middleware)
+	router.Use(audit.ClientIP)
 	router.Use(middleware.Compress(5))
 	router.Use(httplog.RequestLogger(*app.Logger.Logger, []string{
 		"/healthz",
@@ -29,6 +35,79 @@ func (app *Application) routes() http.Handler {
 
 	fileServer := http.FileServer(http.FS(assets.EmbeddedFiles))
 	router.Handle("/static/*", fileServer)
+	// Uploads are only served from here by the disk driver. Object storage
+	// serves them directly.
+	if disk, ok := app.Accounts.Files.(*storage.Disk); ok {
+		media := http.StripPrefix(storage.DiskPublicPath, http.FileServer(noListing{http.Dir(disk.Dir())}))
+		router.Handle(storage.DiskPublicPath+"/*", media)
+	}
+
+	router.Group(func(r chi.Router) {
+		// Static files are exempt so page loads do not use up the limit.
+		if app.RateLimit != nil {
+			r.Use(app.RateLimit.Middleware)
+		}
+		r.Use(app.csrf)
+		r.Use(app.authenticate)
+		r.Use(app.Audit.HTTP)
+
+		r.Get("/login", app.loginPage)
+		r.Post("/login", app.login)
+		r.Get("/login/oidc", app.oidcLogin)
+		r.Get("/login/oidc/callback", app.oidcCallback)
+		r.Get("/verify-email", app.verifyEmailPage)
+		r.Post("/verify-email", app.verifyEmail)
+		r.Get("/forgot-password", app.forgotPasswordPage)
+		r.Post("/forgot-password", app.forgotPassword)
+		r.Get("/reset-password", app.resetPasswordPage)
+		r.Post("/reset-password", app.resetPassword)
+
+		r.Get("/login/2fa", app.twoFactorLoginPage)
+		r.Post("/login/2fa", app.twoFactorLogin)
+
+		r.Group(func(r chi.Router) {
+			r.Use(app.requireAuthentication)
+			r.Post("/logout", app.logout)
+			r.Get("/account/2fa", app.twoFactorSettings)
+			r.Post("/account/2fa/enroll", app.twoFactorEnroll)
+			r.Post("/account/2fa/confirm", app.twoFactorConfirm)
+			r.Post("/account/2fa/disable", app.twoFactorDisable)
+			r.Post("/account/2fa/recovery-codes", app.twoFactorRecoveryCodes)
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.requireTwoFactorEnrollment)
+				r.Get("/", app.dashboard)
+				r.Post("/teams/switch", app.switchTeam)
+				r.Get("/domains/{domain}/diagnostics", app.domainDiagnostics)
+			})
+		})
+	})
 
 	return router
 }
+
+// LoginEmail is the rate limit credential of sign in attempts so that
+// guessing passwords for one account is throttled across client addresses.
+func LoginEmail(r *http.Request) string {
+	if r.Method != http.MethodPost || r.URL.Path != "/login" {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(r.PostFormValue("email")))
+}
+
+// noListing serves files but not directory indexes.
+type noListing struct {
+	fs http.FileSystem
+}
+
+func (n noListing) Open(name string) (http.File, error) {
+	f, err := n.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if st, err := f.Stat(); err != nil || st.IsDir() {
+		f.Close()
+		return nil, os.ErrNotExist
+	}
+	return f, nil
+}
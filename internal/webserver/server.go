@@ -11,8 +11,15 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/danielmichaels/tawny/internal/account"
+	"github.com/danielmichaels/tawny/internal/audit"
+	"github.com/danielmichaels/tawny/internal/certs"
 	"github.com/danielmichaels/tawny/internal/config"
+	"github.com/danielmichaels/tawny/internal/k8sclient"
 	svclogger "github.com/danielmichaels/tawny/internal/logger"
+	"github.com/danielmichaels/tawny/internal/ratelimit"
+	"github.com/danielmichaels/tawny/internal/realip"
+	"github.com/danielmichaels/tawny/internal/sso"
 	"github.com/danielmichaels/tawny/internal/store"
 )
 
@@ -20,6 +27,19 @@ type Application struct {
 	Config *config.Conf
 	Logger *svclogger.Logger
 	DB     *store.Queries
+	// Accounts handles email verification and password resets.
+	Accounts *account.Service
+	// Audit records state changing requests.
+	Audit *audit.Recorder
+	// RealIP resolves client addresses behind trusted proxies.
+	RealIP *realip.Resolver
+	// RateLimit is nil when rate limiting is disabled.
+	RateLimit *ratelimit.Limiter
+	// OIDC is nil when single sign-on is not configured.
+	OIDC *sso.Provider
+	Kube *k8sclient.K8sClient
+	// Ingress is what domains are checked to resolve to.
+	Ingress certs.Ingress
 }
 
 func (app *Application) Serve(ctx context.Context) error {
@@ -53,6 +73,8 @@ func (app *Application) Serve(ctx context.Context) error {
 		shutdownError <- nil
 	}()
 
+	go app.expireSessions(ctx)
+
 	err := srv.ListenAndServe()
 	if !errors.Is(err, http.ErrServerClosed) {
 		return err
@@ -64,3 +86,29 @@ func (app *Application) Serve(ctx context.Context) error {
 	}
 	return nil
 }
+
+// expireSessions periodically removes expired sessions and email tokens from
+// the database.
+func (app *Application) expireSessions(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := app.DB.DeleteExpiredSessions(ctx)
+			if err != nil {
+				app.Logger.Error().Err(err).Msg("failed to delete expired sessions")
+				continue
+			}
+			app.Logger.Debug().Int64("count", n).Msg("deleted expired sessions")
+			n, err = app.DB.DeleteExpiredUserTokens(ctx)
+			if err != nil {
+				app.Logger.Error().Err(err).Msg("failed to delete expired user tokens")
+				continue
+			}
+			app.Logger.Debug().Int64("count", n).Msg("deleted expired user tokens")
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package webserver
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/danielmichaels/tawny/internal/store"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	sessionCookieName = "tawny_session"
+	// tokenBytes is the amount of randomness in session and CSRF tokens.
+	tokenBytes = 32
+)
+
+// newToken returns a random URL safe token.
+func newToken() (string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is used so that a leaked sessions table cannot be replayed.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// createSession stores a new server side session for userID and sets the
+// session cookie. identityID is set when the user signed in through SSO.
+func (app *Application) createSession(
+	w http.ResponseWriter,
+	r *http.Request,
+	userID string,
+	identityID pgtype.Int8,
+) error {
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+	expires := time.Now().Add(app.Config.Session.Lifetime)
+	err = app.DB.CreateSession(r.Context(), store.CreateSessionParams{
+		TokenHash:  hashToken(token),
+		UserID:     userID,
+		IpAddress:  pgtype.Text{String: remoteIP(r), Valid: r.RemoteAddr != ""},
+		UserAgent:  pgtype.Text{String: r.UserAgent(), Valid: r.UserAgent() != ""},
+		ExpiresAt:  pgtype.Timestamptz{Time: expires, Valid: true},
+		IdentityID: identityID,
+	})
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, app.sessionCookie(token, expires))
+	return nil
+}
+
+// remoteIP returns the address of the client without the port RemoteAddr
+// carries for directly connected clients.
+func remoteIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// destroySession removes the session from the database and expires the cookie.
+func (app *Application) destroySession(w http.ResponseWriter, r *http.Request) error {
+	c, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return nil
+	}
+	http.SetCookie(w, app.sessionCookie("", time.Unix(0, 0)))
+	return app.DB.DeleteSession(r.Context(), hashToken(c.Value))
+}
+
+func (app *Application) sessionCookie(value string, expires time.Time) *http.Cookie {
+	c := &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    value,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   app.Config.Session.CookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if value == "" {
+		c.MaxAge = -1
+	}
+	return c
+}
@@ -0,0 +1,27 @@
+package webserver
+
+import (
+	"net/http"
+
+	"github.com/danielmichaels/tawny/internal/auth"
+	"github.com/danielmichaels/tawny/internal/store"
+)
+
+// switchTeam changes the team the signed in user acts within.
+func (app *Application) switchTeam(w http.ResponseWriter, r *http.Request) {
+	ut := auth.CtxAuthInfo(r.Context())
+	n, err := app.DB.SwitchTeam(r.Context(), store.SwitchTeamParams{
+		UserID: ut.UserUUID,
+		TeamID: r.PostFormValue("team_id"),
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	// Users can only select teams they belong to.
+	if n == 0 {
+		app.forbidden(w, r)
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
@@ -0,0 +1,159 @@
+package webserver
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/danielmichaels/tawny/assets/static/view/pages"
+	"github.com/danielmichaels/tawny/internal/account"
+	"github.com/danielmichaels/tawny/internal/render"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const twoFactorCookieName = "tawny_2fa"
+
+func (app *Application) twoFactorLoginPage(w http.ResponseWriter, r *http.Request) {
+	if _, err := r.Cookie(twoFactorCookieName); err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	_ = render.Render(r.Context(), w, http.StatusOK, pages.TwoFactorLoginPage(""))
+}
+
+func (app *Application) twoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	c, err := r.Cookie(twoFactorCookieName)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	http.SetCookie(w, app.twoFactorCookie("", time.Unix(0, 0)))
+	userID, err := app.Accounts.CompleteTwoFactorLogin(r.Context(), c.Value, r.PostFormValue("code"))
+	switch {
+	case errors.Is(err, account.ErrInvalidToken):
+		app.renderLogin(w, r, http.StatusUnauthorized, "", "Your sign in attempt expired, please try again")
+		return
+	case errors.Is(err, account.ErrInvalidCode):
+		app.Logger.Warn().Msg("failed two-factor login attempt")
+		app.renderLogin(w, r, http.StatusUnauthorized, "", "Authentication code is invalid, please sign in again")
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+	app.startSession(w, r, userID, pgtype.Int8{})
+}
+
+// requireTwoFactorEnrollment sends users who belong to a team enforcing
+// two-factor authentication to enrol before they can continue.
+func (app *Application) requireTwoFactorEnrollment(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u := render.GetUserContext(r)
+		st, err := app.Accounts.TwoFactorStatus(r.Context(), u.UserID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		if st.Required && !st.Enabled {
+			http.Redirect(w, r, "/account/2fa", http.StatusSeeOther)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *Application) twoFactorSettings(w http.ResponseWriter, r *http.Request) {
+	app.renderTwoFactorSettings(w, r, http.StatusOK, "")
+}
+
+func (app *Application) renderTwoFactorSettings(w http.ResponseWriter, r *http.Request, status int, errMsg string) {
+	st, err := app.Accounts.TwoFactorStatus(r.Context(), render.GetUserContext(r).UserID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	_ = render.Render(r.Context(), w, status, pages.TwoFactorSettingsPage(st, errMsg))
+}
+
+func (app *Application) twoFactorEnroll(w http.ResponseWriter, r *http.Request) {
+	secret, uri, err := app.Accounts.EnrollTwoFactor(r.Context(), render.GetUserContext(r).UserID)
+	if err != nil {
+		app.twoFactorError(w, r, err)
+		return
+	}
+	_ = render.Render(r.Context(), w, http.StatusOK, pages.TwoFactorEnrollPage(secret, uri, ""))
+}
+
+func (app *Application) twoFactorConfirm(w http.ResponseWriter, r *http.Request) {
+	codes, err := app.Accounts.ConfirmTwoFactor(
+		r.Context(),
+		render.GetUserContext(r).UserID,
+		r.PostFormValue("code"),
+	)
+	if errors.Is(err, account.ErrInvalidCode) {
+		// Redisplay the pending enrolment so the user can try another code.
+		_ = render.Render(r.Context(), w, http.StatusUnprocessableEntity, pages.TwoFactorEnrollPage(
+			r.PostFormValue("secret"),
+			r.PostFormValue("uri"),
+			err.Error(),
+		))
+		return
+	}
+	if err != nil {
+		app.twoFactorError(w, r, err)
+		return
+	}
+	_ = render.Render(r.Context(), w, http.StatusOK, pages.RecoveryCodesPage(codes))
+}
+
+func (app *Application) twoFactorDisable(w http.ResponseWriter, r *http.Request) {
+	err := app.Accounts.DisableTwoFactor(r.Context(), render.GetUserContext(r).UserID, r.PostFormValue("code"))
+	if err != nil {
+		app.twoFactorError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, "/account/2fa", http.StatusSeeOther)
+}
+
+func (app *Application) twoFactorRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	codes, err := app.Accounts.RegenerateRecoveryCodes(
+		r.Context(),
+		render.GetUserContext(r).UserID,
+		r.PostFormValue("code"),
+	)
+	if err != nil {
+		app.twoFactorError(w, r, err)
+		return
+	}
+	_ = render.Render(r.Context(), w, http.StatusOK, pages.RecoveryCodesPage(codes))
+}
+
+// twoFactorError shows expected failures on the settings page.
+func (app *Application) twoFactorError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, account.ErrInvalidCode),
+		errors.Is(err, account.ErrTwoFactorEnabled),
+		errors.Is(err, account.ErrTwoFactorNotEnrolled),
+		errors.Is(err, account.ErrTwoFactorRequired),
+		errors.Is(err, account.ErrTwoFactorUnavailable):
+		app.renderTwoFactorSettings(w, r, http.StatusUnprocessableEntity, err.Error())
+	default:
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *Application) twoFactorCookie(value string, expires time.Time) *http.Cookie {
+	c := &http.Cookie{
+		Name:     twoFactorCookieName,
+		Value:    value,
+		Path:     "/login/2fa",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   app.Config.Session.CookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if value == "" {
+		c.MaxAge = -1
+	}
+	return c
+}
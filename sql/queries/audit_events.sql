@@ -0,0 +1,42 @@
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (actor_user_id, actor_token_id, team_id, action, resource_type, resource_id, request_id,
+                          ip_address, outcome, actor_service_account_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);
+
+-- Audit events for a team, newest first. Filters are ignored when NULL.
+-- name: ListAuditEvents :many
+SELECT id,
+       actor_user_id,
+       actor_token_id,
+       team_id,
+       action,
+       resource_type,
+       resource_id,
+       request_id,
+       ip_address,
+       outcome,
+       created_at,
+       actor_service_account_id
+FROM audit_events
+WHERE team_id = @team_id
+  AND (sqlc.narg(actor_user_id)::TEXT IS NULL OR actor_user_id = sqlc.narg(actor_user_id) OR
+       actor_service_account_id = sqlc.narg(actor_user_id))
+  AND (sqlc.narg(resource_type)::TEXT IS NULL OR resource_type = sqlc.narg(resource_type))
+  AND (sqlc.narg(since)::TIMESTAMPTZ IS NULL OR created_at >= sqlc.narg(since))
+  AND (sqlc.narg(until)::TIMESTAMPTZ IS NULL OR created_at < sqlc.narg(until))
+  AND (sqlc.narg(max_id)::BIGINT IS NULL OR id <= sqlc.narg(max_id))
+ORDER BY created_at DESC, id DESC
+LIMIT @lim OFFSET @off;
+
+-- Count the events matching the filters of ListAuditEvents. max_id is the
+-- newest event, used to anchor later pages.
+-- name: CountAuditEvents :one
+SELECT count(*), COALESCE(max(id), 0)::BIGINT AS max_id
+FROM audit_events
+WHERE team_id = @team_id
+  AND (sqlc.narg(actor_user_id)::TEXT IS NULL OR actor_user_id = sqlc.narg(actor_user_id) OR
+       actor_service_account_id = sqlc.narg(actor_user_id))
+  AND (sqlc.narg(resource_type)::TEXT IS NULL OR resource_type = sqlc.narg(resource_type))
+  AND (sqlc.narg(since)::TIMESTAMPTZ IS NULL OR created_at >= sqlc.narg(since))
+  AND (sqlc.narg(until)::TIMESTAMPTZ IS NULL OR created_at < sqlc.narg(until))
+  AND (sqlc.narg(max_id)::BIGINT IS NULL OR id <= sqlc.narg(max_id));
@@ -0,0 +1,11 @@
+-- Records a notification as sent. No row is affected when it already was.
+-- name: ClaimCertificateNotification :execrows
+INSERT INTO certificate_notifications (key)
+VALUES (@key)
+ON CONFLICT (key) DO NOTHING;
+
+-- Forgets the notifications of conditions which have cleared.
+-- name: DeleteClearedCertificateNotifications :exec
+DELETE
+FROM certificate_notifications
+WHERE key <> ALL (@keys::text[]);
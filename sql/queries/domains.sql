@@ -0,0 +1,55 @@
+-- name: CreateDomain :one
+INSERT INTO domains (team_id, app_id, name, certificate_type, issuer, tls_secret, routes)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, uuid, team_id, app_id, name, certificate_type, created_at, updated_at, issuer, tls_secret, certificate_expires_at, routes;
+
+-- name: ListDomains :many
+SELECT id, uuid, team_id, app_id, name, certificate_type, created_at, updated_at, issuer, tls_secret, certificate_expires_at, routes
+FROM domains
+WHERE team_id = @team_id
+  AND app_id = @app_id
+  AND (sqlc.narg(max_id)::BIGINT IS NULL OR id <= sqlc.narg(max_id))
+ORDER BY name, id
+LIMIT @lim OFFSET @off;
+
+-- name: GetDomain :one
+SELECT id, uuid, team_id, app_id, name, certificate_type, created_at, updated_at, issuer, tls_secret, certificate_expires_at, routes
+FROM domains
+WHERE team_id = $1
+  AND name = $2;
+
+-- Serve a domain with an uploaded certificate instead of one issued by
+-- cert-manager.
+-- name: SetDomainCertificate :one
+UPDATE domains
+SET issuer                 = '',
+    certificate_type       = 'custom',
+    tls_secret             = $3,
+    certificate_expires_at = $4
+WHERE team_id = $1
+  AND name = $2
+RETURNING id, uuid, team_id, app_id, name, certificate_type, created_at, updated_at, issuer, tls_secret, certificate_expires_at, routes;
+
+-- Replace the routes of a domain.
+-- name: SetDomainRoutes :one
+UPDATE domains
+SET routes = $3
+WHERE team_id = $1
+  AND name = $2
+RETURNING id, uuid, team_id, app_id, name, certificate_type, created_at, updated_at, issuer, tls_secret, certificate_expires_at, routes;
+
+-- Count the domains of an app. max_id is the newest domain, used to anchor
+-- later pages.
+-- name: CountDomains :one
+SELECT count(*), COALESCE(max(id), 0)::BIGINT AS max_id
+FROM domains
+WHERE team_id = @team_id
+  AND app_id = @app_id
+  AND (sqlc.narg(max_id)::BIGINT IS NULL OR id <= sqlc.narg(max_id));
+
+-- Remove a domain whose cluster resources could not be created.
+-- name: DeleteDomain :exec
+DELETE
+FROM domains
+WHERE team_id = $1
+  AND name = $2;
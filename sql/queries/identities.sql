@@ -0,0 +1,21 @@
+-- Retrieve the user linked to an external identity
+-- name: GetUserByIdentity :one
+SELECT ui.id AS identity_id, u.uuid AS user_id, u.email
+FROM user_identities ui
+         JOIN users u ON ui.user_id = u.uuid
+WHERE ui.provider = $1
+  AND ui.subject = $2;
+
+-- Link an external identity to a user, refreshing the profile on each login
+-- name: UpsertUserIdentity :one
+INSERT INTO user_identities (user_id, provider, subject, email, avatar_url)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (provider, subject) DO UPDATE SET email      = EXCLUDED.email,
+                                              avatar_url = EXCLUDED.avatar_url
+RETURNING id;
+
+-- Mark a user's email verified when the identity provider has verified it
+-- name: MarkEmailVerified :exec
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, NOW())
+WHERE uuid = $1;
@@ -1,10 +1,35 @@
--- Create admin user (for initial setup only)
+-- Report whether a platform admin exists (for initial setup only)
 -- name: DoesAdminExist :one
 SELECT EXISTS (SELECT 1
-               FROM users u
-                        JOIN team_user tu ON u.uuid = tu.user_id
-               WHERE u.name = 'admin'
-                 AND tu.role = 'admin') AS admin_exists;
+               FROM users
+               WHERE platform_admin) AS admin_exists;
+-- Create the admin user, their team and an API key holding every scope. Used
+-- by `tawny admin bootstrap` on first boot.
+-- name: BootstrapAdmin :one
+WITH new_team AS (
+    INSERT INTO teams (name, personal_team)
+        VALUES ('admin_team', true)
+        RETURNING uuid, id),
+     new_user AS (
+         INSERT INTO users (name, email, password, email_verified_at, current_team_id, platform_admin)
+             VALUES ('admin', $1, $2, NOW(), (SELECT id FROM new_team), TRUE)
+             RETURNING uuid),
+     new_user_team AS (
+         INSERT INTO team_user (team_id, user_id, role)
+             SELECT new_team.uuid, new_user.uuid, 'owner'
+             FROM new_team,
+                  new_user
+             RETURNING user_id, team_id),
+     new_token AS (
+         INSERT INTO personal_access_tokens (tokenable_type, tokenable_id, name, token, abilities)
+             SELECT 'user', new_user.uuid, 'bootstrap', ('key_' || generate_uid(20)), '["*"]'
+             FROM new_user
+             RETURNING token)
+SELECT new_user.uuid AS user_id, new_team.uuid AS team_id, new_token.token AS personal_access_token
+FROM new_user,
+     new_team,
+     new_token;
+
 -- Update a user role
 -- name: UpdateUserRole :exec
 UPDATE team_user
@@ -27,7 +52,7 @@ WITH new_team AS (
              RETURNING uuid),
      new_user_team AS (
          INSERT INTO team_user (team_id, user_id, role)
-             SELECT new_team.uuid, new_user.uuid, 'maintainer'
+             SELECT new_team.uuid, new_user.uuid, 'owner'
              FROM new_team,
                   new_user
              RETURNING user_id, team_id),
@@ -43,49 +68,107 @@ FROM new_user,
 
 -- Get users in the same team mapping as the logged-in user when provided another user's ID
 -- name: GetUserByID :one
-SELECT u.uuid, u.name, u.email, u.created_at, u.updated_at, tu.role
+SELECT u.uuid, u.name, u.email, u.created_at, u.updated_at, tu.role, u.profile_photo_path
 FROM users u
          JOIN team_user tu ON u.uuid = tu.user_id
 WHERE u.uuid = $1 -- $1 is the UUID of the user you want to retrieve
   AND tu.team_id IN (SELECT ut.team_id FROM team_user ut WHERE ut.user_id = $2); -- $2 is the UUID of the authenticated user
 
 
--- Count all users the authorized user can see; used in pagination
+-- Count the members of a team; used in pagination. max_id is the newest
+-- membership, used to anchor later pages.
 -- name: CountUsers :one
-SELECT count(*) OVER ()
-FROM users u
-         JOIN team_user tu ON u.uuid = tu.user_id
-WHERE tu.team_id IN (SELECT tu.team_id
-                     FROM team_user tu
-                     WHERE tu.team_id = $1);
+SELECT count(*), COALESCE(max(id), 0)::BIGINT AS max_id
+FROM team_user
+WHERE team_id = @team_id
+  AND (sqlc.narg(max_id)::BIGINT IS NULL OR id <= sqlc.narg(max_id));
 
--- List all users associated with the authorized user and get the total count
+-- List the members of a team, newest first. The ID breaks ties so that pages
+-- are stable.
 -- name: ListUsers :many
-SELECT u.id, u.name, u.email, u.created_at, u.updated_at, tu.role
+SELECT u.id, u.uuid, u.name, u.email, u.created_at, u.updated_at, tu.role
 FROM users u
          JOIN team_user tu ON u.uuid = tu.user_id
-WHERE tu.team_id IN (SELECT team_id
-                     FROM team_user
-                     WHERE tu.team_id = $1)
-ORDER BY u.created_at DESC
-LIMIT $2 OFFSET $3;
+WHERE tu.team_id = @team_id
+  AND (sqlc.narg(max_id)::BIGINT IS NULL OR tu.id <= sqlc.narg(max_id))
+ORDER BY u.created_at DESC, u.id DESC
+LIMIT @lim OFFSET @off;
 
--- Create a new team. Can only be created by a user with admin privileges
+-- Create a new team owned by the creating user. Permission to create teams
+-- is checked by the caller.
 -- name: CreateTeam :one
-WITH admin_check AS (SELECT 1
-                     FROM team_user
-                     WHERE user_id = $1 -- uuid of the user
-                       AND role = 'admin')
-INSERT
-INTO teams (name, personal_team)
-SELECT $2, false -- name of the new team
-WHERE EXISTS (SELECT 1 FROM admin_check)
-RETURNING name, uuid, personal_team;
+WITH new_team AS (
+    INSERT INTO teams (name, personal_team)
+        VALUES ($2, false)
+        RETURNING name, uuid, personal_team),
+     new_team_user AS (
+         INSERT INTO team_user (team_id, user_id, role)
+             SELECT new_team.uuid, $1, 'owner'
+             FROM new_team
+             RETURNING team_id)
+SELECT name, uuid, personal_team
+FROM new_team;
+
+-- name: GetTeamByUUID :one
+SELECT name, uuid, personal_team, created_at, updated_at
+FROM teams
+WHERE uuid = $1;
 
--- name: RetrieveUserWithTeamInfoByAPIKEY :one
-SELECT u.uuid, u.name, u.email, t.name, t.uuid AS team_uuid
+-- name: AddTeamMember :exec
+INSERT INTO team_user (team_id, user_id, role)
+VALUES ($1, $2, $3);
+
+-- name: RemoveTeamMember :execrows
+DELETE
+FROM team_user
+WHERE team_id = $1
+  AND user_id = $2;
+
+-- Count the owners of a team; a team must always keep at least one.
+-- name: CountTeamOwners :one
+SELECT count(*)
+FROM team_user
+WHERE team_id = $1
+  AND role = 'owner';
+
+-- Retrieve the user owning an API key along with the team the key is bound to
+-- (if any) and the user's current team. The current team falls back to the
+-- personal team when the user is no longer a member of the selected team.
+-- Team membership is resolved separately so that users in several teams act
+-- within an explicit team.
+-- name: RetrieveUserByAPIKEY :one
+SELECT u.uuid,
+       u.name,
+       u.email,
+       u.email_verified_at IS NOT NULL       AS email_verified,
+       u.two_factor_confirmed_at IS NOT NULL AS two_factor_enabled,
+       u.platform_admin,
+       pat.abilities,
+       pat.team_id                           AS token_team_id,
+       ct.team_id                            AS current_team_id,
+       pat.id                                AS token_id
 FROM users u
-         JOIN personal_access_tokens pat ON u.uuid = pat.tokenable_id
-         JOIN team_user tu ON u.uuid = tu.user_id
-         JOIN teams t ON tu.team_id = t.uuid
+         JOIN personal_access_tokens pat ON u.uuid = pat.user_id
+         LEFT JOIN LATERAL (SELECT tu.team_id, tu.role
+                            FROM team_user tu
+                                     JOIN teams t ON t.uuid = tu.team_id
+                            WHERE tu.user_id = u.uuid
+                            ORDER BY (t.id = u.current_team_id) IS TRUE DESC,
+                                     t.personal_team IS TRUE DESC,
+                                     tu.created_at
+                            LIMIT 1) ct ON TRUE
 WHERE pat.token = $1;
+
+-- name: GetTeamMembership :one
+SELECT tu.team_id, tu.user_id, tu.role, t.require_two_factor
+FROM team_user tu
+         JOIN teams t ON tu.team_id = t.uuid
+WHERE tu.team_id = $1
+  AND tu.user_id = $2;
+
+-- Mint a new API key for a user. abilities is a JSON array of scopes and
+-- team_id optionally binds the key to a single team.
+-- name: CreatePersonalAccessToken :one
+INSERT INTO personal_access_tokens (tokenable_type, tokenable_id, name, token, abilities, team_id)
+VALUES ('user', $1, $2, ('key_' || generate_uid(20)), $3, $4)
+RETURNING id, name, token, abilities, team_id, created_at;
@@ -0,0 +1,34 @@
+-- name: CreatePortMapping :one
+INSERT INTO port_mappings (team_id, app_id, entrypoint, protocol, hostname, passthrough, service_port)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, uuid, team_id, app_id, entrypoint, protocol, hostname, passthrough, service_port, created_at, updated_at;
+
+-- name: ListPortMappings :many
+SELECT id, uuid, team_id, app_id, entrypoint, protocol, hostname, passthrough, service_port, created_at, updated_at
+FROM port_mappings
+WHERE team_id = @team_id
+  AND app_id = @app_id
+  AND (sqlc.narg(max_id)::BIGINT IS NULL OR id <= sqlc.narg(max_id))
+ORDER BY entrypoint, hostname, id
+LIMIT @lim OFFSET @off;
+
+-- Count the port mappings of an app. max_id is the newest mapping, used to
+-- anchor later pages.
+-- name: CountPortMappings :one
+SELECT count(*), COALESCE(max(id), 0)::BIGINT AS max_id
+FROM port_mappings
+WHERE team_id = @team_id
+  AND app_id = @app_id
+  AND (sqlc.narg(max_id)::BIGINT IS NULL OR id <= sqlc.narg(max_id));
+
+-- name: GetPortMapping :one
+SELECT id, uuid, team_id, app_id, entrypoint, protocol, hostname, passthrough, service_port, created_at, updated_at
+FROM port_mappings
+WHERE team_id = $1
+  AND uuid = $2;
+
+-- name: DeletePortMapping :exec
+DELETE
+FROM port_mappings
+WHERE team_id = $1
+  AND uuid = $2;
@@ -0,0 +1,52 @@
+-- Takes a token, refilling the bucket for the time elapsed since it was last
+-- used. No row is returned when the bucket holds less than one token.
+-- name: TakeRateLimitToken :one
+INSERT INTO rate_limit_buckets AS b (key, tokens, updated_at, full_at)
+VALUES (@key, @burst::float8 - 1, NOW(), NOW() + make_interval(secs => 1 / @rate::float8))
+ON CONFLICT (key) DO UPDATE
+    SET tokens     = LEAST(@burst::float8, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at) * @rate::float8) - 1,
+        updated_at = NOW(),
+        full_at    = NOW() + make_interval(secs => (@burst::float8 -
+                                                     LEAST(@burst::float8, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at) * @rate::float8) +
+                                                     1) / @rate::float8)
+WHERE LEAST(@burst::float8, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at) * @rate::float8) >= 1
+RETURNING tokens;
+
+-- Seconds until the bucket holds a whole token.
+-- name: RateLimitWait :one
+SELECT GREATEST(0, (1 - (tokens + EXTRACT(EPOCH FROM NOW() - updated_at) * @rate::float8)) / @rate::float8)::float8
+FROM rate_limit_buckets
+WHERE key = @key;
+
+-- Records a failed authentication. Failures are forgotten once a window
+-- passes without another, so backoff carries over between lockouts.
+-- name: CreateAuthFailure :one
+INSERT INTO auth_failures AS f (key, failures, expires_at)
+VALUES (@key, 1, NOW() + make_interval(secs => @window_seconds::float8))
+ON CONFLICT (key) DO UPDATE
+    SET failures   = CASE WHEN f.expires_at <= NOW() THEN 1 ELSE f.failures + 1 END,
+        expires_at = GREATEST(f.expires_at, EXCLUDED.expires_at)
+RETURNING failures;
+
+-- name: LockAuthFailures :exec
+INSERT INTO auth_failures AS f (key, locked_until, expires_at)
+VALUES (@key, NOW() + make_interval(secs => @lockout_seconds::float8),
+        NOW() + make_interval(secs => @lockout_seconds::float8))
+ON CONFLICT (key) DO UPDATE
+    SET locked_until = GREATEST(f.locked_until, EXCLUDED.locked_until),
+        expires_at   = GREATEST(f.expires_at, EXCLUDED.locked_until);
+
+-- Seconds remaining of a lockout, no row if not locked out.
+-- name: GetAuthLockout :one
+SELECT EXTRACT(EPOCH FROM locked_until - NOW())::float8
+FROM auth_failures
+WHERE key = @key
+  AND locked_until > NOW();
+
+-- name: DeleteExpiredRateLimits :exec
+WITH buckets AS (
+    DELETE FROM rate_limit_buckets WHERE full_at <= NOW()
+)
+DELETE
+FROM auth_failures
+WHERE expires_at <= NOW();
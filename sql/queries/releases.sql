@@ -0,0 +1,39 @@
+-- name: CreateRelease :one
+INSERT INTO releases (team_id, app_id, domain, strategy, image, container, stable_deployment, weight)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, uuid, team_id, app_id, domain, strategy, image, container, stable_deployment, weight, status, created_at, updated_at;
+
+-- name: GetActiveRelease :one
+SELECT id, uuid, team_id, app_id, domain, strategy, image, container, stable_deployment, weight, status, created_at, updated_at
+FROM releases
+WHERE team_id = $1
+  AND app_id = $2
+  AND status = 'active';
+
+-- name: ListActiveDomainReleases :many
+SELECT id, uuid, team_id, app_id, domain, strategy, image, container, stable_deployment, weight, status, created_at, updated_at
+FROM releases
+WHERE team_id = $1
+  AND domain = $2
+  AND status = 'active'
+ORDER BY id;
+
+-- name: SetReleaseWeight :one
+UPDATE releases
+SET weight = $3
+WHERE team_id = $1
+  AND uuid = $2
+RETURNING id, uuid, team_id, app_id, domain, strategy, image, container, stable_deployment, weight, status, created_at, updated_at;
+
+-- name: SetReleaseStatus :one
+UPDATE releases
+SET status = $3
+WHERE team_id = $1
+  AND uuid = $2
+RETURNING id, uuid, team_id, app_id, domain, strategy, image, container, stable_deployment, weight, status, created_at, updated_at;
+
+-- name: DeleteRelease :exec
+DELETE
+FROM releases
+WHERE team_id = $1
+  AND uuid = $2;
@@ -0,0 +1,66 @@
+-- name: CreateServiceAccount :one
+INSERT INTO service_accounts (team_id, name, description, role, created_by)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, uuid, team_id, name, description, role, created_by, created_at, updated_at;
+
+-- name: ListServiceAccounts :many
+SELECT id, uuid, team_id, name, description, role, created_by, created_at, updated_at
+FROM service_accounts
+WHERE team_id = @team_id
+  AND (sqlc.narg(max_id)::BIGINT IS NULL OR id <= sqlc.narg(max_id))
+ORDER BY name
+LIMIT @lim OFFSET @off;
+
+-- Count the service accounts of a team. max_id is the newest account, used to
+-- anchor later pages.
+-- name: CountServiceAccounts :one
+SELECT count(*), COALESCE(max(id), 0)::BIGINT AS max_id
+FROM service_accounts
+WHERE team_id = @team_id
+  AND (sqlc.narg(max_id)::BIGINT IS NULL OR id <= sqlc.narg(max_id));
+
+-- name: GetServiceAccount :one
+SELECT id, uuid, team_id, name, description, role, created_by, created_at, updated_at
+FROM service_accounts
+WHERE team_id = $1
+  AND uuid = $2;
+
+-- Deleting a service account revokes its keys through the foreign key.
+-- name: DeleteServiceAccount :execrows
+DELETE
+FROM service_accounts
+WHERE team_id = $1
+  AND uuid = $2;
+
+-- Keys for service accounts are always bound to the account's team.
+-- name: CreateServiceAccountToken :one
+INSERT INTO personal_access_tokens (tokenable_type, tokenable_id, name, token, abilities, team_id)
+SELECT 'service_account', sa.uuid, $3, ('key_' || generate_uid(20)), $4, sa.team_id
+FROM service_accounts sa
+WHERE sa.team_id = $1
+  AND sa.uuid = $2
+RETURNING id, name, token, abilities, team_id, created_at;
+
+-- name: ListServiceAccountTokens :many
+SELECT id, name, abilities, last_used_at, created_at
+FROM personal_access_tokens
+WHERE service_account_id = $1
+ORDER BY created_at, id;
+
+-- name: DeleteServiceAccountToken :execrows
+DELETE
+FROM personal_access_tokens
+WHERE service_account_id = $1
+  AND id = $2;
+
+-- Retrieve the service account owning an API key. Service accounts only ever
+-- act within their own team.
+-- name: RetrieveServiceAccountByAPIKEY :one
+SELECT sa.uuid,
+       sa.team_id,
+       sa.role,
+       pat.abilities,
+       pat.id AS token_id
+FROM service_accounts sa
+         JOIN personal_access_tokens pat ON sa.uuid = pat.service_account_id
+WHERE pat.token = $1;
@@ -0,0 +1,46 @@
+-- name: CreateSession :exec
+INSERT INTO sessions (token_hash, user_id, ip_address, user_agent, expires_at, identity_id)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- Retrieve an unexpired session and the user it belongs to
+-- name: GetSessionUser :one
+SELECT s.user_id,
+       s.expires_at,
+       u.name,
+       u.email,
+       u.profile_photo_path,
+       u.email_verified_at IS NOT NULL AS email_verified,
+       u.platform_admin,
+       ct.team_id                      AS current_team_id,
+       ct.role                         AS current_team_role,
+       ui.subject                      AS provider_user_id,
+       ui.avatar_url                   AS provider_avatar_url
+FROM sessions s
+         JOIN users u ON s.user_id = u.uuid
+         LEFT JOIN LATERAL (SELECT tu.team_id, tu.role
+                            FROM team_user tu
+                                     JOIN teams t ON t.uuid = tu.team_id
+                            WHERE tu.user_id = u.uuid
+                            ORDER BY (t.id = u.current_team_id) IS TRUE DESC,
+                                     t.personal_team IS TRUE DESC,
+                                     tu.created_at
+                            LIMIT 1) ct ON TRUE
+         LEFT JOIN user_identities ui ON s.identity_id = ui.id
+WHERE s.token_hash = $1
+  AND s.expires_at > NOW();
+
+-- name: DeleteSession :exec
+DELETE
+FROM sessions
+WHERE token_hash = $1;
+
+-- name: DeleteExpiredSessions :execrows
+DELETE
+FROM sessions
+WHERE expires_at <= NOW();
+
+-- Retrieve the credentials of a user for password login
+-- name: GetUserCredentialsByEmail :one
+SELECT uuid, name, email, password
+FROM users
+WHERE email = $1;
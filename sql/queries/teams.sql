@@ -0,0 +1,63 @@
+-- Teams the user belongs to, with their role in each
+-- name: ListTeams :many
+SELECT t.uuid, t.name, t.personal_team, t.require_two_factor, tu.role, t.created_at, t.updated_at
+FROM teams t
+         JOIN team_user tu ON tu.team_id = t.uuid
+WHERE tu.user_id = @user_id
+  AND (sqlc.narg(max_id)::BIGINT IS NULL OR tu.id <= sqlc.narg(max_id))
+ORDER BY t.personal_team DESC NULLS LAST, t.name, t.id
+LIMIT @lim OFFSET @off;
+
+-- Count the teams of a user. max_id is the newest membership, used to anchor
+-- later pages.
+-- name: CountTeams :one
+SELECT count(*), COALESCE(max(id), 0)::BIGINT AS max_id
+FROM team_user
+WHERE user_id = @user_id
+  AND (sqlc.narg(max_id)::BIGINT IS NULL OR id <= sqlc.narg(max_id));
+
+-- name: GetTeam :one
+SELECT uuid, name, personal_team, require_two_factor, created_at, updated_at
+FROM teams
+WHERE uuid = $1;
+
+-- name: ListTeamMembers :many
+SELECT u.uuid, u.name, u.email, tu.role, tu.created_at
+FROM team_user tu
+         JOIN users u ON u.uuid = tu.user_id
+WHERE tu.team_id = $1
+ORDER BY tu.created_at;
+
+-- name: UpdateTeam :one
+UPDATE teams
+SET name = $2
+WHERE uuid = $1
+RETURNING uuid, name, personal_team, require_two_factor, created_at, updated_at;
+
+-- Make a member the owner of a team, demoting the previous owner to admin.
+-- Nothing changes unless the new owner is already a member.
+-- name: TransferTeamOwnership :execrows
+UPDATE team_user
+SET role = CASE WHEN user_id = @new_owner_id THEN 'owner'::user_role ELSE 'admin'::user_role END
+WHERE team_id = @team_id
+  AND user_id IN (@owner_id, @new_owner_id)
+  AND EXISTS (SELECT 1
+              FROM team_user m
+              WHERE m.team_id = @team_id
+                AND m.user_id = @new_owner_id);
+
+-- name: ListTeamDomains :many
+SELECT uuid, name, app_id
+FROM domains
+WHERE team_id = $1;
+
+-- Select the team a user acts within by default. Nothing changes unless the
+-- user is a member of the team.
+-- name: SwitchTeam :execrows
+UPDATE users u
+SET current_team_id = t.id
+FROM teams t
+         JOIN team_user tu ON tu.team_id = t.uuid
+WHERE u.uuid = @user_id
+  AND t.uuid = @team_id
+  AND tu.user_id = u.uuid;
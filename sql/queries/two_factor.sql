@@ -0,0 +1,70 @@
+-- name: GetTwoFactor :one
+SELECT uuid, email, two_factor_secret, two_factor_confirmed_at, two_factor_last_counter
+FROM users
+WHERE uuid = $1;
+
+-- Store a new, unconfirmed secret. Any existing enrolment is replaced.
+-- name: SetTwoFactorSecret :exec
+UPDATE users
+SET two_factor_secret       = $2,
+    two_factor_confirmed_at = NULL,
+    two_factor_last_counter = NULL
+WHERE uuid = $1;
+
+-- name: ConfirmTwoFactor :exec
+UPDATE users
+SET two_factor_confirmed_at = NOW(),
+    two_factor_last_counter = $2
+WHERE uuid = $1;
+
+-- Record the time step of an accepted code. No row is updated if the step has
+-- already been used which prevents a code from being replayed.
+-- name: AdvanceTwoFactorCounter :execrows
+UPDATE users
+SET two_factor_last_counter = $2
+WHERE uuid = $1
+  AND (two_factor_last_counter IS NULL OR two_factor_last_counter < $2);
+
+-- name: DisableTwoFactor :exec
+UPDATE users
+SET two_factor_secret       = NULL,
+    two_factor_confirmed_at = NULL,
+    two_factor_last_counter = NULL
+WHERE uuid = $1;
+
+-- name: CreateRecoveryCodes :exec
+INSERT INTO two_factor_recovery_codes (user_id, code_hash)
+SELECT $1, UNNEST(@code_hashes::TEXT[]);
+
+-- name: DeleteRecoveryCodes :exec
+DELETE
+FROM two_factor_recovery_codes
+WHERE user_id = $1;
+
+-- name: UseRecoveryCode :execrows
+UPDATE two_factor_recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1
+  AND code_hash = $2
+  AND used_at IS NULL;
+
+-- name: CountRecoveryCodes :one
+SELECT COUNT(*)
+FROM two_factor_recovery_codes
+WHERE user_id = $1
+  AND used_at IS NULL;
+
+-- Whether any team the user belongs to requires two-factor authentication
+-- name: IsTwoFactorRequired :one
+SELECT EXISTS (SELECT 1
+               FROM team_user tu
+                        JOIN teams t ON tu.team_id = t.uuid
+               WHERE tu.user_id = $1
+                 AND t.require_two_factor) AS required;
+
+-- name: SetTeamTwoFactorPolicy :one
+UPDATE teams
+SET require_two_factor = $2
+WHERE uuid = $1
+RETURNING uuid, name, personal_team, require_two_factor, created_at, updated_at;
+
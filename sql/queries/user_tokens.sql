@@ -0,0 +1,52 @@
+-- name: CreateUserToken :exec
+INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at)
+VALUES ($1, $2, $3, $4);
+
+-- Mark a token used and return its owner. Expired, used or unknown tokens
+-- return no rows so each token can only be consumed once.
+-- name: ConsumeUserToken :one
+UPDATE user_tokens
+SET used_at = NOW()
+WHERE token_hash = $1
+  AND purpose = $2
+  AND used_at IS NULL
+  AND expires_at > NOW()
+RETURNING user_id;
+
+-- Return the owner of an unused, unexpired token without consuming it
+-- name: GetUserTokenOwner :one
+SELECT user_id
+FROM user_tokens
+WHERE token_hash = $1
+  AND purpose = $2
+  AND used_at IS NULL
+  AND expires_at > NOW();
+
+-- Invalidate outstanding tokens, e.g. once a password has been reset
+-- name: RevokeUserTokens :exec
+UPDATE user_tokens
+SET used_at = NOW()
+WHERE user_id = $1
+  AND purpose = $2
+  AND used_at IS NULL;
+
+-- name: DeleteExpiredUserTokens :execrows
+DELETE
+FROM user_tokens
+WHERE expires_at <= NOW();
+
+-- name: GetUserVerificationStatus :one
+SELECT uuid, email, email_verified_at
+FROM users
+WHERE uuid = $1;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET password = $2
+WHERE uuid = $1;
+
+-- Sign a user out of every web session, e.g. after a password reset
+-- name: DeleteUserSessions :exec
+DELETE
+FROM sessions
+WHERE user_id = $1;
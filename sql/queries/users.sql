@@ -0,0 +1,77 @@
+-- Update a user's profile. Changing the email address clears its
+-- verification so the new address must be verified again.
+-- name: UpdateUser :one
+UPDATE users
+SET name              = COALESCE(sqlc.narg(name), name),
+    email             = COALESCE(sqlc.narg(email), email),
+    email_verified_at = CASE
+                            WHEN sqlc.narg(email) IS NOT NULL AND sqlc.narg(email) <> email THEN NULL
+                            ELSE email_verified_at END
+WHERE uuid = sqlc.arg(uuid)
+RETURNING uuid, name, email, email_verified_at, profile_photo_path, created_at, updated_at;
+
+-- name: GetUserPassword :one
+SELECT password
+FROM users
+WHERE uuid = $1;
+
+-- name: GetUserProfilePhoto :one
+SELECT profile_photo_path
+FROM users
+WHERE uuid = $1;
+
+-- Set the profile photo and return the previous one so it can be removed
+-- name: UpdateProfilePhoto :one
+UPDATE users u
+SET profile_photo_path = $2
+FROM users old
+WHERE u.uuid = $1
+  AND old.id = u.id
+RETURNING old.profile_photo_path AS previous_path;
+
+-- Teams where the user is the only owner or admin and other members would be
+-- left without anyone able to manage the team
+-- name: ListSoleAdminTeams :many
+SELECT t.uuid, t.name
+FROM teams t
+         JOIN team_user tu ON tu.team_id = t.uuid
+WHERE tu.user_id = $1
+  AND tu.role IN ('owner', 'admin')
+  AND NOT EXISTS (SELECT 1
+                  FROM team_user o
+                  WHERE o.team_id = t.uuid
+                    AND o.user_id <> $1
+                    AND o.role IN ('owner', 'admin'))
+  AND EXISTS (SELECT 1
+              FROM team_user m
+              WHERE m.team_id = t.uuid
+                AND m.user_id <> $1);
+
+-- Teams the user is the only member of, such as their personal team
+-- name: ListSoleMemberTeams :many
+SELECT t.uuid
+FROM teams t
+         JOIN team_user tu ON tu.team_id = t.uuid
+WHERE tu.user_id = $1
+  AND NOT EXISTS (SELECT 1
+                  FROM team_user m
+                  WHERE m.team_id = t.uuid
+                    AND m.user_id <> $1);
+
+-- Delete a user. Tokens, memberships, sessions and identities are removed by
+-- ON DELETE CASCADE.
+-- name: DeleteUser :execrows
+DELETE
+FROM users
+WHERE uuid = $1;
+
+-- Delete teams, clearing them as anyone's current team first
+-- name: DeleteTeams :exec
+WITH cleared AS (
+    UPDATE users
+        SET current_team_id = NULL
+        WHERE current_team_id IN (SELECT id FROM teams WHERE uuid = ANY (@team_ids::TEXT[]))
+        RETURNING id)
+DELETE
+FROM teams
+WHERE uuid = ANY (@team_ids::TEXT[]);
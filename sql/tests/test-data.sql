@@ -4,13 +4,13 @@ WITH new_team AS (
         VALUES ('team_0000000', COALESCE('admin' || '_team', 'default_team'), true)
         RETURNING uuid, id),
      new_user AS (
-         INSERT INTO users (uuid, name, email, password, current_team_id)
+         INSERT INTO users (uuid, name, email, password, current_team_id, platform_admin)
              VALUES ('user_0000000', 'admin', 'admin@tawny.internal', crypt('password', gen_salt('bf')),
-                     (SELECT id FROM new_team))
+                     (SELECT id FROM new_team), true)
              RETURNING uuid),
      new_user_team AS (
          INSERT INTO team_user (team_id, user_id, role)
-             SELECT new_team.uuid, new_user.uuid, 'admin'
+             SELECT new_team.uuid, new_user.uuid, 'owner'
              FROM new_team,
                   new_user
              RETURNING user_id, team_id),
@@ -36,7 +36,7 @@ WITH new_team AS (
              RETURNING uuid),
      new_user_team AS (
          INSERT INTO team_user (team_id, user_id, role)
-             SELECT new_team.uuid, new_user.uuid, 'maintainer'
+             SELECT new_team.uuid, new_user.uuid, 'owner'
              FROM new_team,
                   new_user
              RETURNING user_id, team_id),